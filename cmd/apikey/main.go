@@ -0,0 +1,140 @@
+// Command apikey manages API keys:
+//
+//	apikey create -name importer -scopes subscriptions:read,subscriptions:write
+//	apikey revoke -id <uuid>
+//	apikey list
+//
+// The database is taken from -db-url or, if empty, from the service config
+// (CONFIG_PATH).
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"user-aggregation/internal/auth"
+	"user-aggregation/internal/config"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/repo/postgres"
+
+	"github.com/google/uuid"
+)
+
+const usage = "usage: apikey create|revoke|list [flags]"
+
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+	cmd, args := os.Args[1], os.Args[2:]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	dbURL := fs.String("db-url", "", "Postgres URL (default: storage.db_url from CONFIG_PATH)")
+
+	var name, scopes, id *string
+	switch cmd {
+	case "create":
+		name = fs.String("name", "", "who the key is for, shown in the audit log")
+		scopes = fs.String("scopes", "", "comma-separated: "+scopeList())
+	case "revoke":
+		id = fs.String("id", "", "key ID (see apikey list)")
+	case "list":
+	default:
+		log.Fatalf("unknown command %q\n%s", cmd, usage)
+	}
+	_ = fs.Parse(args)
+
+	if *dbURL == "" {
+		*dbURL = config.MustLoad().Storage.DBURL
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	db, err := postgres.New(ctx, *dbURL, postgres.Options{MaxConns: 1, ApplicationName: "apikey"})
+	if err != nil {
+		log.Fatalf("init postgres: %v", err)
+	}
+	defer db.Close()
+
+	switch cmd {
+	case "create":
+		create(ctx, db, *name, *scopes)
+	case "revoke":
+		revoke(ctx, db, *id)
+	case "list":
+		list(ctx, db)
+	}
+}
+
+func create(ctx context.Context, db *postgres.Repo, name, scopes string) {
+	if strings.TrimSpace(name) == "" {
+		log.Fatal("-name is required")
+	}
+	parsed, err := auth.ParseScopes(scopes)
+	if err != nil {
+		log.Fatalf("-scopes: %v (use %s)", err, scopeList())
+	}
+
+	key, hash, prefix, err := auth.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	k := repo.APIKey{Name: name, Prefix: prefix}
+	for _, s := range parsed {
+		k.Scopes = append(k.Scopes, string(s))
+	}
+	if err := db.CreateAPIKey(ctx, &k, hash); err != nil {
+		log.Fatalf("create key: %v", err)
+	}
+
+	fmt.Printf("id:     %s\nscopes: %s\nkey:    %s\n", k.ID, strings.Join(k.Scopes, ","), key)
+	fmt.Println("the key is shown only once, store it now")
+}
+
+func revoke(ctx context.Context, db *postgres.Repo, idStr string) {
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		log.Fatalf("-id: %v", err)
+	}
+	if err := db.RevokeAPIKey(ctx, id); err != nil {
+		if errors.Is(err, repo.ErrNotFound) {
+			log.Fatalf("no active key with id %s", id)
+		}
+		log.Fatalf("revoke key: %v", err)
+	}
+	fmt.Println("revoked", id)
+}
+
+func list(ctx context.Context, db *postgres.Repo) {
+	keys, err := db.ListAPIKeys(ctx)
+	if err != nil {
+		log.Fatalf("list keys: %v", err)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tKEY\tSCOPES\tCREATED\tREVOKED")
+	for _, k := range keys {
+		revoked := "-"
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s…\t%s\t%s\t%s\n",
+			k.ID, k.Name, k.Prefix, strings.Join(k.Scopes, ","), k.CreatedAt.UTC().Format(time.RFC3339), revoked)
+	}
+	_ = tw.Flush()
+}
+
+func scopeList() string {
+	names := make([]string, 0, len(auth.Scopes))
+	for _, s := range auth.Scopes {
+		names = append(names, string(s))
+	}
+	return strings.Join(names, ", ")
+}
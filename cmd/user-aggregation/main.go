@@ -5,12 +5,21 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
+	"user-aggregation/internal/auth"
 	"user-aggregation/internal/config"
+	"user-aggregation/internal/health"
+	"user-aggregation/internal/lifecycle"
 	"user-aggregation/internal/lib/logger"
+	"user-aggregation/internal/metrics"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/purge"
+	"user-aggregation/internal/ratelimit"
 	"user-aggregation/internal/repo"
 	"user-aggregation/internal/repo/postgres"
 	"user-aggregation/internal/server"
 	"user-aggregation/internal/server/handlers"
+	"user-aggregation/internal/tracing"
 	_ "user-aggregation/docs"
 )
 
@@ -20,39 +29,154 @@ import (
 // @contact.url  http://github.com/h4tecancel
 // @BasePath /
 // @schemes http
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description API key ("Bearer ua_...") or JWT ("Bearer eyJ...")
 func main() {
 	cfg := config.MustLoad()
 
 	log, cleanup := logger.Init(cfg.App.Env)
-	defer cleanup()
 	log.Info("App is starting!")
 
+	// phases run in reverse order of registration, whatever started last stops first
+	lc := lifecycle.New(log)
+	lc.OnShutdown("flush logger", 0, func(context.Context) error {
+		cleanup()
+		return nil
+	})
+	defer func() { _ = lc.Shutdown(context.Background()) }()
+
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 	defer stop()
 
-	db, err := postgres.New(ctx, cfg.Storage.DBURL, 1) // 1 - maxconns
+	if t := cfg.Tracing; t.Enabled {
+		shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+			ServiceName: cfg.App.Name,
+			Environment: cfg.App.Env,
+			Exporter:    t.Exporter,
+			Endpoint:    t.Endpoint,
+			Insecure:    t.Insecure,
+			SampleRatio: t.SampleRatio,
+		})
+		if err != nil {
+			log.Error("smth with init tracing", "err", err)
+			return
+		}
+		lc.OnShutdown("flush traces", 5*time.Second, shutdownTracing)
+	}
+
+	st := cfg.Storage
+	db, err := postgres.New(ctx, st.DBURL, postgres.Options{
+		MaxConns:          st.MaxConns,
+		MinConns:          st.MinConns,
+		MaxConnLifetime:   st.MaxConnLifetime,
+		MaxConnIdleTime:   st.MaxConnIdleTime,
+		HealthCheckPeriod: st.HealthCheckPeriod,
+		ApplicationName:   st.ApplicationName,
+		Timeouts: postgres.Timeouts{
+			Read:   st.StatementTimeout.Read,
+			Write:  st.StatementTimeout.Write,
+			Report: st.StatementTimeout.Report,
+			Bulk:   st.StatementTimeout.Bulk,
+		},
+	})
 	if err != nil {
 		log.Error("smth with init postgres", "err", err)
 		return
 	}
-	if c, ok := any(db).(interface{ Close() error }); ok {
-		defer func() {
-			if err := c.Close(); err != nil {
-				log.Error("failed to close db", "err", err)
-			}
-		}()
+	lc.OnShutdown("close database pool", 0, func(context.Context) error {
+		db.Close()
+		return nil
+	})
+
+	if cfg.Purge.Retention > 0 {
+		go purge.Run(ctx, log, db, cfg.Purge.Retention, cfg.Purge.Interval)
 	}
 
 	var repoIface repo.Repo = db
+	if cfg.Tracing.Enabled {
+		repoIface = tracing.Repo(repoIface)
+	}
+	probes := health.New(cfg.Health.CheckTimeout,
+		health.Database(db),
+		health.Migrations(db, postgres.SchemaVersion),
+		health.Pool(db.Stat),
+	)
+	opts := server.Options{
+		PublicHealth:  cfg.Auth.PublicHealth,
+		PublicDocs:    cfg.Auth.PublicDocs,
+		PublicMetrics: cfg.Auth.PublicMetrics,
+		Health:        probes,
+		GroupTimeouts: cfg.HTTPServer.GroupTimeouts,
+	}
+	if cfg.Metrics.Enabled {
+		m := metrics.New()
+		m.RegisterPool(db.Stat)
+		repoIface = m.Repo(repoIface)
+		go m.RunBusiness(ctx, log, db, cfg.Metrics.BusinessInterval)
+		opts.Metrics = m
+	}
+	if err := models.SetDateFormat(cfg.App.DateFormat); err != nil {
+		log.Error("smth with date format", "err", err)
+		return
+	}
 	h := handlers.New(log, repoIface)
-	s := server.New(h)
+	h.DefaultCurrency = cfg.App.DefaultCurrency
+	if cfg.Auth.Enabled {
+		verifiers := []auth.Verifier{auth.NewAPIKeys(db, cfg.Auth.KeyCacheTTL)}
+		if j := cfg.Auth.JWT; j.Enabled {
+			v, err := auth.NewJWT(auth.JWTOptions{
+				HS256Secret:    j.HS256Secret,
+				RS256PublicKey: j.RS256PublicKey,
+				JWKSFile:       j.JWKSFile,
+				Issuer:         j.Issuer,
+				Audience:       j.Audience,
+				ClockSkew:      j.ClockSkew,
+				AdminRole:      j.AdminRole,
+				UserScoped:     j.UserScoped,
+			})
+			if err != nil {
+				log.Error("smth with jwt keys", "err", err)
+				return
+			}
+			verifiers = append(verifiers, v)
+		}
+		opts.Auth = auth.New(verifiers...)
+	} else {
+		log.Warn("authentication is disabled, all routes are public")
+	}
+	if rl := cfg.HTTPServer.RateLimit; rl.Enabled {
+		rules := make(map[string]ratelimit.Rule, len(rl.Groups))
+		for name, g := range rl.Groups {
+			rules[name] = ratelimit.PerMinute(g.RequestsPerMinute, g.Burst)
+		}
+		var store ratelimit.Store = ratelimit.NewMemory()
+		if rl.Backend == "postgres" {
+			store = ratelimit.NewPostgres(db, rules)
+		}
+		opts.RateLimit = ratelimit.New(log, store, rules)
+		go opts.RateLimit.Run(ctx, time.Minute)
+	}
+	s := server.New(h, opts)
 
-	if err := s.Start(ctx,
-		cfg.HTTPServer.Address,
-		cfg.HTTPServer.IdleTimeout,
-		cfg.HTTPServer.Timeout,
-		cfg.HTTPServer.Timeout); err != nil {
+	if err := s.Start(cfg.HTTPServer.Address, cfg.HTTPServer.IdleTimeout, cfg.HTTPServer.Timeout); err != nil {
 		log.Error("smth with server", "err", err)
 		return
 	}
+	lc.OnShutdown("drain in-flight requests", cfg.HTTPServer.ShutdownTimeout, s.Shutdown)
+	lc.OnShutdown("stop accepting connections", 0, s.StopAccepting)
+	lc.OnShutdown("mark not ready", 0, func(ctx context.Context) error {
+		probes.Drain()
+		return lifecycle.Sleep(ctx, cfg.HTTPServer.ShutdownDelay)
+	})
+	log.Info("server started", "address", s.Addr().String())
+
+	select {
+	case <-ctx.Done():
+		log.Info("shutdown signal received")
+	case err := <-s.Err():
+		log.Error("smth with server", "err", err)
+	}
+	stop() // background jobs stop with ctx
 }
\ No newline at end of file
@@ -17,47 +17,78 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/summary": {
+        "/exchange-rates": {
             "get": {
-                "description": "Get total cost summary with optional filters",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "All stored exchange rates, ordered by pair and date",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "summary"
+                    "rates"
                 ],
-                "summary": "Get filtered summary",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Filter by service name",
-                        "name": "service_name",
-                        "in": "query"
+                "summary": "List exchange rates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.ExchangeRates"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "Filter by user ID (UUID)",
-                        "name": "user_id",
-                        "in": "query"
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
                     {
-                        "type": "string",
-                        "description": "Filter by start date (RFC3339 format)",
-                        "name": "start_date",
-                        "in": "query"
-                    },
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Creates or overwrites rates by (base, quote, effective_from). effective_from is truncated to the UTC date.\nA rate is used from its date until the next rate of the same pair; the inverse pair is derived when missing.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "rates"
+                ],
+                "summary": "Save exchange rates",
+                "parameters": [
                     {
-                        "type": "string",
-                        "description": "Filter by end date (RFC3339 format)",
-                        "name": "end_date",
-                        "in": "query"
+                        "description": "Exchange rates",
+                        "name": "rates",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.ExchangeRate"
+                            }
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/response.Summary"
+                            "$ref": "#/definitions/response.ExchangeRates"
                         }
                     },
                     "400": {
@@ -66,6 +97,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ErrorPayload"
                         }
                     },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ValidationError"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -75,60 +118,79 @@ const docTemplate = `{
                 }
             }
         },
-        "/users": {
+        "/livez": {
+            "get": {
+                "description": "Returns 200 while the process is running. Does not touch dependencies.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
             "get": {
-                "description": "Get all user subscription information records",
+                "description": "Pings the database, checks the migration version and reports pool usage.\nFails with 503 when a check fails or the server is shutting down.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "health"
                 ],
-                "summary": "List of all users",
+                "summary": "Readiness probe",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.UserInfo"
-                            }
+                            "$ref": "#/definitions/response.Readiness"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
-                            "$ref": "#/definitions/response.ErrorPayload"
+                            "$ref": "#/definitions/response.Readiness"
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Create a new user subscription information record",
-                "consumes": [
-                    "application/json"
+            }
+        },
+        "/subscriptions/{subscription_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Get a single subscription record by its ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "subscriptions"
                 ],
-                "summary": "Create new user info",
+                "summary": "Get subscription by ID",
                 "parameters": [
                     {
-                        "description": "User subscription information",
-                        "name": "userInfo",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.UserInfo"
-                        }
+                        "type": "string",
+                        "description": "Subscription ID (UUID)",
+                        "name": "subscription_id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/models.UserInfo"
                         }
@@ -139,6 +201,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ErrorPayload"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -146,34 +220,38 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/users/{id}": {
-            "get": {
-                "description": "Get all subscription information for a specific user",
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a single subscription record by its ID",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "subscriptions"
                 ],
-                "summary": "Get user info by ID",
+                "summary": "Delete subscription",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID (UUID)",
-                        "name": "id",
+                        "description": "Subscription ID (UUID)",
+                        "name": "subscription_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Number of deleted records",
                         "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.UserInfo"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "integer",
+                                "format": "int64"
                             }
                         }
                     },
@@ -189,6 +267,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ErrorPayload"
                         }
                     },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -197,29 +281,46 @@ const docTemplate = `{
                     }
                 }
             },
-            "delete": {
-                "description": "Delete all subscription information for a specific user",
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Partially update a single subscription record (price and/or end date)\nA new price applies from effective_from (today by default); earlier periods keep their price.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "subscriptions"
                 ],
-                "summary": "Delete user info by ID",
+                "summary": "Update subscription",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID (UUID)",
-                        "name": "id",
+                        "description": "Subscription ID (UUID)",
+                        "name": "subscription_id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Update fields (price and/or end_date; end_date null makes the subscription open-ended; effective_from dates the price change)",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateUserInfo"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Number of deleted records, but not in json. this will need to be done",
+                        "description": "OK",
                         "schema": {
-                            "type": "integer"
+                            "$ref": "#/definitions/models.UserInfo"
                         }
                     },
                     "400": {
@@ -234,6 +335,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ErrorPayload"
                         }
                     },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ValidationError"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -241,42 +354,98 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "patch": {
-                "description": "Partially update user subscription information (price and/or end date)",
-                "consumes": [
-                    "application/json"
+            }
+        },
+        "/subscriptions/{subscription_id}/prices": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
+                "description": "Get the price periods of a subscription, oldest first. Each price applies from effective_from until the next period starts.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "subscriptions"
                 ],
-                "summary": "Update user info",
+                "summary": "Price history of a subscription",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "User ID (UUID)",
-                        "name": "id",
+                        "description": "Subscription ID (UUID)",
+                        "name": "subscription_id",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.PricePeriod"
+                            }
+                        }
                     },
-                    {
-                        "description": "Update fields (price and/or end_date)",
-                        "name": "update",
-                        "in": "body",
-                        "required": true,
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/models.UpdateUserInfo"
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
                         }
                     }
+                }
+            }
+        },
+        "/subscriptions/{subscription_id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Bring a single trashed subscription record back by its ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "subscriptions"
+                ],
+                "summary": "Restore deleted subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subscription ID (UUID)",
+                        "name": "subscription_id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Number of updated records, but not in json, this will need to be done",
+                        "description": "OK",
                         "schema": {
-                            "type": "integer"
+                            "$ref": "#/definitions/models.UserInfo"
                         }
                     },
                     "400": {
@@ -291,6 +460,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/response.ErrorPayload"
                         }
                     },
+                    "409": {
+                        "description": "a live record with the same user, service and start date exists",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -299,17 +480,1298 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
+        },
+        "/summary": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get total cost of the charges made in [start_date, end_date] with optional filters. A subscription is charged on its start date and then every billing period, at the price in effect on the charge date.\nTotals are split by currency; with currency set they are also converted and summed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "summary"
+                ],
+                "summary": "Get filtered summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by user ID (UUID)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by start date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by end date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its last moment)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Convert the totals into this currency (ISO 4217)",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date of the exchange rates, YYYY-MM-DD (default today, UTC)",
+                        "name": "rate_date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.Summary"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "422": {
+                        "description": "no exchange rate for a currency",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/summary/grouped": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Total cost, count and avg/min/max charge per service, user or charge month. Takes the same filters as /summary.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "summary"
+                ],
+                "summary": "Get grouped summary",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Group by service_name, user_id or month (month of the charge)",
+                        "name": "group_by",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by user ID (UUID)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by start date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by end date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its last moment)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Convert into this currency, merging the currencies of each group (ISO 4217)",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date of the exchange rates, YYYY-MM-DD (default today, UTC)",
+                        "name": "rate_date",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.GroupedSummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "422": {
+                        "description": "no exchange rate for a currency",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/summary/monthly": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Cost of each calendar month of [from, to]. With basis=charges (default) a subscription is charged its price on the start date and then every billing period, and a month gets the charges made in it. With basis=prorated a month gets price * covered_days / days_in_period instead (one-off prices stay whole). Either way each day is at the price in effect on it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "summary"
+                ],
+                "summary": "Get monthly cost breakdown",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "First month: the month of this date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last month, inclusive: the month of this date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by user ID (UUID)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Split each month by service_name or user_id",
+                        "name": "group_by",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "charges (default) or prorated",
+                        "name": "basis",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Convert each month into this currency at the rates of its first day (ISO 4217)",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.MonthlySummary"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "422": {
+                        "description": "no exchange rate for a currency",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get subscription records page by page (keyset pagination)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List of all users",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: price, start_date (default), end_date, service_name",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort direction: asc (default) or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name prefix",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by minimum price (inclusive)",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by maximum price (inclusive)",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only subscriptions active at this moment (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its first moment)",
+                        "name": "active_at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.UserInfoPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new user subscription information record. A duplicate (user_id, service_name, start_date) is rejected with 409.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create new user info",
+                "parameters": [
+                    {
+                        "description": "User subscription information",
+                        "name": "userInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ValidationError"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/bulk": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upserts many subscriptions at once from a JSON array or NDJSON (Content-Type: application/x-ndjson).\natomic mode saves everything or nothing; best_effort saves every valid item and reports the rest.",
+                "consumes": [
+                    "application/json",
+                    "application/x-ndjson"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Bulk import subscriptions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "atomic (default) or best_effort",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Subscriptions",
+                        "name": "items",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UserInfo"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.BulkReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "422": {
+                        "description": "atomic import rolled back",
+                        "schema": {
+                            "$ref": "#/definitions/response.BulkReport"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/export.csv": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Streams matching records as CSV. Takes the same filters as /summary.",
+                "produces": [
+                    "text/csv"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Export subscriptions as CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by service name",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by user ID (UUID)",
+                        "name": "user_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by start date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)",
+                        "name": "start_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by end date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its last moment)",
+                        "name": "end_date",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma (default), semicolon, tab or pipe",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "rfc3339 (default), yyyy-mm-dd or dd.mm.yyyy",
+                        "name": "date_format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "CSV with header id,user_id,service_name,price,currency,billing_period,start_date,end_date",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/import.csv": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Upserts records from a CSV file. Columns are matched by header name (case-insensitive, any order):\nuser_id, service_name, price, start_date, end_date are required; currency and billing_period are optional; other columns are ignored.\nAn empty end_date makes the subscription open-ended.\nErrors are reported by CSV line number (the header is line 1).",
+                "consumes": [
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Import subscriptions from CSV",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "atomic (default) or best_effort",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "comma (default), semicolon, tab or pipe",
+                        "name": "delimiter",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "rfc3339 (default), yyyy-mm-dd or dd.mm.yyyy",
+                        "name": "date_format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.BulkReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "422": {
+                        "description": "atomic import rolled back",
+                        "schema": {
+                            "$ref": "#/definitions/response.BulkReport"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/trash": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get soft-deleted subscription records page by page. Takes the same parameters as GET /users.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "List deleted records",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort field: price, start_date (default), end_date, service_name",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort direction: asc (default) or desc",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by service name prefix",
+                        "name": "service_name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by minimum price (inclusive)",
+                        "name": "min_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Filter by maximum price (inclusive)",
+                        "name": "max_price",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only subscriptions active at this moment (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its first moment)",
+                        "name": "active_at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.UserInfoPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all subscription information for a specific user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user info by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.UserInfo"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Move all subscription records of a user to the trash (see /users/trash and /users/{id}/restore).\nWith permanent=true the records, including already trashed ones, are removed for good.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Delete user info by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Delete permanently instead of moving to the trash (requires admin)",
+                        "name": "permanent",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of deleted records, but not in json. this will need to be done",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "403": {
+                        "description": "permanent without the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Partially update user subscription information (price and/or end date)\nA new price applies from effective_from (today by default); earlier periods keep their price.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user info",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Update fields (price and/or end_date; end_date null makes the subscription open-ended; effective_from dates the price change)",
+                        "name": "update",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UpdateUserInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Number of updated records, but not in json, this will need to be done",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ValidationError"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the audit timeline of all subscription records of a user, oldest first:\nevery insert, update, delete, restore and purge with old and new values, actor and request ID.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Change history of user info",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page size (default 50, max 500)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Opaque cursor from next_cursor of the previous page",
+                        "name": "cursor",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.HistoryPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Bring back the records removed by the last DELETE /users/{id} of a user. Records that clash with a live one on user, service and start date stay in the trash and are listed in conflicts.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Restore deleted user info",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/response.RestoreReport"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "409": {
+                        "description": "a conflicting record was created concurrently",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/subscriptions": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Idempotent upsert by (user_id, service_name, start_date): creates the record or overwrites its price, end date, currency and billing period",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Create or overwrite user subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "User ID (UUID)",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Subscription (user_id may be omitted, it is taken from the path)",
+                        "name": "userInfo",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.UserInfo"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Existing record updated",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserInfo"
+                        }
+                    },
+                    "201": {
+                        "description": "New record created",
+                        "schema": {
+                            "$ref": "#/definitions/models.UserInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.ValidationError"
+                        }
+                    },
+                    "429": {
+                        "description": "rate limit exceeded, see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/response.ErrorPayload"
+                        }
+                    }
+                }
+            }
+        }
+    },
     "definitions": {
+        "models.AuditEntry": {
+            "description": "One entry of the subscription change history",
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor is who made the change",
+                    "type": "string"
+                },
+                "changed_at": {
+                    "description": "ChangedAt is when the change was committed",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID is the position of the entry in the audit log",
+                    "type": "integer"
+                },
+                "new_value": {
+                    "description": "NewValue is the record after the change (absent for purge)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UserInfo"
+                        }
+                    ]
+                },
+                "old_value": {
+                    "description": "OldValue is the record before the change (absent for insert)",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.UserInfo"
+                        }
+                    ]
+                },
+                "operation": {
+                    "description": "Operation is one of insert, update, delete, restore, purge",
+                    "type": "string"
+                },
+                "record_id": {
+                    "description": "RecordID is the ID of the changed subscription record",
+                    "type": "string"
+                },
+                "request_id": {
+                    "description": "RequestID is the ID of the HTTP request that made the change, if any",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "UserID is the owner of the record",
+                    "type": "string"
+                }
+            }
+        },
+        "models.BillingPeriod": {
+            "type": "string",
+            "enum": [
+                "monthly",
+                "yearly",
+                "weekly",
+                "one_off"
+            ],
+            "x-enum-comments": {
+                "BillingOneOff": "charged once, on the start date"
+            },
+            "x-enum-descriptions": [
+                "",
+                "",
+                "",
+                "charged once, on the start date"
+            ],
+            "x-enum-varnames": [
+                "BillingMonthly",
+                "BillingYearly",
+                "BillingWeekly",
+                "BillingOneOff"
+            ]
+        },
+        "models.ExchangeRate": {
+            "description": "Exchange rate in effect from a date (UTC) until the next rate of the same pair",
+            "type": "object",
+            "properties": {
+                "base": {
+                    "description": "Base is the currency being priced (ISO 4217)",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "effective_from": {
+                    "description": "EffectiveFrom is the first day (UTC) the rate applies",
+                    "type": "string"
+                },
+                "quote": {
+                    "description": "Quote is the currency the rate is expressed in (ISO 4217)",
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "rate": {
+                    "description": "Rate is the price of one Base in Quote, up to 10 decimals",
+                    "type": "number",
+                    "example": 92.5
+                }
+            }
+        },
+        "models.PricePeriod": {
+            "description": "Subscription price in effect from a date (UTC, midnight)",
+            "type": "object",
+            "properties": {
+                "effective_from": {
+                    "description": "EffectiveFrom is the first day the price applies",
+                    "type": "string"
+                },
+                "price": {
+                    "description": "Price is the subscription price for the period",
+                    "type": "integer"
+                }
+            }
+        },
         "models.UpdateUserInfo": {
             "description": "User subscription update fields (all fields are optional, except UserID,It does not need to be filled out to submit a request.)",
             "type": "object",
             "properties": {
-                "end_date": {
-                    "description": "EndDate is the updated subscription end date (optional)",
+                "effective_from": {
+                    "description": "EffectiveFrom is the date (UTC) the new price applies from; today if omitted. Requires price",
                     "type": "string"
                 },
+                "end_date": {
+                    "description": "EndDate is the updated subscription end date (optional, same formats as UserInfo.EndDate); null makes the subscription open-ended",
+                    "type": "string",
+                    "x-nullable": true,
+                    "example": "2025-06-30"
+                },
                 "price": {
                     "description": "Price is the updated subscription price (optional, always integer)",
                     "type": "integer"
@@ -324,8 +1786,38 @@ const docTemplate = `{
             "description": "User subscription information with service details and pricing",
             "type": "object",
             "properties": {
+                "billing_period": {
+                    "description": "BillingPeriod is how often Price is charged, counted from StartDate; monthly if omitted",
+                    "enum": [
+                        "monthly",
+                        "yearly",
+                        "weekly",
+                        "one_off"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.BillingPeriod"
+                        }
+                    ],
+                    "example": "monthly"
+                },
+                "currency": {
+                    "description": "Currency is the ISO 4217 code of Price; the configured default if omitted",
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "deleted_at": {
+                    "description": "DeletedAt is when the record was moved to the trash (trash listing only)",
+                    "type": "string"
+                },
                 "end_date": {
-                    "description": "EndDate is when the subscription ends",
+                    "description": "EndDate is when the subscription ends, a day or month meaning its last moment; null for an open-ended subscription",
+                    "type": "string",
+                    "x-nullable": true,
+                    "example": "2025-12-31T23:59:59Z"
+                },
+                "id": {
+                    "description": "ID is the unique identifier of the subscription record (assigned by the service)",
                     "type": "string"
                 },
                 "price": {
@@ -337,8 +1829,9 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "start_date": {
-                    "description": "StartDate is when the subscription begins",
-                    "type": "string"
+                    "description": "StartDate is when the subscription begins; a month (MM-YYYY or YYYY-MM) means its first day",
+                    "type": "string",
+                    "example": "2025-01-01T00:00:00Z"
                 },
                 "user_id": {
                     "description": "UserID is the unique identifier of the user",
@@ -346,6 +1839,106 @@ const docTemplate = `{
                 }
             }
         },
+        "response.BulkReport": {
+            "description": "Per-item report of a bulk import",
+            "type": "object",
+            "properties": {
+                "committed": {
+                    "description": "Committed is false when an atomic import was rolled back",
+                    "type": "boolean"
+                },
+                "created": {
+                    "description": "Created is the number of new records",
+                    "type": "integer"
+                },
+                "mode": {
+                    "description": "Mode is atomic or best_effort",
+                    "type": "string"
+                },
+                "rejected": {
+                    "description": "Rejected is the number of items that were not saved because of their own errors",
+                    "type": "integer"
+                },
+                "results": {
+                    "description": "Results has one entry per input item, in input order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.BulkResult"
+                    }
+                },
+                "updated": {
+                    "description": "Updated is the number of overwritten records",
+                    "type": "integer"
+                }
+            }
+        },
+        "response.BulkResult": {
+            "description": "Outcome of one item of a bulk import",
+            "type": "object",
+            "properties": {
+                "errors": {
+                    "description": "Errors holds field-level validation errors, if any",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.FieldError"
+                    }
+                },
+                "id": {
+                    "description": "ID of the saved record",
+                    "type": "string"
+                },
+                "line": {
+                    "description": "Line is the 1-based line (NDJSON) or array position (JSON)",
+                    "type": "integer"
+                },
+                "reason": {
+                    "description": "Reason the item was rejected",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is created, updated, rejected or skipped (not saved because an atomic import was rolled back)",
+                    "type": "string"
+                }
+            }
+        },
+        "response.CheckResult": {
+            "description": "Outcome of a single dependency check",
+            "type": "object",
+            "properties": {
+                "details": {
+                    "description": "Details depend on the check (versions, pool counters)",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "duration_ms": {
+                    "description": "DurationMS is how long the check took",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "Error explains a failed check",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is ok or fail",
+                    "type": "string"
+                }
+            }
+        },
+        "response.CurrencyTotal": {
+            "description": "Sum of prices in one currency",
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Currency is the ISO 4217 code",
+                    "type": "string",
+                    "example": "RUB"
+                },
+                "total_cost": {
+                    "description": "TotalCost is the sum in that currency",
+                    "type": "integer"
+                }
+            }
+        },
         "response.ErrorPayload": {
             "description": "Returned for all non-2xx responses.",
             "type": "object",
@@ -358,22 +1951,329 @@ const docTemplate = `{
                     "description": "Operation/context where the error occurred (optional)",
                     "type": "string"
                 },
+                "request_id": {
+                    "description": "RequestID matches the X-Request-ID response header and the server logs",
+                    "type": "string"
+                },
                 "status": {
                     "description": "HTTP status code (mirrors the response status)",
                     "type": "integer"
                 }
             }
         },
+        "response.ExchangeRates": {
+            "description": "Exchange rates ordered by pair and date",
+            "type": "object",
+            "properties": {
+                "rates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExchangeRate"
+                    }
+                }
+            }
+        },
+        "response.FieldError": {
+            "description": "One invalid field and the reason code",
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Code is a machine-readable reason: required, negative, before_start, too_long",
+                    "type": "string",
+                    "example": "before_start"
+                },
+                "field": {
+                    "description": "Field is the JSON name of the invalid field",
+                    "type": "string",
+                    "example": "end_date"
+                }
+            }
+        },
+        "response.GroupCost": {
+            "description": "Cost of one service or user within a month",
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Currency of TotalCost: the original one, or the target when converting",
+                    "type": "string"
+                },
+                "key": {
+                    "description": "Key is the service name or user ID",
+                    "type": "string"
+                },
+                "total_cost": {
+                    "description": "TotalCost is the cost charged for this key",
+                    "type": "integer"
+                }
+            }
+        },
+        "response.GroupStats": {
+            "description": "Aggregated prices of one group",
+            "type": "object",
+            "properties": {
+                "avg_price": {
+                    "description": "AvgPrice is the average charge, rounded to 2 decimals",
+                    "type": "number"
+                },
+                "charge_count": {
+                    "description": "ChargeCount is the number of charges in the group",
+                    "type": "integer"
+                },
+                "currency": {
+                    "description": "Currency of the prices: the original one, or the target when converting",
+                    "type": "string"
+                },
+                "key": {
+                    "description": "Key is the service name, user ID or month (YYYY-MM)",
+                    "type": "string"
+                },
+                "max_price": {
+                    "description": "MaxPrice is the highest charge in the group",
+                    "type": "integer"
+                },
+                "min_price": {
+                    "description": "MinPrice is the lowest charge in the group",
+                    "type": "integer"
+                },
+                "subscription_count": {
+                    "description": "SubscriptionCount is the number of records charged in the group",
+                    "type": "integer"
+                },
+                "total_cost": {
+                    "description": "TotalCost is the sum of the charges in the group",
+                    "type": "integer"
+                }
+            }
+        },
+        "response.GroupedSummary": {
+            "description": "Summary split by service, user or charge month",
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Currency is the target currency of the conversion, if any",
+                    "type": "string"
+                },
+                "group_by": {
+                    "description": "Grouping dimension: service_name, user_id or month",
+                    "type": "string"
+                },
+                "groups": {
+                    "description": "One row per group key and currency (one per key when converting), ordered by key",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.GroupStats"
+                    }
+                },
+                "rates": {
+                    "description": "Rates lists the exchange rates used for the conversion",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExchangeRate"
+                    }
+                }
+            }
+        },
+        "response.HistoryPage": {
+            "description": "Page of audit entries, oldest first, with a cursor for the next page",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "Items on this page",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.AuditEntry"
+                    }
+                },
+                "next_cursor": {
+                    "description": "NextCursor is passed as ?cursor= to fetch the next page; empty on the last page",
+                    "type": "string"
+                }
+            }
+        },
+        "response.MonthCost": {
+            "description": "Cost of one calendar month",
+            "type": "object",
+            "properties": {
+                "groups": {
+                    "description": "Groups holds the per-key split when group_by is set",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.GroupCost"
+                    }
+                },
+                "month": {
+                    "description": "Month in YYYY-MM format",
+                    "type": "string"
+                },
+                "total_cost": {
+                    "description": "TotalCost is the cost charged in the month in Currency; only set when converting",
+                    "type": "integer"
+                },
+                "totals": {
+                    "description": "Totals is the cost charged in each original currency",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.CurrencyTotal"
+                    }
+                }
+            }
+        },
+        "response.MonthlySummary": {
+            "description": "Cost charged per calendar month, optionally split by service or user",
+            "type": "object",
+            "properties": {
+                "basis": {
+                    "description": "How costs are put into months: charges or prorated",
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "Currency is the target currency of the conversion, if any",
+                    "type": "string"
+                },
+                "from": {
+                    "description": "First month of the range (YYYY-MM)",
+                    "type": "string"
+                },
+                "group_by": {
+                    "description": "Grouping dimension: service_name, user_id or empty",
+                    "type": "string"
+                },
+                "months": {
+                    "description": "One bucket per month in [from, to]",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.MonthCost"
+                    }
+                },
+                "rates": {
+                    "description": "Rates lists the exchange rates used for the conversion",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExchangeRate"
+                    }
+                },
+                "to": {
+                    "description": "Last month of the range, inclusive (YYYY-MM)",
+                    "type": "string"
+                }
+            }
+        },
+        "response.Readiness": {
+            "description": "Overall readiness and the outcome of every dependency check",
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "description": "Checks by name: database, migrations, pool",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/response.CheckResult"
+                    }
+                },
+                "status": {
+                    "description": "Status is ok, fail or shutting_down",
+                    "type": "string"
+                }
+            }
+        },
+        "response.RestoreReport": {
+            "description": "Records brought back from the last deletion of the user",
+            "type": "object",
+            "properties": {
+                "conflicts": {
+                    "description": "Conflicts are the records left in the trash because a live record has the same user, service and start date",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UserInfo"
+                    }
+                },
+                "restored": {
+                    "description": "Restored is the number of records brought back",
+                    "type": "integer"
+                }
+            }
+        },
         "response.Summary": {
             "description": "Summary response with total cost calculation",
             "type": "object",
             "properties": {
+                "currency": {
+                    "description": "Currency is the target currency of the conversion",
+                    "type": "string"
+                },
+                "rates": {
+                    "description": "Rates lists the exchange rates used for the conversion",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.ExchangeRate"
+                    }
+                },
                 "total_cost": {
-                    "description": "TotalCost is the sum of prices from filtered records",
+                    "description": "TotalCost is the sum in Currency; only set when converting (?currency=)",
+                    "type": "integer"
+                },
+                "totals": {
+                    "description": "Totals is the sum in each original currency",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.CurrencyTotal"
+                    }
+                }
+            }
+        },
+        "response.UserInfoPage": {
+            "description": "Page of subscription records with a cursor for the next page",
+            "type": "object",
+            "properties": {
+                "items": {
+                    "description": "Items on this page",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.UserInfo"
+                    }
+                },
+                "next_cursor": {
+                    "description": "NextCursor is passed as ?cursor= to fetch the next page; empty on the last page",
+                    "type": "string"
+                }
+            }
+        },
+        "response.ValidationError": {
+            "description": "Field-level validation errors",
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "Human-readable error message",
+                    "type": "string"
+                },
+                "errors": {
+                    "description": "Errors lists every failed check",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/response.FieldError"
+                    }
+                },
+                "op": {
+                    "description": "Operation/context where the error occurred (optional)",
+                    "type": "string"
+                },
+                "request_id": {
+                    "description": "RequestID matches the X-Request-ID response header and the server logs",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "HTTP status code (always 422)",
                     "type": "integer"
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "BearerAuth": {
+            "description": "API key (\"Bearer ua_...\") or JWT (\"Bearer eyJ...\")",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
     }
 }`
 
@@ -0,0 +1,191 @@
+// Package auth authenticates API callers from the Authorization header and
+// enforces per-route scopes.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"user-aggregation/internal/lib/reqctx"
+	"user-aggregation/internal/transport/http/respond"
+
+	"github.com/google/uuid"
+)
+
+// Scope is a permission carried by a credential.
+type Scope string
+
+const (
+	ScopeSubscriptionsRead  Scope = "subscriptions:read"
+	ScopeSubscriptionsWrite Scope = "subscriptions:write"
+	ScopeSummaryRead        Scope = "summary:read"
+	ScopeAdmin              Scope = "admin" // implies every other scope
+)
+
+// Scopes lists every known scope.
+var Scopes = []Scope{ScopeSubscriptionsRead, ScopeSubscriptionsWrite, ScopeSummaryRead, ScopeAdmin}
+
+// ParseScopes parses a comma-separated scope list and rejects unknown names.
+func ParseScopes(s string) ([]Scope, error) {
+	var out []Scope
+	for _, part := range strings.Split(s, ",") {
+		sc := Scope(strings.TrimSpace(part))
+		if sc == "" {
+			continue
+		}
+		if !slices.Contains(Scopes, sc) {
+			return nil, fmt.Errorf("unknown scope %q", sc)
+		}
+		if !slices.Contains(out, sc) {
+			out = append(out, sc)
+		}
+	}
+	if len(out) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return out, nil
+}
+
+// Principal is an authenticated caller.
+type Principal struct {
+	// Subject identifies the caller in logs and the audit log, e.g. "apikey:importer".
+	Subject string
+	Scopes  []Scope
+	// UserID is set for end-user tokens, which may only reach routes that
+	// belong to this user (see RequireOwner).
+	UserID *uuid.UUID
+}
+
+// Has reports whether p carries scope, directly or through admin.
+func (p Principal) Has(scope Scope) bool {
+	return slices.Contains(p.Scopes, ScopeAdmin) || slices.Contains(p.Scopes, scope)
+}
+
+type ctxKey struct{}
+
+// WithPrincipal returns a copy of ctx that carries p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, p)
+}
+
+// FromContext returns the principal of an authenticated request.
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(ctxKey{}).(Principal)
+	return p, ok
+}
+
+var (
+	// ErrUnauthenticated means the credentials are missing or invalid (401).
+	// Any other error from a Verifier is treated as a server failure.
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrUnknownToken is returned by a Verifier for tokens it does not
+	// handle, so the next one can try.
+	ErrUnknownToken = errors.New("unknown token format")
+)
+
+// Verifier turns a bearer token into a principal.
+type Verifier interface {
+	Verify(ctx context.Context, token string) (Principal, error)
+}
+
+// Authenticator checks bearer tokens with its verifiers, in order.
+// A nil *Authenticator lets every request through.
+type Authenticator struct {
+	verifiers []Verifier
+}
+
+func New(verifiers ...Verifier) *Authenticator {
+	return &Authenticator{verifiers: verifiers}
+}
+
+// Require wraps h so that it only runs for callers that carry scope.
+// User-scoped callers are refused.
+func (a *Authenticator) Require(scope Scope) func(http.HandlerFunc) http.Handler {
+	return a.RequireOwner(scope, nil)
+}
+
+// OwnerFunc returns the user a request is about, e.g. a path variable.
+type OwnerFunc func(r *http.Request) string
+
+// RequireOwner is Require for routes that user-scoped callers may reach
+// as long as owner(r) is their own user ID.
+func (a *Authenticator) RequireOwner(scope Scope, owner OwnerFunc) func(http.HandlerFunc) http.Handler {
+	return func(h http.HandlerFunc) http.Handler {
+		if a == nil {
+			return h
+		}
+		return a.handler(scope, owner, h)
+	}
+}
+
+// Authenticated is a middleware that only lets authenticated callers in,
+// whatever their scopes.
+func (a *Authenticator) Authenticated(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return a.handler("", nil, next)
+}
+
+func (a *Authenticator) handler(scope Scope, owner OwnerFunc, next http.Handler) http.Handler {
+	const op = "auth.require"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.authenticate(r)
+		if errors.Is(err, ErrUnauthenticated) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="user-aggregation"`)
+			respond.Error(w, r, op, http.StatusUnauthorized, "missing or invalid credentials", err)
+			return
+		}
+		if err != nil {
+			respond.Error(w, r, op, http.StatusInternalServerError, "failed to authenticate", err)
+			return
+		}
+		if scope != "" && !p.Has(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope=%q`, scope))
+			respond.Error(w, r, op, http.StatusForbidden, "missing scope "+string(scope), nil)
+			return
+		}
+		if scope != "" && p.UserID != nil && !ownedBy(r, owner, *p.UserID) {
+			respond.Error(w, r, op, http.StatusForbidden, "user tokens can only access their own data", nil)
+			return
+		}
+
+		ctx := WithPrincipal(r.Context(), p)
+		ctx = reqctx.WithActor(ctx, p.Subject)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (a *Authenticator) authenticate(r *http.Request) (Principal, error) {
+	if p, ok := FromContext(r.Context()); ok {
+		return p, nil
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return Principal{}, fmt.Errorf("%w: no bearer token", ErrUnauthenticated)
+	}
+	token = strings.TrimSpace(token)
+
+	for _, v := range a.verifiers {
+		p, err := v.Verify(r.Context(), token)
+		if errors.Is(err, ErrUnknownToken) {
+			continue
+		}
+		return p, err
+	}
+	return Principal{}, fmt.Errorf("%w: %w", ErrUnauthenticated, ErrUnknownToken)
+}
+
+// ownedBy reports whether the request is about userID. Routes without an
+// owner are never open to user-scoped callers.
+func ownedBy(r *http.Request, owner OwnerFunc, userID uuid.UUID) bool {
+	if owner == nil {
+		return false
+	}
+	id, err := uuid.Parse(owner(r))
+	return err == nil && id == userID
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-aggregation/internal/lib/reqctx"
+	"user-aggregation/internal/repo"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+type staticVerifier map[string]Principal
+
+func (v staticVerifier) Verify(_ context.Context, token string) (Principal, error) {
+	p, ok := v[token]
+	if !ok {
+		return Principal{}, ErrUnknownToken
+	}
+	return p, nil
+}
+
+func TestRequire(t *testing.T) {
+	a := New(staticVerifier{
+		"reader": {Subject: "apikey:reader", Scopes: []Scope{ScopeSubscriptionsRead}},
+		"root":   {Subject: "apikey:root", Scopes: []Scope{ScopeAdmin}},
+	})
+
+	var actor string
+	h := a.Require(ScopeSubscriptionsWrite)(func(w http.ResponseWriter, r *http.Request) {
+		actor = reqctx.Actor(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	cases := []struct {
+		header string
+		code   int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Basic cm9vdDo=", http.StatusUnauthorized},
+		{"Bearer nope", http.StatusUnauthorized},
+		{"Bearer reader", http.StatusForbidden},
+		{"Bearer root", http.StatusNoContent},
+		{"bearer root", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		if c.header != "" {
+			req.Header.Set("Authorization", c.header)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+
+		require.Equal(t, c.code, w.Code, c.header)
+		if c.code == http.StatusUnauthorized || c.code == http.StatusForbidden {
+			require.NotEmpty(t, w.Header().Get("WWW-Authenticate"), c.header)
+		}
+	}
+	require.Equal(t, "apikey:root", actor)
+}
+
+type failingVerifier struct{}
+
+func (failingVerifier) Verify(context.Context, string) (Principal, error) {
+	return Principal{}, errors.New("db down")
+}
+
+func TestRequireOwner(t *testing.T) {
+	self, other := uuid.New(), uuid.New()
+	a := New(staticVerifier{
+		"user":  {Subject: "jwt:" + self.String(), Scopes: []Scope{ScopeSubscriptionsRead}, UserID: &self},
+		"admin": {Subject: "jwt:backoffice", Scopes: []Scope{ScopeAdmin}},
+	})
+	ok := func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	owner := func(r *http.Request) string { return r.URL.Query().Get("user_id") }
+
+	own := a.RequireOwner(ScopeSubscriptionsRead, owner)(ok)
+	plain := a.Require(ScopeSubscriptionsRead)(ok)
+
+	cases := []struct {
+		h      http.Handler
+		token  string
+		target string
+		code   int
+	}{
+		{own, "user", "/?user_id=" + self.String(), http.StatusNoContent},
+		{own, "user", "/?user_id=" + other.String(), http.StatusForbidden},
+		{own, "user", "/", http.StatusForbidden},
+		{own, "admin", "/?user_id=" + other.String(), http.StatusNoContent},
+		{own, "admin", "/", http.StatusNoContent},
+		{plain, "user", "/?user_id=" + self.String(), http.StatusForbidden},
+		{plain, "admin", "/", http.StatusNoContent},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(http.MethodGet, c.target, nil)
+		req.Header.Set("Authorization", "Bearer "+c.token)
+		w := httptest.NewRecorder()
+		c.h.ServeHTTP(w, req)
+		require.Equal(t, c.code, w.Code, "%s %s", c.token, c.target)
+	}
+}
+
+func TestRequire_VerifierFailure(t *testing.T) {
+	a := New(failingVerifier{})
+	h := a.Require(ScopeSubscriptionsRead)(func(http.ResponseWriter, *http.Request) {
+		t.Fatal("handler must not run")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set("Authorization", "Bearer x")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestRequire_NilAuthenticator(t *testing.T) {
+	var a *Authenticator
+	h := a.Require(ScopeAdmin)(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/users/x", nil))
+	require.Equal(t, http.StatusNoContent, w.Code)
+}
+
+func TestParseScopes(t *testing.T) {
+	got, err := ParseScopes(" subscriptions:read, summary:read ,subscriptions:read")
+	require.NoError(t, err)
+	require.Equal(t, []Scope{ScopeSubscriptionsRead, ScopeSummaryRead}, got)
+
+	_, err = ParseScopes("subscriptions:read,root")
+	require.Error(t, err)
+	_, err = ParseScopes(" , ")
+	require.Error(t, err)
+}
+
+type fakeKeyStore struct {
+	keys  map[string]repo.APIKey
+	calls int
+}
+
+func (s *fakeKeyStore) APIKeyByHash(_ context.Context, hash []byte) (repo.APIKey, error) {
+	s.calls++
+	k, ok := s.keys[string(hash)]
+	if !ok {
+		return repo.APIKey{}, repo.ErrNotFound
+	}
+	return k, nil
+}
+
+func TestAPIKeys_Verify(t *testing.T) {
+	key, hash, prefix, err := GenerateKey()
+	require.NoError(t, err)
+	require.True(t, len(prefix) < len(key) && key[:len(prefix)] == prefix)
+
+	store := &fakeKeyStore{keys: map[string]repo.APIKey{
+		string(hash): {Name: "importer", Scopes: []string{"subscriptions:write"}},
+	}}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	v := NewAPIKeys(store, time.Minute)
+	v.now = func() time.Time { return now }
+
+	p, err := v.Verify(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, "apikey:importer", p.Subject)
+	require.True(t, p.Has(ScopeSubscriptionsWrite))
+	require.False(t, p.Has(ScopeSubscriptionsRead))
+
+	// cached until the TTL runs out
+	_, err = v.Verify(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, 1, store.calls)
+
+	delete(store.keys, string(hash)) // revoked
+	now = now.Add(2 * time.Minute)
+	_, err = v.Verify(context.Background(), key)
+	require.ErrorIs(t, err, ErrUnauthenticated)
+	require.Equal(t, 2, store.calls)
+
+	// other token formats are left to the next verifier
+	_, err = v.Verify(context.Background(), "eyJhbGciOi...")
+	require.ErrorIs(t, err, ErrUnknownToken)
+	require.Equal(t, 2, store.calls)
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// JWTOptions configures JWT verification. At least one key source is
+// required; tokens signed with an algorithm that has no key are rejected.
+type JWTOptions struct {
+	HS256Secret    string
+	RS256PublicKey string // path to a PEM public key
+	JWKSFile       string // path to a JWKS document with RSA keys
+	Issuer         string // checked when set
+	Audience       string // checked when set
+	ClockSkew      time.Duration
+	AdminRole      string // "role"/"roles" value that grants full access
+	UserScoped     bool   // non-admin tokens only reach the data of the user in "sub"
+}
+
+// defaultUserScopes are given to user tokens without a "scope" claim.
+var defaultUserScopes = []Scope{ScopeSubscriptionsRead, ScopeSummaryRead}
+
+// JWT verifies HS256 and RS256 signed tokens.
+type JWT struct {
+	opts   JWTOptions
+	secret []byte
+	rsa    map[string]*rsa.PublicKey // by kid; "" for the PEM key
+	parser *jwt.Parser
+}
+
+type jwtClaims struct {
+	jwt.RegisteredClaims
+	Scope string   `json:"scope"`
+	Role  string   `json:"role"`
+	Roles []string `json:"roles"`
+}
+
+func NewJWT(o JWTOptions) (*JWT, error) {
+	v := &JWT{opts: o, rsa: make(map[string]*rsa.PublicKey)}
+
+	var methods []string
+	if o.HS256Secret != "" {
+		v.secret = []byte(o.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if o.RS256PublicKey != "" {
+		pemBytes, err := os.ReadFile(o.RS256PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("read rs256 public key: %w", err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse rs256 public key %q: %w", o.RS256PublicKey, err)
+		}
+		v.rsa[""] = key
+	}
+	if o.JWKSFile != "" {
+		keys, err := readJWKS(o.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		for kid, k := range keys {
+			v.rsa[kid] = k
+		}
+	}
+	if len(v.rsa) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("jwt: no keys configured")
+	}
+
+	popts := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(o.ClockSkew),
+		jwt.WithExpirationRequired(),
+	}
+	if o.Issuer != "" {
+		popts = append(popts, jwt.WithIssuer(o.Issuer))
+	}
+	if o.Audience != "" {
+		popts = append(popts, jwt.WithAudience(o.Audience))
+	}
+	v.parser = jwt.NewParser(popts...)
+	return v, nil
+}
+
+func (v *JWT) Verify(_ context.Context, token string) (Principal, error) {
+	if strings.HasPrefix(token, KeyPrefix) || strings.Count(token, ".") != 2 {
+		return Principal{}, ErrUnknownToken
+	}
+
+	var c jwtClaims
+	if _, err := v.parser.ParseWithClaims(token, &c, v.key); err != nil {
+		return Principal{}, fmt.Errorf("%w: %w", ErrUnauthenticated, err)
+	}
+	if c.Subject == "" {
+		return Principal{}, fmt.Errorf("%w: token has no sub", ErrUnauthenticated)
+	}
+
+	p := Principal{Subject: "jwt:" + c.Subject}
+	if v.opts.AdminRole != "" && (c.Role == v.opts.AdminRole || slices.Contains(c.Roles, v.opts.AdminRole)) {
+		p.Scopes = []Scope{ScopeAdmin}
+		return p, nil
+	}
+
+	for _, s := range strings.Fields(c.Scope) {
+		// unknown scopes belong to other services
+		if sc := Scope(s); sc != ScopeAdmin && slices.Contains(Scopes, sc) {
+			p.Scopes = append(p.Scopes, sc)
+		}
+	}
+
+	if v.opts.UserScoped {
+		id, err := uuid.Parse(c.Subject)
+		if err != nil {
+			return Principal{}, fmt.Errorf("%w: sub is not a user ID", ErrUnauthenticated)
+		}
+		p.UserID = &id
+		if c.Scope == "" {
+			p.Scopes = defaultUserScopes
+		}
+	}
+	return p, nil
+}
+
+func (v *JWT) key(t *jwt.Token) (any, error) {
+	switch t.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return v.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		if kid, _ := t.Header["kid"].(string); kid != "" {
+			if k, ok := v.rsa[kid]; ok {
+				return k, nil
+			}
+			return nil, fmt.Errorf("unknown kid %q", kid)
+		}
+		set := jwt.VerificationKeySet{}
+		for _, k := range v.rsa {
+			set.Keys = append(set.Keys, k)
+		}
+		return set, nil
+	}
+	return nil, fmt.Errorf("unexpected alg %q", t.Method.Alg())
+}
+
+// readJWKS loads the RSA signing keys of a JWKS document, by kid.
+// Keys of other types or uses are skipped.
+func readJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks: %w", err)
+	}
+	var doc struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Use string `json:"use"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parse jwks %q: %w", path, err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for i, k := range doc.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if err := errors.Join(errN, errE); err != nil || len(e) > 4 {
+			return nil, fmt.Errorf("jwks %q: key %d: invalid modulus or exponent", path, i)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks %q: no RSA signing keys", path)
+	}
+	return keys, nil
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func claims(sub string, extra jwt.MapClaims) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"sub": sub,
+		"iss": "https://id.example.com",
+		"aud": "user-aggregation",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	}
+	for k, v := range extra {
+		c[k] = v
+	}
+	return c
+}
+
+func TestJWT_HS256(t *testing.T) {
+	v, err := NewJWT(JWTOptions{
+		HS256Secret: testSecret,
+		Issuer:      "https://id.example.com",
+		Audience:    "user-aggregation",
+		ClockSkew:   30 * time.Second,
+		AdminRole:   "admin",
+		UserScoped:  true,
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+	userID := uuid.New()
+
+	p, err := v.Verify(ctx, sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(userID.String(), nil)))
+	require.NoError(t, err)
+	require.Equal(t, "jwt:"+userID.String(), p.Subject)
+	require.Equal(t, &userID, p.UserID)
+	require.True(t, p.Has(ScopeSubscriptionsRead))
+	require.False(t, p.Has(ScopeSubscriptionsWrite))
+
+	p, err = v.Verify(ctx, sign(t, jwt.SigningMethodHS256, []byte(testSecret), "",
+		claims(userID.String(), jwt.MapClaims{"scope": "openid subscriptions:write admin"})))
+	require.NoError(t, err)
+	require.Equal(t, []Scope{ScopeSubscriptionsWrite}, p.Scopes, "admin is only granted by role")
+
+	p, err = v.Verify(ctx, sign(t, jwt.SigningMethodHS256, []byte(testSecret), "",
+		claims("backoffice", jwt.MapClaims{"roles": []string{"staff", "admin"}})))
+	require.NoError(t, err)
+	require.Nil(t, p.UserID)
+	require.True(t, p.Has(ScopeAdmin))
+
+	// within the clock skew
+	_, err = v.Verify(ctx, sign(t, jwt.SigningMethodHS256, []byte(testSecret), "",
+		claims(userID.String(), jwt.MapClaims{"exp": time.Now().Add(-10 * time.Second).Unix()})))
+	require.NoError(t, err)
+
+	rejected := map[string]string{
+		"expired":      sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(userID.String(), jwt.MapClaims{"exp": time.Now().Add(-time.Minute).Unix()})),
+		"no exp":       sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(userID.String(), jwt.MapClaims{"exp": nil})),
+		"wrong iss":    sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(userID.String(), jwt.MapClaims{"iss": "evil"})),
+		"wrong aud":    sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(userID.String(), jwt.MapClaims{"aud": "other"})),
+		"wrong secret": sign(t, jwt.SigningMethodHS256, []byte(testSecret+"x"), "", claims(userID.String(), nil)),
+		"sub not uuid": sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims("alice", nil)),
+		"alg none":     sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(userID.String(), nil)),
+	}
+	for name, tok := range rejected {
+		_, err := v.Verify(ctx, tok)
+		require.ErrorIs(t, err, ErrUnauthenticated, name)
+	}
+
+	_, err = v.Verify(ctx, "ua_notajwt")
+	require.ErrorIs(t, err, ErrUnknownToken)
+}
+
+func TestJWT_RS256_JWKS(t *testing.T) {
+	k1, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	k2, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	jwk := func(kid string, k *rsa.PrivateKey) map[string]string {
+		return map[string]string{
+			"kty": "RSA", "use": "sig", "kid": kid,
+			"n": base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	}
+	doc, err := json.Marshal(map[string]any{"keys": []any{
+		jwk("one", k1), jwk("two", k2), map[string]string{"kty": "EC", "kid": "ec"},
+	}})
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, doc, 0o600))
+
+	v, err := NewJWT(JWTOptions{JWKSFile: path})
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	p, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, k2, "two", claims("svc", jwt.MapClaims{"scope": "summary:read"})))
+	require.NoError(t, err)
+	require.Nil(t, p.UserID, "not user-scoped")
+	require.Equal(t, []Scope{ScopeSummaryRead}, p.Scopes)
+
+	// without kid every key is tried
+	_, err = v.Verify(ctx, sign(t, jwt.SigningMethodRS256, k1, "", claims("svc", nil)))
+	require.NoError(t, err)
+
+	_, err = v.Verify(ctx, sign(t, jwt.SigningMethodRS256, k1, "two", claims("svc", nil)))
+	require.ErrorIs(t, err, ErrUnauthenticated)
+	_, err = v.Verify(ctx, sign(t, jwt.SigningMethodRS256, k1, "three", claims("svc", nil)))
+	require.ErrorIs(t, err, ErrUnauthenticated)
+	// HS256 is not accepted when no secret is configured
+	_, err = v.Verify(ctx, sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims("svc", nil)))
+	require.ErrorIs(t, err, ErrUnauthenticated)
+}
+
+func TestNewJWT_NoKeys(t *testing.T) {
+	_, err := NewJWT(JWTOptions{Issuer: "x"})
+	require.Error(t, err)
+}
@@ -4,7 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/money"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -13,11 +17,21 @@ type Config struct {
 	App        App        `yaml:"app"`
 	HTTPServer HTTPServer `yaml:"http_server"`
 	Storage    Storage    `yaml:"storage"`
+	Purge      Purge      `yaml:"purge"`
+	Auth       Auth       `yaml:"auth"`
+	Metrics    Metrics    `yaml:"metrics"`
+	Tracing    Tracing    `yaml:"tracing"`
+	Health     Health     `yaml:"health"`
 }
 
 type App struct {
 	Name string `yaml:"name"`
 	Env  string `yaml:"env"`
+	// DefaultCurrency is stored for records created without a currency.
+	DefaultCurrency string `yaml:"default_currency" env:"DEFAULT_CURRENCY" env-default:"RUB"`
+	// DateFormat is how subscription dates are written in responses:
+	// rfc3339, yyyy-mm-dd, yyyy-mm or mm-yyyy.
+	DateFormat string `yaml:"date_format" env:"DATE_FORMAT" env-default:"rfc3339"`
 }
 
 type HTTPServer struct {
@@ -25,10 +39,121 @@ type HTTPServer struct {
 	Timeout         time.Duration `yaml:"timeout"`
 	IdleTimeout     time.Duration `yaml:"idle_timeout"`
 	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// ShutdownDelay is how long /readyz fails before the listener closes,
+	// so that load balancers stop sending traffic first.
+	ShutdownDelay time.Duration `yaml:"shutdown_delay"`
+	// GroupTimeouts replace Timeout for the routes of a group (read, write,
+	// bulk, summary), for reading the request, writing the response and the
+	// time handlers may spend on the database.
+	GroupTimeouts map[string]time.Duration `yaml:"group_timeouts"`
+	RateLimit     RateLimit                `yaml:"rate_limit"`
 }
 
+// TimeoutOf returns the request timeout of a route group.
+func (h HTTPServer) TimeoutOf(group string) time.Duration {
+	if d, ok := h.GroupTimeouts[group]; ok {
+		return d
+	}
+	return h.Timeout
+}
+
+// RateLimit configures token buckets per client and route group
+// (read, write, bulk, summary), plus the ip group: one bucket per client
+// address, taken before authentication. Groups without a rule are not
+// limited.
+type RateLimit struct {
+	Enabled bool                     `yaml:"enabled"`
+	Backend string                   `yaml:"backend"` // memory | postgres
+	Groups  map[string]RateLimitRule `yaml:"groups"`
+}
+
+type RateLimitRule struct {
+	RequestsPerMinute int `yaml:"requests_per_minute"`
+	Burst             int `yaml:"burst"`
+}
+
+// routeGroups are the route groups the server knows.
+var routeGroups = []string{"read", "write", "bulk", "summary"}
+
+// rateLimitGroups are the groups rate_limit takes rules for: the route
+// groups and ip (ratelimit.GroupIP).
+var rateLimitGroups = append(slices.Clone(routeGroups), "ip")
+
+// Storage configures the Postgres pool. Zero pool settings keep the pgx
+// defaults; a zero statement timeout leaves that query class unlimited.
 type Storage struct {
-	DBURL string `yaml:"db_url"`
+	DBURL             string            `yaml:"db_url"`
+	MaxConns          int32             `yaml:"max_conns" env:"DB_MAX_CONNS"`
+	MinConns          int32             `yaml:"min_conns" env:"DB_MIN_CONNS"`
+	MaxConnLifetime   time.Duration     `yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME"`
+	MaxConnIdleTime   time.Duration     `yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME"`
+	HealthCheckPeriod time.Duration     `yaml:"health_check_period" env:"DB_HEALTH_CHECK_PERIOD"`
+	ApplicationName   string            `yaml:"application_name" env:"DB_APPLICATION_NAME"`
+	StatementTimeout  StatementTimeouts `yaml:"statement_timeout"`
+}
+
+// StatementTimeouts bound one repository call per query class.
+type StatementTimeouts struct {
+	Read   time.Duration `yaml:"read" env:"DB_STATEMENT_TIMEOUT_READ"`     // lookups and listings
+	Write  time.Duration `yaml:"write" env:"DB_STATEMENT_TIMEOUT_WRITE"`   // single-record changes
+	Report time.Duration `yaml:"report" env:"DB_STATEMENT_TIMEOUT_REPORT"` // /summary* and CSV export
+	Bulk   time.Duration `yaml:"bulk" env:"DB_STATEMENT_TIMEOUT_BULK"`     // bulk load and CSV import
+}
+
+// Auth configures bearer authentication. When Enabled is false every
+// route is public.
+type Auth struct {
+	Enabled       bool          `yaml:"enabled"`
+	PublicHealth  bool          `yaml:"public_health"`
+	PublicDocs    bool          `yaml:"public_docs"`
+	PublicMetrics bool          `yaml:"public_metrics"`
+	KeyCacheTTL   time.Duration `yaml:"key_cache_ttl"`
+	JWT           JWT           `yaml:"jwt"`
+}
+
+// JWT configures bearer JWT validation next to API keys. Keys come from
+// HS256Secret, a PEM file, a local JWKS file or any combination of them.
+type JWT struct {
+	Enabled        bool          `yaml:"enabled"`
+	HS256Secret    string        `yaml:"hs256_secret" env:"JWT_HS256_SECRET"`
+	RS256PublicKey string        `yaml:"rs256_public_key"`
+	JWKSFile       string        `yaml:"jwks_file"`
+	Issuer         string        `yaml:"issuer"`
+	Audience       string        `yaml:"audience"`
+	ClockSkew      time.Duration `yaml:"clock_skew"`
+	AdminRole      string        `yaml:"admin_role"`
+	// UserScoped limits non-admin tokens to the user whose UUID is in "sub".
+	UserScoped bool `yaml:"user_scoped"`
+}
+
+// Metrics configures /metrics. BusinessInterval is how often the gauges
+// computed from the data (active subscriptions) are refreshed.
+type Metrics struct {
+	Enabled          bool          `yaml:"enabled"`
+	BusinessInterval time.Duration `yaml:"business_interval"`
+}
+
+// Tracing configures OpenTelemetry. Exporter is "otlp" (OTLP/HTTP to
+// Endpoint) or "stdout" for local debugging.
+type Tracing struct {
+	Enabled     bool    `yaml:"enabled"`
+	Exporter    string  `yaml:"exporter"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Health configures /readyz. CheckTimeout bounds all dependency checks
+// of one probe together.
+type Health struct {
+	CheckTimeout time.Duration `yaml:"check_timeout"`
+}
+
+// Purge controls how long soft-deleted records stay in the trash.
+// A zero Retention disables the purge job.
+type Purge struct {
+	Retention time.Duration `yaml:"retention"`
+	Interval  time.Duration `yaml:"interval"`
 }
 
 func MustLoad() *Config {
@@ -61,11 +186,119 @@ func (c *Config) validate() error {
 	if c.App.Env == "" {
 		return errors.New("app.env is required (local|prod)")
 	}
+	if !money.Valid(c.App.DefaultCurrency) {
+		return fmt.Errorf("app.default_currency %q is not an upper-case ISO 4217 code", c.App.DefaultCurrency)
+	}
+	if _, ok := models.DateFormats[strings.ToLower(c.App.DateFormat)]; !ok {
+		return fmt.Errorf("app.date_format %q is unknown (use rfc3339, yyyy-mm-dd, yyyy-mm or mm-yyyy)", c.App.DateFormat)
+	}
 	if c.HTTPServer.Address == "" {
 		return errors.New("http_server.address is required")
 	}
 	if c.Storage.DBURL == "" {
 		return errors.New("DBURL is required")
 	}
+	if st := c.Storage; st.MaxConns < 0 || st.MinConns < 0 {
+		return errors.New("storage.max_conns and storage.min_conns must not be negative")
+	}
+	if st := c.Storage; st.MaxConns > 0 && st.MinConns > st.MaxConns {
+		return errors.New("storage.min_conns must not exceed storage.max_conns")
+	}
+	if t := c.Storage.StatementTimeout; t.Read < 0 || t.Write < 0 || t.Report < 0 || t.Bulk < 0 {
+		return errors.New("storage.statement_timeout values must not be negative")
+	}
+	if err := c.validateTimeouts(); err != nil {
+		return err
+	}
+	if c.Purge.Retention < 0 {
+		return errors.New("purge.retention must not be negative")
+	}
+	if c.Purge.Retention > 0 && c.Purge.Interval <= 0 {
+		return errors.New("purge.interval is required when purge.retention is set")
+	}
+	if c.HTTPServer.ShutdownTimeout <= 0 {
+		return errors.New("http_server.shutdown_timeout must be positive")
+	}
+	if c.HTTPServer.ShutdownDelay < 0 {
+		return errors.New("http_server.shutdown_delay must not be negative")
+	}
+	if c.Health.CheckTimeout <= 0 {
+		return errors.New("health.check_timeout must be positive")
+	}
+	if c.Metrics.Enabled && c.Metrics.BusinessInterval <= 0 {
+		return errors.New("metrics.business_interval is required when metrics are enabled")
+	}
+	if t := c.Tracing; t.Enabled {
+		if t.Exporter != "otlp" && t.Exporter != "stdout" {
+			return errors.New("tracing.exporter must be otlp or stdout")
+		}
+		if t.SampleRatio < 0 || t.SampleRatio > 1 {
+			return errors.New("tracing.sample_ratio must be between 0 and 1")
+		}
+	}
+	if rl := c.HTTPServer.RateLimit; rl.Enabled {
+		if rl.Backend != "memory" && rl.Backend != "postgres" {
+			return errors.New("http_server.rate_limit.backend must be memory or postgres")
+		}
+		for name, g := range rl.Groups {
+			if !slices.Contains(rateLimitGroups, name) {
+				return fmt.Errorf("http_server.rate_limit.groups: unknown group %q (use %s)", name, strings.Join(rateLimitGroups, ", "))
+			}
+			if g.RequestsPerMinute <= 0 || g.Burst <= 0 {
+				return fmt.Errorf("http_server.rate_limit.groups.%s: requests_per_minute and burst must be positive", name)
+			}
+		}
+	}
+	if j := c.Auth.JWT; j.Enabled {
+		if j.HS256Secret == "" && j.RS256PublicKey == "" && j.JWKSFile == "" {
+			return errors.New("auth.jwt needs hs256_secret, rs256_public_key or jwks_file")
+		}
+		if j.HS256Secret != "" && len(j.HS256Secret) < 32 {
+			return errors.New("auth.jwt.hs256_secret must be at least 32 bytes")
+		}
+		if j.ClockSkew < 0 {
+			return errors.New("auth.jwt.clock_skew must not be negative")
+		}
+	}
+	return nil
+}
+
+// validateTimeouts checks that every statement timeout fits the request
+// budget of the route groups running that query class, so that it is not
+// cut short by the request deadline. The server leaves a tenth of the
+// request timeout for writing the response.
+func (c *Config) validateTimeouts() error {
+	h := c.HTTPServer
+	for name, d := range h.GroupTimeouts {
+		if !slices.Contains(routeGroups, name) {
+			return fmt.Errorf("http_server.group_timeouts: unknown group %q (use %s)", name, strings.Join(routeGroups, ", "))
+		}
+		if d <= 0 {
+			return fmt.Errorf("http_server.group_timeouts.%s must be positive", name)
+		}
+	}
+
+	st := c.Storage.StatementTimeout
+	for _, class := range []struct {
+		name    string
+		timeout time.Duration
+		groups  []string
+	}{
+		{"read", st.Read, []string{"read"}},
+		{"write", st.Write, []string{"write"}},
+		{"report", st.Report, []string{"summary", "bulk"}}, // CSV export is a bulk route
+		{"bulk", st.Bulk, []string{"bulk"}},
+	} {
+		for _, g := range class.groups {
+			t := h.TimeoutOf(g)
+			if t <= 0 || class.timeout <= 0 {
+				continue // no deadline on one side
+			}
+			if budget := t - t/10; class.timeout > budget {
+				return fmt.Errorf("storage.statement_timeout.%s (%s) exceeds the %s request budget of %s (90%% of its http_server timeout); raise http_server.group_timeouts.%s",
+					class.name, class.timeout, g, budget, g)
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,120 @@
+// Package cost expands subscriptions into the dates they are charged on.
+//
+// A subscription is charged its price on its start date and then once per
+// billing period, counted from the start date: a monthly subscription that
+// starts on the 31st is charged on the last day of shorter months and on
+// the 31st again afterwards, a yearly one started on 29 February is charged
+// on 28 February in common years. Every date is in UTC and keeps the time of
+// day of the start.
+//
+// The summaries compute the same dates in Postgres with the charge_dates
+// function (migrations/0014_charge_dates); this package is the reference it
+// is tested against, so change both together.
+package cost
+
+import (
+	"time"
+	"user-aggregation/internal/models"
+)
+
+// Schedule is when one subscription is charged.
+type Schedule struct {
+	Period models.BillingPeriod
+	Start  time.Time // the first charge
+	// End stops the schedule: nothing is charged at or after End, except the
+	// first charge itself. The zero value means the subscription is open-ended.
+	End time.Time
+}
+
+// nth is the date of charge n (0 is Start). ok is false for a one-off
+// subscription past its only charge and for an unknown period.
+func (s Schedule) nth(n int) (time.Time, bool) {
+	start := s.Start.UTC()
+	switch s.Period {
+	case models.BillingOneOff:
+		return start, n == 0
+	case models.BillingWeekly:
+		return start.AddDate(0, 0, 7*n), true
+	case models.BillingMonthly:
+		return addMonths(start, n), true
+	case models.BillingYearly:
+		return addMonths(start, 12*n), true
+	}
+	return time.Time{}, false
+}
+
+// addMonths moves t by n months, keeping its day of month unless the target
+// month is shorter; then it is the last day of that month.
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return first.AddDate(0, 0, min(d, daysIn(first))-1)
+}
+
+// daysIn is the number of days in the month of t.
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// firstFrom is a charge number not past the first charge at or after
+// from, estimated from the period length so that long schedules are not
+// walked from the start.
+func (s Schedule) firstFrom(from time.Time) int {
+	start := s.Start.UTC()
+	if !from.After(start) {
+		return 0
+	}
+	var n int
+	switch s.Period {
+	case models.BillingWeekly:
+		n = int(from.Sub(start) / (7 * 24 * time.Hour))
+	case models.BillingMonthly:
+		n = months(start, from) - 1
+	case models.BillingYearly:
+		n = months(start, from)/12 - 1
+	}
+	return max(n, 0)
+}
+
+// months counts calendar month boundaries between a and b.
+func months(a, b time.Time) int {
+	return (b.Year()-a.Year())*12 + int(b.Month()-a.Month())
+}
+
+// Each calls fn with every charge date in [from, to], both inclusive, in
+// order. A zero from or to leaves that side unbounded; an open-ended
+// schedule needs a to.
+func (s Schedule) Each(from, to time.Time, fn func(time.Time)) {
+	if !s.Period.Valid() || (s.End.IsZero() && to.IsZero() && s.Period != models.BillingOneOff) {
+		return
+	}
+	for n := s.firstFrom(from); ; n++ {
+		d, ok := s.nth(n)
+		if !ok {
+			return
+		}
+		if n > 0 && !s.End.IsZero() && !d.Before(s.End) {
+			return
+		}
+		if !to.IsZero() && d.After(to) {
+			return
+		}
+		if from.IsZero() || !d.Before(from) {
+			fn(d)
+		}
+	}
+}
+
+// Charges lists the charge dates in [from, to]; see Each.
+func (s Schedule) Charges(from, to time.Time) []time.Time {
+	var out []time.Time
+	s.Each(from, to, func(d time.Time) { out = append(out, d) })
+	return out
+}
+
+// Count is the number of charges in [from, to]; see Each.
+func (s Schedule) Count(from, to time.Time) int {
+	n := 0
+	s.Each(from, to, func(time.Time) { n++ })
+	return n
+}
@@ -0,0 +1,131 @@
+package cost
+
+import (
+	"testing"
+	"time"
+	"user-aggregation/internal/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCharges_MonthEnd(t *testing.T) {
+	s := Schedule{Period: models.BillingMonthly, Start: date(2025, 1, 31)}
+	require.Equal(t, []time.Time{
+		date(2025, 1, 31),
+		date(2025, 2, 28),
+		date(2025, 3, 31),
+		date(2025, 4, 30),
+		date(2025, 5, 31),
+	}, s.Charges(time.Time{}, date(2025, 5, 31)))
+
+	// the day is taken from the start, not from the previous charge
+	s.Start = date(2025, 1, 30)
+	require.Equal(t, []time.Time{date(2025, 2, 28), date(2025, 3, 30)}, s.Charges(date(2025, 2, 1), date(2025, 3, 31)))
+}
+
+func TestCharges_LeapYear(t *testing.T) {
+	monthly := Schedule{Period: models.BillingMonthly, Start: date(2023, 12, 31)}
+	require.Equal(t, []time.Time{date(2024, 2, 29)}, monthly.Charges(date(2024, 2, 1), date(2024, 2, 29)))
+	require.Equal(t, []time.Time{date(2025, 2, 28)}, monthly.Charges(date(2025, 2, 1), date(2025, 2, 28)))
+
+	yearly := Schedule{Period: models.BillingYearly, Start: date(2024, 2, 29)}
+	require.Equal(t, []time.Time{
+		date(2024, 2, 29),
+		date(2025, 2, 28),
+		date(2026, 2, 28),
+		date(2027, 2, 28),
+		date(2028, 2, 29),
+	}, yearly.Charges(time.Time{}, date(2028, 12, 31)))
+
+	// 2100 is not a leap year
+	require.Equal(t, []time.Time{date(2100, 2, 28)}, yearly.Charges(date(2100, 1, 1), date(2100, 12, 31)))
+
+	weekly := Schedule{Period: models.BillingWeekly, Start: date(2024, 2, 22)}
+	require.Equal(t, []time.Time{date(2024, 2, 29), date(2024, 3, 7)}, weekly.Charges(date(2024, 2, 23), date(2024, 3, 7)))
+}
+
+func TestCharges_KeepsTimeOfDay(t *testing.T) {
+	start := time.Date(2025, 1, 31, 21, 30, 0, 0, time.FixedZone("MSK", 3*3600))
+	s := Schedule{Period: models.BillingMonthly, Start: start}
+
+	// 2025-01-31 21:30 MSK is 18:30 UTC on the 31st
+	require.Equal(t, []time.Time{
+		time.Date(2025, 1, 31, 18, 30, 0, 0, time.UTC),
+		time.Date(2025, 2, 28, 18, 30, 0, 0, time.UTC),
+	}, s.Charges(time.Time{}, date(2025, 3, 1)))
+
+	// a bound inside the charge day still counts by the instant
+	require.Empty(t, s.Charges(time.Date(2025, 2, 28, 18, 31, 0, 0, time.UTC), date(2025, 3, 30)))
+}
+
+func TestCharges_End(t *testing.T) {
+	s := Schedule{Period: models.BillingMonthly, Start: date(2025, 1, 1), End: date(2025, 12, 31)}
+	require.Equal(t, 12, s.Count(time.Time{}, time.Time{}))
+
+	// ending on a renewal date does not renew
+	s.End = date(2025, 3, 1)
+	require.Equal(t, []time.Time{date(2025, 1, 1), date(2025, 2, 1)}, s.Charges(time.Time{}, time.Time{}))
+
+	// the first charge is made even for a same-day subscription
+	s.End = s.Start
+	require.Equal(t, []time.Time{date(2025, 1, 1)}, s.Charges(time.Time{}, time.Time{}))
+
+	yearly := Schedule{Period: models.BillingYearly, Start: date(2025, 1, 1), End: date(2026, 1, 1)}
+	require.Equal(t, 1, yearly.Count(time.Time{}, time.Time{}))
+}
+
+func TestCharges_OpenEnded(t *testing.T) {
+	s := Schedule{Period: models.BillingWeekly, Start: date(2025, 1, 1)}
+	require.Empty(t, s.Charges(date(2025, 1, 1), time.Time{}), "unbounded on both sides")
+	require.Equal(t, 5, s.Count(time.Time{}, date(2025, 1, 29)))
+}
+
+func TestCharges_OneOff(t *testing.T) {
+	s := Schedule{Period: models.BillingOneOff, Start: date(2025, 5, 10), End: date(2030, 1, 1)}
+	require.Equal(t, []time.Time{date(2025, 5, 10)}, s.Charges(time.Time{}, time.Time{}))
+	require.Empty(t, s.Charges(date(2025, 5, 11), date(2025, 12, 31)))
+	require.Equal(t, 1, s.Count(date(2025, 5, 10), date(2025, 5, 10)))
+
+	open := Schedule{Period: models.BillingOneOff, Start: date(2025, 5, 10)}
+	require.Equal(t, 1, open.Count(time.Time{}, time.Time{}))
+}
+
+func TestCharges_Bounds(t *testing.T) {
+	s := Schedule{Period: models.BillingMonthly, Start: date(2025, 1, 15), End: date(2026, 1, 1)}
+
+	require.Equal(t, []time.Time{date(2025, 3, 15)}, s.Charges(date(2025, 3, 15), date(2025, 3, 15)), "both bounds inclusive")
+	require.Empty(t, s.Charges(date(2025, 3, 16), date(2025, 4, 14)))
+	require.Empty(t, s.Charges(date(2024, 1, 1), date(2025, 1, 14)), "before the start")
+	require.Empty(t, s.Charges(date(2026, 1, 1), date(2027, 1, 1)), "after the end")
+	require.Equal(t, 12, s.Count(date(2000, 1, 1), date(2100, 1, 1)))
+}
+
+func TestCharges_UnknownPeriod(t *testing.T) {
+	s := Schedule{Period: "daily", Start: date(2025, 1, 1), End: date(2025, 2, 1)}
+	require.Empty(t, s.Charges(time.Time{}, time.Time{}))
+}
+
+// TestCharges_SkipAhead checks that starting from an estimated charge
+// gives the same dates as walking from the start.
+func TestCharges_SkipAhead(t *testing.T) {
+	for _, p := range []models.BillingPeriod{models.BillingWeekly, models.BillingMonthly, models.BillingYearly} {
+		for _, start := range []time.Time{date(2020, 2, 29), date(2021, 1, 31), date(2021, 8, 31), time.Date(2022, 3, 31, 23, 0, 0, 0, time.UTC)} {
+			s := Schedule{Period: p, Start: start, End: date(2031, 1, 1)}
+			all := s.Charges(time.Time{}, time.Time{})
+			for from := date(2019, 12, 1); from.Before(date(2031, 2, 1)); from = from.AddDate(0, 0, 13) {
+				to := from.AddDate(0, 4, 3)
+				var want []time.Time
+				for _, d := range all {
+					if !d.Before(from) && !d.After(to) {
+						want = append(want, d)
+					}
+				}
+				require.Equal(t, want, s.Charges(from, to), "%s from %s, window %s", p, start, from)
+			}
+		}
+	}
+}
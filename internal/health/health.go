@@ -0,0 +1,196 @@
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/transport/http/respond"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusFail         = "fail"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check is one readiness check. Run returns details for the JSON breakdown
+// and an error if the dependency is not usable.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (map[string]any, error)
+}
+
+// Checker runs the readiness checks. It reports not ready once Drain is
+// called, so load balancers stop sending traffic before the server stops.
+type Checker struct {
+	checks   []Check
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+// New returns a Checker that gives all checks together timeout to finish.
+func New(timeout time.Duration, checks ...Check) *Checker {
+	return &Checker{checks: checks, timeout: timeout}
+}
+
+// Drain makes every following readiness probe fail.
+func (c *Checker) Drain() {
+	if c == nil {
+		return
+	}
+	c.draining.Store(true)
+}
+
+// Live godoc
+// @Summary Liveness probe
+// @Description Returns 200 while the process is running. Does not touch dependencies.
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string "ok"
+// @Router /livez [get]
+func (c *Checker) Live(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Pings the database, checks the migration version and reports pool usage.
+// @Description Fails with 503 when a check fails or the server is shutting down.
+// @Tags health
+// @Produce json
+// @Success 200 {object} response.Readiness
+// @Failure 503 {object} response.Readiness
+// @Router /readyz [get]
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	const op = "health.ready"
+
+	out := c.Run(r.Context())
+	code := http.StatusOK
+	if out.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	respond.Writer(w, r, op, code, out)
+}
+
+// Run executes all checks concurrently.
+func (c *Checker) Run(ctx context.Context) response.Readiness {
+	out := response.Readiness{Status: StatusOK, Checks: make(map[string]response.CheckResult, len(c.checks))}
+	if c.draining.Load() {
+		out.Status = StatusShuttingDown
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	results := make([]response.CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, ch := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, ch)
+		}()
+	}
+	wg.Wait()
+
+	for i, ch := range c.checks {
+		out.Checks[ch.Name] = results[i]
+		if results[i].Status != StatusOK && out.Status == StatusOK {
+			out.Status = StatusFail
+		}
+	}
+	return out
+}
+
+func run(ctx context.Context, ch Check) (res response.CheckResult) {
+	start := time.Now()
+	defer func() {
+		if p := recover(); p != nil {
+			res = response.CheckResult{Status: StatusFail, Error: fmt.Sprint("panic: ", p)}
+		}
+		res.DurationMS = time.Since(start).Milliseconds()
+	}()
+
+	details, err := ch.Run(ctx)
+	res = response.CheckResult{Status: StatusOK, Details: details}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// Pinger is a database that can be pinged.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Database checks that the database answers.
+func Database(db Pinger) Check {
+	return Check{Name: "database", Run: func(ctx context.Context) (map[string]any, error) {
+		return nil, db.Ping(ctx)
+	}}
+}
+
+// Migrator reports the applied migration version.
+type Migrator interface {
+	MigrationVersion(ctx context.Context) (version int64, dirty bool, err error)
+}
+
+// Migrations checks that the schema is at least at version want and that
+// no migration was left half applied. A newer schema is fine: migrations
+// are applied before the new code rolls out.
+func Migrations(db Migrator, want int64) Check {
+	return Check{Name: "migrations", Run: func(ctx context.Context) (map[string]any, error) {
+		v, dirty, err := db.MigrationVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		details := map[string]any{"version": v, "expected": want, "dirty": dirty}
+		switch {
+		case dirty:
+			return details, fmt.Errorf("migration %d is dirty", v)
+		case v < want:
+			return details, fmt.Errorf("schema version %d is behind %d", v, want)
+		}
+		return details, nil
+	}}
+}
+
+// Pool reports how full the connection pool is. It never fails: a busy
+// pool means load, and taking every replica out of rotation would not help.
+func Pool(stat func() *pgxpool.Stat) Check {
+	return Check{Name: "pool", Run: func(context.Context) (map[string]any, error) {
+		s := stat()
+		return poolDetails(s.AcquiredConns(), s.IdleConns(), s.TotalConns(), s.MaxConns(), s.EmptyAcquireCount()), nil
+	}}
+}
+
+func poolDetails(acquired, idle, total, maxConns int32, waited int64) map[string]any {
+	usage := 0.0
+	if maxConns > 0 {
+		usage = float64(acquired) / float64(maxConns)
+	}
+	return map[string]any{
+		"acquired": acquired,
+		"idle":     idle,
+		"total":    total,
+		"max":      maxConns,
+		"usage":    usage,
+		// acquires that had to wait for a connection since start
+		"waited": waited,
+	}
+}
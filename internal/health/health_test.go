@@ -0,0 +1,132 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-aggregation/internal/models/response"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDB struct {
+	pingErr error
+	version int64
+	dirty   bool
+	block   bool
+}
+
+func (f fakeDB) Ping(ctx context.Context) error {
+	if f.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return f.pingErr
+}
+
+func (f fakeDB) MigrationVersion(context.Context) (int64, bool, error) {
+	return f.version, f.dirty, nil
+}
+
+func ready(t *testing.T, c *Checker) (int, response.Readiness) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c.Ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var out response.Readiness
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	return w.Code, out
+}
+
+func TestReady(t *testing.T) {
+	db := fakeDB{version: 9}
+	c := New(time.Second, Database(db), Migrations(db, 9))
+
+	code, out := ready(t, c)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, StatusOK, out.Status)
+	require.Equal(t, StatusOK, out.Checks["database"].Status)
+	require.Equal(t, StatusOK, out.Checks["migrations"].Status)
+	require.EqualValues(t, 9, out.Checks["migrations"].Details["version"])
+}
+
+func TestReady_FailingChecks(t *testing.T) {
+	cases := map[string]struct {
+		db    fakeDB
+		check string
+	}{
+		"ping fails":      {fakeDB{pingErr: errors.New("connection refused"), version: 9}, "database"},
+		"schema behind":   {fakeDB{version: 8}, "migrations"},
+		"dirty migration": {fakeDB{version: 9, dirty: true}, "migrations"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			c := New(time.Second, Database(tc.db), Migrations(tc.db, 9))
+
+			code, out := ready(t, c)
+			require.Equal(t, http.StatusServiceUnavailable, code)
+			require.Equal(t, StatusFail, out.Status)
+			require.Equal(t, StatusFail, out.Checks[tc.check].Status)
+			require.NotEmpty(t, out.Checks[tc.check].Error)
+		})
+	}
+
+	// a newer schema is fine
+	db := fakeDB{version: 10}
+	code, _ := ready(t, New(time.Second, Migrations(db, 9)))
+	require.Equal(t, http.StatusOK, code)
+}
+
+func TestReady_Timeout(t *testing.T) {
+	c := New(20*time.Millisecond, Database(fakeDB{block: true}))
+
+	start := time.Now()
+	code, out := ready(t, c)
+	require.Less(t, time.Since(start), time.Second)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Contains(t, out.Checks["database"].Error, "deadline exceeded")
+}
+
+func TestReady_Draining(t *testing.T) {
+	c := New(time.Second, Database(fakeDB{}))
+	code, _ := ready(t, c)
+	require.Equal(t, http.StatusOK, code)
+
+	c.Drain()
+	code, out := ready(t, c)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, StatusShuttingDown, out.Status)
+	require.Equal(t, StatusOK, out.Checks["database"].Status)
+
+	var nilChecker *Checker
+	nilChecker.Drain()
+}
+
+func TestReady_PanickingCheck(t *testing.T) {
+	c := New(time.Second, Check{Name: "boom", Run: func(context.Context) (map[string]any, error) {
+		panic("oops")
+	}})
+
+	code, out := ready(t, c)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Contains(t, out.Checks["boom"].Error, "oops")
+}
+
+func TestPoolDetails(t *testing.T) {
+	d := poolDetails(3, 1, 4, 4, 7)
+	require.Equal(t, 0.75, d["usage"])
+	require.EqualValues(t, 7, d["waited"])
+
+	require.Equal(t, 0.0, poolDetails(0, 0, 0, 0, 0)["usage"])
+}
+
+func TestLive(t *testing.T) {
+	w := httptest.NewRecorder()
+	New(0).Live(w, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "ok", w.Body.String())
+}
@@ -31,7 +31,7 @@ func Init(env string) (*slog.Logger, func()) {
 		}.
 			NewZapHandler()
 
-		l := slog.New(handler).With(
+		l := slog.New(withTrace(handler)).With(
 			"app", "user_aggregation",
 			"env", env,
 		)
@@ -39,13 +39,14 @@ func Init(env string) (*slog.Logger, func()) {
 		return l, cleanup
 
 	default:
-		l := sweetLogger.New(sweetLogger.Options{
+		sl := sweetLogger.New(sweetLogger.Options{
 			Level:      slog.LevelDebug,
 			AddSource:  true,
 			TimeFormat: "2006-01-02 15:04:05",
 			Color:      sweetLogger.ColorAuto,
 			Writer:     os.Stderr,
-		}).With("app", "user_aggregation", "env", env)
+		})
+		l := slog.New(withTrace(sl.Handler())).With("app", "user_aggregation", "env", env)
 
 		return l, func() {}
 	}
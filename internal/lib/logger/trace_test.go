@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceHandler(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(withTrace(slog.NewJSONHandler(&buf, nil))).With("app", "test")
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3},
+		SpanID:  trace.SpanID{4, 5, 6},
+	})
+	log.InfoContext(trace.ContextWithSpanContext(context.Background(), sc), "hello")
+
+	var rec map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &rec))
+	require.Equal(t, sc.TraceID().String(), rec["trace_id"])
+	require.Equal(t, sc.SpanID().String(), rec["span_id"])
+	require.Equal(t, "test", rec["app"])
+
+	buf.Reset()
+	log.Info("no span")
+	require.NotContains(t, buf.String(), "trace_id")
+}
@@ -0,0 +1,51 @@
+// Package reqctx carries request-scoped values (who is acting, which request
+// it is, the request's logger) from the HTTP layer down to the repository.
+package reqctx
+
+import (
+	"context"
+	"log/slog"
+)
+
+type ctxKey int
+
+const (
+	actorKey ctxKey = iota
+	requestIDKey
+	loggerKey
+)
+
+// WithActor returns a copy of ctx that carries the actor.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns the actor stored in ctx or "".
+func Actor(ctx context.Context) string {
+	s, _ := ctx.Value(actorKey).(string)
+	return s
+}
+
+// WithRequestID returns a copy of ctx that carries the request ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID stored in ctx or "".
+func RequestID(ctx context.Context) string {
+	s, _ := ctx.Value(requestIDKey).(string)
+	return s
+}
+
+// WithLogger returns a copy of ctx that carries the request-scoped logger.
+func WithLogger(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, log)
+}
+
+// Logger returns the logger stored in ctx, or slog.Default() outside a request.
+func Logger(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
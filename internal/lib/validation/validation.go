@@ -0,0 +1,168 @@
+// Package validation checks subscription payloads before they reach the repo
+// and reports every problem as a (field, code) pair.
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/money"
+
+	"github.com/google/uuid"
+)
+
+// Error codes returned in FieldError.Code.
+const (
+	CodeRequired    = "required"
+	CodeNegative    = "negative"
+	CodeBeforeStart = "before_start"
+	CodeTooLong     = "too_long"
+	CodeInvalid     = "invalid"
+	CodeSameAsBase  = "same_as_base"
+)
+
+// MaxServiceNameLen limits service_name to a sane length.
+const MaxServiceNameLen = 255
+
+// FieldError is a single failed check.
+type FieldError struct {
+	Field string
+	Code  string
+}
+
+// Errors is the list of failed checks; empty means the payload is valid.
+// It implements error so it can be wrapped and logged like any other.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, 0, len(e))
+	for _, fe := range e {
+		parts = append(parts, fe.Field+": "+fe.Code)
+	}
+	return "validation failed: " + strings.Join(parts, ", ")
+}
+
+func (e *Errors) add(field, code string) {
+	*e = append(*e, FieldError{Field: field, Code: code})
+}
+
+// UserInfo validates a record before it is created.
+func UserInfo(u *models.UserInfo) Errors {
+	var errs Errors
+	if u == nil {
+		errs.add("body", CodeRequired)
+		return errs
+	}
+
+	// the name is stored as is, so callers trim it first
+	switch name := u.ServiceName; {
+	case strings.TrimSpace(name) == "":
+		errs.add("service_name", CodeRequired)
+	case strings.TrimSpace(name) != name:
+		errs.add("service_name", CodeInvalid)
+	case len(name) > MaxServiceNameLen:
+		errs.add("service_name", CodeTooLong)
+	}
+	if u.Price < 0 {
+		errs.add("price", CodeNegative)
+	}
+	switch {
+	case u.Currency == "":
+		errs.add("currency", CodeRequired)
+	case !money.Valid(u.Currency):
+		errs.add("currency", CodeInvalid)
+	}
+	if !u.BillingPeriod.Valid() {
+		errs.add("billing_period", CodeInvalid)
+	}
+	if u.UserID == uuid.Nil {
+		errs.add("user_id", CodeRequired)
+	}
+	if u.StartDate.IsZero() {
+		errs.add("start_date", CodeRequired)
+	}
+	// a missing end_date is an open-ended subscription
+	switch {
+	case u.EndDate == nil:
+	case u.EndDate.IsZero():
+		errs.add("end_date", CodeRequired)
+	case !u.StartDate.IsZero() && u.EndDate.Before(u.StartDate.Time):
+		errs.add("end_date", CodeBeforeStart)
+	}
+	return errs
+}
+
+// Update validates a partial update. end_date is not compared with
+// start_date here: the rows being patched are not loaded, so that rule is
+// left to the user_info_end_after_start constraint, which the repo reports
+// as repo.ErrEndBeforeStart.
+func Update(u *models.UpdateUserInfo) Errors {
+	var errs Errors
+	if u == nil {
+		errs.add("body", CodeRequired)
+		return errs
+	}
+
+	if u.Price != nil && *u.Price < 0 {
+		errs.add("price", CodeNegative)
+	}
+	if u.EndDate.Time != nil && u.EndDate.Time.IsZero() {
+		errs.add("end_date", CodeRequired)
+	}
+	if u.EffectiveFrom != nil {
+		if u.EffectiveFrom.IsZero() {
+			errs.add("effective_from", CodeRequired)
+		}
+		// an effective date on its own changes nothing
+		if u.Price == nil {
+			errs.add("price", CodeRequired)
+		}
+	}
+	return errs
+}
+
+// rateRe matches what fits the numeric(20,10) rate column.
+var rateRe = regexp.MustCompile(`^[0-9]{1,10}(\.[0-9]{1,10})?$`)
+
+// ExchangeRates validates rates before they are saved. Currency codes are
+// expected to be normalized; fields are reported as "[i].name".
+func ExchangeRates(rates []models.ExchangeRate) Errors {
+	var errs Errors
+	if len(rates) == 0 {
+		errs.add("body", CodeRequired)
+		return errs
+	}
+
+	for i, r := range rates {
+		field := func(name string) string { return fmt.Sprintf("[%d].%s", i, name) }
+		checkCode(&errs, field("base"), r.Base)
+		checkCode(&errs, field("quote"), r.Quote)
+		if r.Base != "" && r.Base == r.Quote {
+			errs.add(field("quote"), CodeSameAsBase)
+		}
+		switch s := string(r.Rate); {
+		case s == "":
+			errs.add(field("rate"), CodeRequired)
+		case !rateRe.MatchString(s):
+			errs.add(field("rate"), CodeInvalid)
+		default:
+			if _, ok := money.ParseRate(s); !ok {
+				errs.add(field("rate"), CodeInvalid)
+			}
+		}
+		if r.EffectiveFrom.IsZero() {
+			errs.add(field("effective_from"), CodeRequired)
+		}
+	}
+	return errs
+}
+
+func checkCode(errs *Errors, field, code string) {
+	switch {
+	case code == "":
+		errs.add(field, CodeRequired)
+	case !money.Valid(code):
+		errs.add(field, CodeInvalid)
+	}
+}
@@ -0,0 +1,114 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"user-aggregation/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func validInfo() models.UserInfo {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	return models.UserInfo{
+		ServiceName:   "Netflix",
+		Price:         999,
+		Currency:      "RUB",
+		BillingPeriod: models.BillingMonthly,
+		UserID:        uuid.New(),
+		StartDate:     models.Date{Time: start},
+		EndDate:       &models.EndDate{Time: end},
+	}
+}
+
+func TestUserInfo_Valid(t *testing.T) {
+	u := validInfo()
+	require.Empty(t, UserInfo(&u))
+
+	u.EndDate = &models.EndDate{Time: u.StartDate.Time}
+	require.Empty(t, UserInfo(&u), "same-day subscription is fine")
+
+	u.EndDate = nil
+	require.Empty(t, UserInfo(&u), "open-ended subscription is fine")
+}
+
+func TestUserInfo_Errors(t *testing.T) {
+	cases := []struct {
+		name   string
+		mutate func(u *models.UserInfo)
+		want   Errors
+	}{
+		{"blank service", func(u *models.UserInfo) { u.ServiceName = "  " }, Errors{{"service_name", CodeRequired}}},
+		{"untrimmed service", func(u *models.UserInfo) { u.ServiceName = " Netflix " }, Errors{{"service_name", CodeInvalid}}},
+		{"long service", func(u *models.UserInfo) { u.ServiceName = strings.Repeat("x", MaxServiceNameLen+1) }, Errors{{"service_name", CodeTooLong}}},
+		{"negative price", func(u *models.UserInfo) { u.Price = -1 }, Errors{{"price", CodeNegative}}},
+		{"no currency", func(u *models.UserInfo) { u.Currency = "" }, Errors{{"currency", CodeRequired}}},
+		{"unknown currency", func(u *models.UserInfo) { u.Currency = "ABC" }, Errors{{"currency", CodeInvalid}}},
+		{"unknown billing period", func(u *models.UserInfo) { u.BillingPeriod = "daily" }, Errors{{"billing_period", CodeInvalid}}},
+		{"nil user", func(u *models.UserInfo) { u.UserID = uuid.Nil }, Errors{{"user_id", CodeRequired}}},
+		{"no start", func(u *models.UserInfo) { u.StartDate = models.Date{} }, Errors{{"start_date", CodeRequired}}},
+		{"zero end", func(u *models.UserInfo) { u.EndDate = &models.EndDate{} }, Errors{{"end_date", CodeRequired}}},
+		{"end before start", func(u *models.UserInfo) {
+			u.EndDate = &models.EndDate{Time: u.StartDate.Add(-time.Second)}
+		}, Errors{{"end_date", CodeBeforeStart}}},
+		{"everything", func(u *models.UserInfo) { *u = models.UserInfo{Price: -5} }, Errors{
+			{"service_name", CodeRequired},
+			{"price", CodeNegative},
+			{"currency", CodeRequired},
+			{"billing_period", CodeInvalid},
+			{"user_id", CodeRequired},
+			{"start_date", CodeRequired},
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			u := validInfo()
+			tc.mutate(&u)
+			require.Equal(t, tc.want, UserInfo(&u))
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	price := int64(-1)
+	var zero time.Time
+	require.Equal(t, Errors{{"price", CodeNegative}, {"end_date", CodeRequired}},
+		Update(&models.UpdateUserInfo{Price: &price, EndDate: models.NullableTime{Set: true, Time: &zero}}))
+
+	price = 0
+	end := time.Now()
+	require.Empty(t, Update(&models.UpdateUserInfo{Price: &price, EndDate: models.NullableTime{Set: true, Time: &end}}))
+	require.Empty(t, Update(&models.UpdateUserInfo{EndDate: models.NullableTime{Set: true}}), "null clears the end date")
+
+	require.Equal(t, Errors{{"effective_from", CodeRequired}, {"price", CodeRequired}},
+		Update(&models.UpdateUserInfo{EndDate: models.NullableTime{Set: true, Time: &end}, EffectiveFrom: &zero}))
+	require.Empty(t, Update(&models.UpdateUserInfo{Price: &price, EffectiveFrom: &end}))
+}
+
+func TestExchangeRates(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.Equal(t, Errors{{"body", CodeRequired}}, ExchangeRates(nil))
+	require.Empty(t, ExchangeRates([]models.ExchangeRate{
+		{Base: "USD", Quote: "RUB", Rate: "92.5", EffectiveFrom: day},
+		{Base: "EUR", Quote: "USD", Rate: "0.0000000001", EffectiveFrom: day},
+	}))
+
+	require.Equal(t, Errors{
+		{"[0].base", CodeRequired},
+		{"[0].quote", CodeInvalid},
+		{"[0].rate", CodeRequired},
+		{"[0].effective_from", CodeRequired},
+		{"[1].quote", CodeSameAsBase},
+		{"[1].rate", CodeInvalid},
+		{"[2].rate", CodeInvalid},
+		{"[3].rate", CodeInvalid},
+	}, ExchangeRates([]models.ExchangeRate{
+		{Quote: "XYZ"},
+		{Base: "USD", Quote: "USD", Rate: "-1", EffectiveFrom: day},
+		{Base: "USD", Quote: "RUB", Rate: "0", EffectiveFrom: day},
+		{Base: "USD", Quote: "RUB", Rate: "1e3", EffectiveFrom: day},
+	}))
+}
@@ -0,0 +1,121 @@
+// Package lifecycle shuts the service down in a fixed order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// phase is one shutdown step. A zero timeout leaves only the deadline of
+// the context passed to Shutdown.
+type phase struct {
+	name    string
+	timeout time.Duration
+	run     func(ctx context.Context) error
+}
+
+// Manager collects shutdown phases as resources come up and runs them in
+// reverse order, like deferred calls: whatever started last stops first.
+type Manager struct {
+	log *slog.Logger
+
+	mu     sync.Mutex
+	phases []phase
+	done   bool
+}
+
+func New(log *slog.Logger) *Manager {
+	return &Manager{log: log}
+}
+
+// OnShutdown registers a phase. It runs before every phase registered
+// earlier.
+func (m *Manager) OnShutdown(name string, timeout time.Duration, run func(ctx context.Context) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.phases = append(m.phases, phase{name: name, timeout: timeout, run: run})
+}
+
+// Shutdown runs every phase once, logging each. A failing phase does not
+// stop the ones after it; all errors are returned together. Later calls
+// do nothing.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	const op = "lifecycle.shutdown"
+
+	m.mu.Lock()
+	if m.done {
+		m.mu.Unlock()
+		return nil
+	}
+	m.done = true
+	phases := m.phases
+	m.mu.Unlock()
+
+	start := time.Now()
+	m.log.Info("shutting down", slog.String("op", op), slog.Int("phases", len(phases)))
+
+	var errs []error
+	for i := len(phases) - 1; i >= 0; i-- {
+		p := phases[i]
+		step := len(phases) - i
+		m.log.Info("shutdown phase started",
+			slog.String("op", op),
+			slog.String("phase", p.name),
+			slog.Int("step", step),
+		)
+
+		began := time.Now()
+		if err := runPhase(ctx, p); err != nil {
+			m.log.Error("shutdown phase failed",
+				slog.String("op", op),
+				slog.String("phase", p.name),
+				slog.Duration("duration", time.Since(began)),
+				slog.Any("error", err),
+			)
+			errs = append(errs, fmt.Errorf("%s: %w", p.name, err))
+			continue
+		}
+		m.log.Info("shutdown phase done",
+			slog.String("op", op),
+			slog.String("phase", p.name),
+			slog.Duration("duration", time.Since(began)),
+		)
+	}
+
+	m.log.Info("shutdown complete", slog.String("op", op), slog.Duration("duration", time.Since(start)))
+	return errors.Join(errs...)
+}
+
+func runPhase(ctx context.Context, p phase) (err error) {
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.timeout)
+		defer cancel()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return p.run(ctx)
+}
+
+// Sleep waits for d or until ctx is done, for phases that give the outside
+// world time to notice a change.
+func Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,84 @@
+package lifecycle
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdown_ReverseOrder(t *testing.T) {
+	var buf bytes.Buffer
+	m := New(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	var order []string
+	for _, name := range []string{"flush logger", "close pool", "drain", "mark not ready"} {
+		m.OnShutdown(name, 0, func(context.Context) error {
+			order = append(order, name)
+			return nil
+		})
+	}
+
+	require.NoError(t, m.Shutdown(context.Background()))
+	require.Equal(t, []string{"mark not ready", "drain", "close pool", "flush logger"}, order)
+
+	for _, name := range order {
+		require.Contains(t, buf.String(), `"phase":"`+name+`"`)
+	}
+	require.Equal(t, 4, strings.Count(buf.String(), "shutdown phase done"))
+
+	// only the first call runs the phases
+	require.NoError(t, m.Shutdown(context.Background()))
+	require.Len(t, order, 4)
+}
+
+func TestShutdown_ContinuesAfterFailure(t *testing.T) {
+	m := New(slog.New(slog.DiscardHandler))
+
+	ran := false
+	m.OnShutdown("last", 0, func(context.Context) error {
+		ran = true
+		return nil
+	})
+	m.OnShutdown("panics", 0, func(context.Context) error { panic("boom") })
+	m.OnShutdown("fails", 0, func(context.Context) error { return errors.New("broken") })
+
+	err := m.Shutdown(context.Background())
+	require.True(t, ran)
+	require.ErrorContains(t, err, "fails: broken")
+	require.ErrorContains(t, err, "panics: panic: boom")
+}
+
+func TestShutdown_PhaseTimeout(t *testing.T) {
+	m := New(slog.New(slog.DiscardHandler))
+
+	var next time.Duration
+	m.OnShutdown("next", 0, func(ctx context.Context) error {
+		if d, ok := ctx.Deadline(); ok {
+			next = time.Until(d)
+		}
+		return nil
+	})
+	m.OnShutdown("slow", 20*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	err := m.Shutdown(context.Background())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Zero(t, next, "a phase timeout does not carry over to the next phase")
+}
+
+func TestSleep(t *testing.T) {
+	require.NoError(t, Sleep(context.Background(), 0))
+	require.NoError(t, Sleep(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, Sleep(ctx, time.Hour), context.Canceled)
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// ActiveCounter is the part of the postgres repo the business gauges need.
+type ActiveCounter interface {
+	ActiveByService(ctx context.Context) (map[string]int64, error)
+}
+
+// RunBusiness refreshes the business gauges right away and then every
+// interval until ctx is done. The query runs here rather than on scrape so
+// that scrapes never wait for the database.
+func (m *Metrics) RunBusiness(ctx context.Context, log *slog.Logger, src ActiveCounter, interval time.Duration) {
+	const op = "metrics.run_business"
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if err := m.refreshBusiness(ctx, src); err != nil && ctx.Err() == nil {
+			log.Warn("refresh business metrics failed", slog.String("op", op), slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (m *Metrics) refreshBusiness(ctx context.Context, src ActiveCounter) error {
+	active, err := src.ActiveByService(ctx)
+	if err != nil {
+		return err
+	}
+	// services without active subscriptions must disappear, not keep their last value
+	m.active.Reset()
+	for service, n := range active {
+		m.active.WithLabelValues(service).Set(float64(n))
+	}
+	return nil
+}
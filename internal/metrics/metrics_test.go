@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware_RouteTemplate(t *testing.T) {
+	m := New()
+	r := mux.NewRouter()
+	r.Use(m.Middleware)
+	r.Methods(http.MethodGet).Path("/users/{id}").HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Methods(http.MethodGet).Path("/users").HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("[]"))
+	})
+
+	for _, path := range []string{"/users/" + uuid.NewString(), "/users/" + uuid.NewString(), "/users"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/users/{id}", "GET", "404")))
+	require.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/users", "GET", "200")))
+	require.Equal(t, 2, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestRepo_ObservesCalls(t *testing.T) {
+	m := New()
+	db := new(mocks.RepoMock)
+	r := m.Repo(db)
+	ctx := context.Background()
+
+	db.On("GetByID", mock.Anything, mock.Anything).Return(models.UserInfo{}, repo.ErrNotFound).Once()
+	db.On("DeleteByID", mock.Anything, mock.Anything).Return(errors.New("boom")).Once()
+
+	_, err := r.GetByID(ctx, uuid.New())
+	require.ErrorIs(t, err, repo.ErrNotFound)
+	require.Error(t, r.DeleteByID(ctx, uuid.New()))
+
+	require.Equal(t, 2, testutil.CollectAndCount(m.dbDuration))
+	require.Equal(t, 0.0, testutil.ToFloat64(m.dbErrors.WithLabelValues("GetByID")), "not found is not an error")
+	require.Equal(t, 1.0, testutil.ToFloat64(m.dbErrors.WithLabelValues("DeleteByID")))
+	db.AssertExpectations(t)
+}
+
+type fakeActive map[string]int64
+
+func (f fakeActive) ActiveByService(context.Context) (map[string]int64, error) { return f, nil }
+
+func TestRefreshBusiness(t *testing.T) {
+	m := New()
+	require.NoError(t, m.refreshBusiness(context.Background(), fakeActive{"Netflix": 3, "Spotify": 1}))
+	require.Equal(t, 3.0, testutil.ToFloat64(m.active.WithLabelValues("Netflix")))
+
+	require.NoError(t, m.refreshBusiness(context.Background(), fakeActive{"Spotify": 2}))
+	require.Equal(t, 1, testutil.CollectAndCount(m.active), "Netflix is gone")
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(w.Body)
+	require.True(t, strings.Contains(string(body), `subscriptions_active{service_name="Spotify"} 2`))
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterPool exports the statistics of a pgx pool, read on every scrape.
+func (m *Metrics) RegisterPool(stat func() *pgxpool.Stat) {
+	m.reg.MustRegister(&poolCollector{stat: stat})
+}
+
+var (
+	poolAcquired = prometheus.NewDesc("db_pool_acquired_connections", "Connections currently in use.", nil, nil)
+	poolIdle     = prometheus.NewDesc("db_pool_idle_connections", "Idle connections in the pool.", nil, nil)
+	poolTotal    = prometheus.NewDesc("db_pool_total_connections", "All open connections, including ones being set up.", nil, nil)
+	poolMax      = prometheus.NewDesc("db_pool_max_connections", "Maximum size of the pool.", nil, nil)
+	poolAcquires = prometheus.NewDesc("db_pool_acquires_total", "Successful connection acquisitions.", nil, nil)
+	poolWaited   = prometheus.NewDesc("db_pool_empty_acquires_total", "Acquisitions that had to wait for a free connection.", nil, nil)
+	poolCanceled = prometheus.NewDesc("db_pool_canceled_acquires_total", "Acquisitions canceled by their context.", nil, nil)
+	poolWait     = prometheus.NewDesc("db_pool_acquire_wait_seconds_total", "Total time spent acquiring connections.", nil, nil)
+)
+
+type poolCollector struct {
+	stat func() *pgxpool.Stat
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{poolAcquired, poolIdle, poolTotal, poolMax, poolAcquires, poolWaited, poolCanceled, poolWait} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotal, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMax, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolWaited, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceled, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolWait, prometheus.CounterValue, s.AcquireDuration().Seconds())
+}
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/repo"
+
+	"github.com/google/uuid"
+)
+
+// Repo wraps r so that every call is timed under its method name.
+func (m *Metrics) Repo(r repo.Repo) repo.Repo {
+	return &observedRepo{next: r, m: m}
+}
+
+type observedRepo struct {
+	next repo.Repo
+	m    *Metrics
+}
+
+func (o *observedRepo) observe(method string, start time.Time, err error) {
+	o.m.dbDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		o.m.dbErrors.WithLabelValues(method).Inc()
+	}
+}
+
+func (o *observedRepo) Insert(ctx context.Context, u *models.UserInfo) (err error) {
+	defer func(start time.Time) { o.observe("Insert", start, err) }(time.Now())
+	return o.next.Insert(ctx, u)
+}
+
+func (o *observedRepo) Upsert(ctx context.Context, u *models.UserInfo) (created bool, err error) {
+	defer func(start time.Time) { o.observe("Upsert", start, err) }(time.Now())
+	return o.next.Upsert(ctx, u)
+}
+
+func (o *observedRepo) BulkUpsert(ctx context.Context, items []models.UserInfo, atomic bool) (res []repo.BulkResult, err error) {
+	defer func(start time.Time) { o.observe("BulkUpsert", start, err) }(time.Now())
+	return o.next.BulkUpsert(ctx, items, atomic)
+}
+
+func (o *observedRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) (n int64, err error) {
+	defer func(start time.Time) { o.observe("DeleteByUserID", start, err) }(time.Now())
+	return o.next.DeleteByUserID(ctx, userID)
+}
+
+func (o *observedRepo) UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (n int64, err error) {
+	defer func(start time.Time) { o.observe("UpdateUserInfo", start, err) }(time.Now())
+	return o.next.UpdateUserInfo(ctx, userID, price, end, priceFrom)
+}
+
+func (o *observedRepo) List(ctx context.Context) (out []models.UserInfo, err error) {
+	defer func(start time.Time) { o.observe("List", start, err) }(time.Now())
+	return o.next.List(ctx)
+}
+
+func (o *observedRepo) ListPage(ctx context.Context, params repo.ListParams) (page repo.Page, err error) {
+	defer func(start time.Time) { o.observe("ListPage", start, err) }(time.Now())
+	return o.next.ListPage(ctx, params)
+}
+
+func (o *observedRepo) GetByUserID(ctx context.Context, userID uuid.UUID) (out []models.UserInfo, err error) {
+	defer func(start time.Time) { o.observe("GetByUserID", start, err) }(time.Now())
+	return o.next.GetByUserID(ctx, userID)
+}
+
+func (o *observedRepo) Stream(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time, fn func(models.UserInfo) error) (err error) {
+	defer func(t time.Time) { o.observe("Stream", t, err) }(time.Now())
+	return o.next.Stream(ctx, userID, serviceName, start, end, fn)
+}
+
+func (o *observedRepo) FilterSum(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time) (out []repo.CurrencyTotal, err error) {
+	defer func(t time.Time) { o.observe("FilterSum", t, err) }(time.Now())
+	return o.next.FilterSum(ctx, userID, serviceName, start, end)
+}
+
+func (o *observedRepo) GroupedSum(ctx context.Context, groupBy repo.GroupBy, userID *uuid.UUID, serviceName *string, start, end *time.Time) (out []repo.GroupStats, err error) {
+	defer func(t time.Time) { o.observe("GroupedSum", t, err) }(time.Now())
+	return o.next.GroupedSum(ctx, groupBy, userID, serviceName, start, end)
+}
+
+func (o *observedRepo) MonthlyCost(ctx context.Context, userID *uuid.UUID, serviceName *string, from, to time.Time, groupBy repo.GroupBy, basis repo.Basis) (out []repo.MonthlyCost, err error) {
+	defer func(start time.Time) { o.observe("MonthlyCost", start, err) }(time.Now())
+	return o.next.MonthlyCost(ctx, userID, serviceName, from, to, groupBy, basis)
+}
+
+func (o *observedRepo) GetByID(ctx context.Context, id uuid.UUID) (u models.UserInfo, err error) {
+	defer func(start time.Time) { o.observe("GetByID", start, err) }(time.Now())
+	return o.next.GetByID(ctx, id)
+}
+
+func (o *observedRepo) UpdateByID(ctx context.Context, id uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (u models.UserInfo, err error) {
+	defer func(start time.Time) { o.observe("UpdateByID", start, err) }(time.Now())
+	return o.next.UpdateByID(ctx, id, price, end, priceFrom)
+}
+
+func (o *observedRepo) PriceHistory(ctx context.Context, id uuid.UUID) (out []models.PricePeriod, err error) {
+	defer func(start time.Time) { o.observe("PriceHistory", start, err) }(time.Now())
+	return o.next.PriceHistory(ctx, id)
+}
+
+func (o *observedRepo) DeleteByID(ctx context.Context, id uuid.UUID) (err error) {
+	defer func(start time.Time) { o.observe("DeleteByID", start, err) }(time.Now())
+	return o.next.DeleteByID(ctx, id)
+}
+
+func (o *observedRepo) RestoreByUserID(ctx context.Context, userID uuid.UUID) (res repo.RestoreResult, err error) {
+	defer func(start time.Time) { o.observe("RestoreByUserID", start, err) }(time.Now())
+	return o.next.RestoreByUserID(ctx, userID)
+}
+
+func (o *observedRepo) RestoreByID(ctx context.Context, id uuid.UUID) (u models.UserInfo, err error) {
+	defer func(start time.Time) { o.observe("RestoreByID", start, err) }(time.Now())
+	return o.next.RestoreByID(ctx, id)
+}
+
+func (o *observedRepo) PurgeByUserID(ctx context.Context, userID uuid.UUID) (n int64, err error) {
+	defer func(start time.Time) { o.observe("PurgeByUserID", start, err) }(time.Now())
+	return o.next.PurgeByUserID(ctx, userID)
+}
+
+func (o *observedRepo) PurgeDeleted(ctx context.Context, before time.Time) (n int64, err error) {
+	defer func(start time.Time) { o.observe("PurgeDeleted", start, err) }(time.Now())
+	return o.next.PurgeDeleted(ctx, before)
+}
+
+func (o *observedRepo) History(ctx context.Context, userID uuid.UUID, afterID int64, limit int) (out []models.AuditEntry, err error) {
+	defer func(start time.Time) { o.observe("History", start, err) }(time.Now())
+	return o.next.History(ctx, userID, afterID, limit)
+}
+
+func (o *observedRepo) ExchangeRates(ctx context.Context) (out []models.ExchangeRate, err error) {
+	defer func(start time.Time) { o.observe("ExchangeRates", start, err) }(time.Now())
+	return o.next.ExchangeRates(ctx)
+}
+
+func (o *observedRepo) SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) (err error) {
+	defer func(start time.Time) { o.observe("SaveExchangeRates", start, err) }(time.Now())
+	return o.next.SaveExchangeRates(ctx, rates)
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEntry is one change of a subscription record
+// @Description One entry of the subscription change history
+type AuditEntry struct {
+	// ID is the position of the entry in the audit log
+	ID int64 `json:"id"`
+	// RecordID is the ID of the changed subscription record
+	RecordID uuid.UUID `json:"record_id"`
+	// UserID is the owner of the record
+	UserID uuid.UUID `json:"user_id"`
+	// Operation is one of insert, update, delete, restore, purge
+	Operation string `json:"operation"`
+	// OldValue is the record before the change (absent for insert)
+	OldValue *UserInfo `json:"old_value,omitempty"`
+	// NewValue is the record after the change (absent for purge)
+	NewValue *UserInfo `json:"new_value,omitempty"`
+	// Actor is who made the change
+	Actor string `json:"actor"`
+	// RequestID is the ID of the HTTP request that made the change, if any
+	RequestID string `json:"request_id,omitempty"`
+	// ChangedAt is when the change was committed
+	ChangedAt time.Time `json:"changed_at"`
+}
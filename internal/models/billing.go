@@ -0,0 +1,20 @@
+package models
+
+// BillingPeriod is how often a subscription is charged its price.
+type BillingPeriod string
+
+const (
+	BillingMonthly BillingPeriod = "monthly"
+	BillingYearly  BillingPeriod = "yearly"
+	BillingWeekly  BillingPeriod = "weekly"
+	BillingOneOff  BillingPeriod = "one_off" // charged once, on the start date
+)
+
+// Valid reports whether p is one of the supported billing periods.
+func (p BillingPeriod) Valid() bool {
+	switch p {
+	case BillingMonthly, BillingYearly, BillingWeekly, BillingOneOff:
+		return true
+	}
+	return false
+}
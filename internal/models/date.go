@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateInputs lists the accepted date forms, for error messages.
+const DateInputs = "RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY"
+
+// dateLayouts are the accepted input forms. A date-only or month-only form
+// stands for a whole day or month, the span after its first instant.
+var dateLayouts = []struct {
+	layout string
+	span   dateSpan
+}{
+	{time.RFC3339, dateSpan{}},
+	{time.DateOnly, dateSpan{days: 1}},
+	{"2006-01", dateSpan{months: 1}},
+	{"01-2006", dateSpan{months: 1}},
+}
+
+// dateSpan is the length of a day or month form; zero for an instant.
+type dateSpan struct{ months, days int }
+
+// DateFormats maps the names accepted by SetDateFormat to layouts.
+var DateFormats = map[string]string{
+	"rfc3339":    time.RFC3339Nano,
+	"yyyy-mm-dd": time.DateOnly,
+	"yyyy-mm":    "2006-01",
+	"mm-yyyy":    "01-2006",
+}
+
+// dateLayout is how Date and EndDate are written to JSON.
+var dateLayout = time.RFC3339Nano
+
+// SetDateFormat sets how Date and EndDate are written, by a DateFormats
+// name. It is meant to be called once, before serving.
+func SetDateFormat(name string) error {
+	layout, ok := DateFormats[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("unknown date format %q (use rfc3339, yyyy-mm-dd, yyyy-mm or mm-yyyy)", name)
+	}
+	dateLayout = layout
+	return nil
+}
+
+// parseDate reads s in any of dateLayouts; a date without a zone is UTC.
+// A day or month form is returned as its first instant with its span.
+func parseDate(s string) (time.Time, dateSpan, error) {
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l.layout, s); err == nil {
+			return t, l.span, nil
+		}
+	}
+	return time.Time{}, dateSpan{}, fmt.Errorf("invalid date %q: use %s", s, DateInputs)
+}
+
+// ParseDate reads the start of a range: a day or month means its first
+// instant.
+func ParseDate(s string) (time.Time, error) {
+	t, _, err := parseDate(s)
+	return t, err
+}
+
+// ParseEndDate reads the inclusive end of a range: a day or month means its
+// last instant. That is a microsecond before the next one, the precision
+// Postgres keeps.
+func ParseEndDate(s string) (time.Time, error) {
+	t, span, err := parseDate(s)
+	if err != nil || span == (dateSpan{}) {
+		return t, err
+	}
+	return t.AddDate(0, span.months, span.days).Add(-time.Microsecond), nil
+}
+
+// Date is a time read with ParseDate from a JSON string and written in the
+// configured format (see SetDateFormat).
+type Date struct{ time.Time }
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	t, err := unmarshalDate(b, ParseDate)
+	if err == nil {
+		d.Time = t
+	}
+	return err
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.UTC().Format(dateLayout))
+}
+
+// EndDate is Date for the end of a period: it is read with ParseEndDate.
+type EndDate struct{ time.Time }
+
+func (d *EndDate) UnmarshalJSON(b []byte) error {
+	t, err := unmarshalDate(b, ParseEndDate)
+	if err == nil {
+		d.Time = t
+	}
+	return err
+}
+
+func (d EndDate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.UTC().Format(dateLayout))
+}
+
+// unmarshalDate parses a JSON string with parse. null is the zero time.
+func unmarshalDate(b []byte, parse func(string) (time.Time, error)) (time.Time, error) {
+	if string(b) == "null" {
+		return time.Time{}, nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return time.Time{}, fmt.Errorf("date must be a string: %w", err)
+	}
+	return parse(s)
+}
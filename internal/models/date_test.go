@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDate(t *testing.T) {
+	jul := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	endOfJul := time.Date(2025, 7, 31, 23, 59, 59, 999999000, time.UTC)
+	endOfJul1 := time.Date(2025, 7, 1, 23, 59, 59, 999999000, time.UTC)
+
+	for _, tc := range []struct {
+		in         string
+		start, end time.Time
+	}{
+		{"07-2025", jul, endOfJul},
+		{"2025-07", jul, endOfJul},
+		{"2025-07-01", jul, endOfJul1},
+		{"2025-07-31", time.Date(2025, 7, 31, 0, 0, 0, 0, time.UTC), endOfJul},
+		{"2025-07-01T00:00:00Z", jul, jul},
+		{"2025-07-01T03:00:00+03:00", jul, jul},
+		{"02-2024", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 23, 59, 59, 999999000, time.UTC)},
+		{"2025-12", time.Date(2025, 12, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 12, 31, 23, 59, 59, 999999000, time.UTC)},
+	} {
+		start, err := ParseDate(tc.in)
+		require.NoError(t, err, tc.in)
+		require.True(t, tc.start.Equal(start), "%s: start %s", tc.in, start)
+
+		end, err := ParseEndDate(tc.in)
+		require.NoError(t, err, tc.in)
+		require.True(t, tc.end.Equal(end), "%s: end %s", tc.in, end)
+	}
+
+	for _, in := range []string{"", "2025", "13-2025", "2025-13", "7-2025", "01.07.2025", "2025-07-01 00:00:00"} {
+		_, err := ParseDate(in)
+		require.Error(t, err, in)
+	}
+}
+
+func TestDate_JSON(t *testing.T) {
+	var v struct {
+		Start Date     `json:"start"`
+		End   *EndDate `json:"end"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"start":"03-2025","end":"2025-03"}`), &v))
+	require.Equal(t, time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), v.Start.Time)
+	require.Equal(t, time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC), v.End.Time)
+
+	require.Error(t, json.Unmarshal([]byte(`{"start":20250301}`), &v))
+	require.NoError(t, json.Unmarshal([]byte(`{"end":null}`), &v))
+	require.Nil(t, v.End)
+
+	b, err := json.Marshal(Date{time.Date(2025, 3, 1, 3, 0, 0, 0, time.FixedZone("MSK", 3*3600))})
+	require.NoError(t, err)
+	require.Equal(t, `"2025-03-01T00:00:00Z"`, string(b))
+
+	t.Cleanup(func() { require.NoError(t, SetDateFormat("rfc3339")) })
+	require.NoError(t, SetDateFormat("MM-YYYY"))
+	b, err = json.Marshal(EndDate{time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC)})
+	require.NoError(t, err)
+	require.Equal(t, `"03-2025"`, string(b))
+
+	require.Error(t, SetDateFormat("dd.mm.yyyy"))
+}
+
+func TestNullableTime_JSON(t *testing.T) {
+	var v struct {
+		End NullableTime `json:"end,omitzero"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{}`), &v))
+	require.False(t, v.End.Set)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"end":null}`), &v))
+	require.True(t, v.End.Set)
+	require.Nil(t, v.End.Time)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"end":"06-2025"}`), &v))
+	require.Equal(t, time.Date(2025, 6, 30, 23, 59, 59, 999999000, time.UTC), *v.End.Time)
+
+	b, err := json.Marshal(struct {
+		End NullableTime `json:"end,omitzero"`
+	}{})
+	require.NoError(t, err)
+	require.Equal(t, `{}`, string(b))
+}
@@ -9,16 +9,24 @@ import (
 // UserInfo represents user subscription information
 // @Description User subscription information with service details and pricing
 type UserInfo struct {
+	// ID is the unique identifier of the subscription record (assigned by the service)
+	ID uuid.UUID `json:"id"`
 	// ServiceName is the name of the subscribed service
 	ServiceName string `json:"service_name"`
 	// Price is the subscription price (always integer)
 	Price int64 `json:"price"`
+	// Currency is the ISO 4217 code of Price; the configured default if omitted
+	Currency string `json:"currency" example:"RUB"`
+	// BillingPeriod is how often Price is charged, counted from StartDate; monthly if omitted
+	BillingPeriod BillingPeriod `json:"billing_period" enums:"monthly,yearly,weekly,one_off" example:"monthly"`
 	// UserID is the unique identifier of the user
 	UserID uuid.UUID `json:"user_id"`
-	// StartDate is when the subscription begins
-	StartDate time.Time `json:"start_date"`
-	// EndDate is when the subscription ends
-	EndDate time.Time `json:"end_date"`
+	// StartDate is when the subscription begins; a month (MM-YYYY or YYYY-MM) means its first day
+	StartDate Date `json:"start_date" swaggertype:"string" example:"2025-01-01T00:00:00Z"`
+	// EndDate is when the subscription ends, a day or month meaning its last moment; null for an open-ended subscription
+	EndDate *EndDate `json:"end_date" swaggertype:"string" example:"2025-12-31T23:59:59Z" extensions:"x-nullable"`
+	// DeletedAt is when the record was moved to the trash (trash listing only)
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 
@@ -29,6 +37,17 @@ type UpdateUserInfo struct {
 	UserID uuid.UUID `json:"user_id"`
 	// Price is the updated subscription price (optional, always integer)
 	Price *int64 `json:"price,omitempty"`
-	// EndDate is the updated subscription end date (optional)
-	EndDate *time.Time `json:"end_date,omitempty"`
+	// EndDate is the updated subscription end date (optional, same formats as UserInfo.EndDate); null makes the subscription open-ended
+	EndDate NullableTime `json:"end_date,omitzero" swaggertype:"string" example:"2025-06-30" extensions:"x-nullable"`
+	// EffectiveFrom is the date (UTC) the new price applies from; today if omitted. Requires price
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+}
+
+// PricePeriod is a price that applies from EffectiveFrom until the next period starts
+// @Description Subscription price in effect from a date (UTC, midnight)
+type PricePeriod struct {
+	// EffectiveFrom is the first day the price applies
+	EffectiveFrom time.Time `json:"effective_from"`
+	// Price is the subscription price for the period
+	Price int64 `json:"price"`
 }
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// NullableTime is a PATCH end date that tells a missing value from an
+// explicit null: Set is false when the field was not sent, Time is nil when
+// it was sent as null. It is read like EndDate.
+type NullableTime struct {
+	Set  bool
+	Time *time.Time
+}
+
+// UnmarshalJSON is only called when the field is present, so it always sets Set.
+func (n *NullableTime) UnmarshalJSON(b []byte) error {
+	n.Set = true
+	if string(b) == "null" {
+		n.Time = nil
+		return nil
+	}
+	var d EndDate
+	if err := d.UnmarshalJSON(b); err != nil {
+		return err
+	}
+	n.Time = &d.Time
+	return nil
+}
+
+func (n NullableTime) MarshalJSON() ([]byte, error) {
+	if n.Time == nil {
+		return []byte("null"), nil
+	}
+	return EndDate{*n.Time}.MarshalJSON()
+}
+
+// IsZero reports whether the field was not set, for the omitzero option.
+func (n NullableTime) IsZero() bool {
+	return !n.Set
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ExchangeRate says that one unit of Base costs Rate units of Quote
+// @Description Exchange rate in effect from a date (UTC) until the next rate of the same pair
+type ExchangeRate struct {
+	// Base is the currency being priced (ISO 4217)
+	Base string `json:"base" example:"USD"`
+	// Quote is the currency the rate is expressed in (ISO 4217)
+	Quote string `json:"quote" example:"RUB"`
+	// Rate is the price of one Base in Quote, up to 10 decimals
+	Rate json.Number `json:"rate" swaggertype:"number" example:"92.5"`
+	// EffectiveFrom is the first day (UTC) the rate applies
+	EffectiveFrom time.Time `json:"effective_from"`
+}
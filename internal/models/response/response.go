@@ -1,9 +1,26 @@
 package response
 
+import "user-aggregation/internal/models"
+
 // Summary represents the total cost from filtered results
 // @Description Summary response with total cost calculation
 type Summary struct {
-	// TotalCost is the sum of prices from filtered records
+	// TotalCost is the sum in Currency; only set when converting (?currency=)
+	TotalCost *int64 `json:"total_cost,omitempty"`
+	// Currency is the target currency of the conversion
+	Currency string `json:"currency,omitempty"`
+	// Totals is the sum in each original currency
+	Totals []CurrencyTotal `json:"totals"`
+	// Rates lists the exchange rates used for the conversion
+	Rates []models.ExchangeRate `json:"rates,omitempty"`
+}
+
+// CurrencyTotal is a sum in one currency.
+// @Description Sum of prices in one currency
+type CurrencyTotal struct {
+	// Currency is the ISO 4217 code
+	Currency string `json:"currency" example:"RUB"`
+	// TotalCost is the sum in that currency
 	TotalCost int64 `json:"total_cost"`
 }
 
@@ -16,4 +33,194 @@ type ErrorPayload struct {
 	Op string `json:"op,omitempty"`
 	// HTTP status code (mirrors the response status)
 	Status int `json:"status"`
+	// RequestID matches the X-Request-ID response header and the server logs
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// UserInfoPage is one page of GET /users.
+// @Description Page of subscription records with a cursor for the next page
+type UserInfoPage struct {
+	// Items on this page
+	Items []models.UserInfo `json:"items"`
+	// NextCursor is passed as ?cursor= to fetch the next page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// HistoryPage is one page of GET /users/{id}/history.
+// @Description Page of audit entries, oldest first, with a cursor for the next page
+type HistoryPage struct {
+	// Items on this page
+	Items []models.AuditEntry `json:"items"`
+	// NextCursor is passed as ?cursor= to fetch the next page; empty on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// MonthlySummary is the per-month cost breakdown of GET /summary/monthly.
+// @Description Cost charged per calendar month, optionally split by service or user
+type MonthlySummary struct {
+	// First month of the range (YYYY-MM)
+	From string `json:"from"`
+	// Last month of the range, inclusive (YYYY-MM)
+	To string `json:"to"`
+	// Grouping dimension: service_name, user_id or empty
+	GroupBy string `json:"group_by,omitempty"`
+	// How costs are put into months: charges or prorated
+	Basis string `json:"basis"`
+	// Currency is the target currency of the conversion, if any
+	Currency string `json:"currency,omitempty"`
+	// One bucket per month in [from, to]
+	Months []MonthCost `json:"months"`
+	// Rates lists the exchange rates used for the conversion
+	Rates []models.ExchangeRate `json:"rates,omitempty"`
+}
+
+// MonthCost is the cost of a single month.
+// @Description Cost of one calendar month
+type MonthCost struct {
+	// Month in YYYY-MM format
+	Month string `json:"month"`
+	// TotalCost is the cost charged in the month in Currency; only set when converting
+	TotalCost *int64 `json:"total_cost,omitempty"`
+	// Totals is the cost charged in each original currency
+	Totals []CurrencyTotal `json:"totals"`
+	// Groups holds the per-key split when group_by is set
+	Groups []GroupCost `json:"groups,omitempty"`
+}
+
+// GroupCost is the cost of one group within a month.
+// @Description Cost of one service or user within a month
+type GroupCost struct {
+	// Key is the service name or user ID
+	Key string `json:"key"`
+	// Currency of TotalCost: the original one, or the target when converting
+	Currency string `json:"currency"`
+	// TotalCost is the cost charged for this key
+	TotalCost int64 `json:"total_cost"`
+}
+
+// GroupedSummary is the response of GET /summary/grouped.
+// @Description Summary split by service, user or charge month
+type GroupedSummary struct {
+	// Grouping dimension: service_name, user_id or month
+	GroupBy string `json:"group_by"`
+	// Currency is the target currency of the conversion, if any
+	Currency string `json:"currency,omitempty"`
+	// One row per group key and currency (one per key when converting), ordered by key
+	Groups []GroupStats `json:"groups"`
+	// Rates lists the exchange rates used for the conversion
+	Rates []models.ExchangeRate `json:"rates,omitempty"`
+}
+
+// GroupStats is one row of a grouped summary.
+// @Description Aggregated prices of one group
+type GroupStats struct {
+	// Key is the service name, user ID or month (YYYY-MM)
+	Key string `json:"key"`
+	// Currency of the prices: the original one, or the target when converting
+	Currency string `json:"currency"`
+	// TotalCost is the sum of the charges in the group
+	TotalCost int64 `json:"total_cost"`
+	// ChargeCount is the number of charges in the group
+	ChargeCount int64 `json:"charge_count"`
+	// SubscriptionCount is the number of records charged in the group
+	SubscriptionCount int64 `json:"subscription_count"`
+	// AvgPrice is the average charge, rounded to 2 decimals
+	AvgPrice float64 `json:"avg_price"`
+	// MinPrice is the lowest charge in the group
+	MinPrice int64 `json:"min_price"`
+	// MaxPrice is the highest charge in the group
+	MaxPrice int64 `json:"max_price"`
+}
+
+// ValidationError is returned with 422 when the payload fails validation.
+// @Description Field-level validation errors
+type ValidationError struct {
+	// Human-readable error message
+	Error string `json:"error"`
+	// Operation/context where the error occurred (optional)
+	Op string `json:"op,omitempty"`
+	// HTTP status code (always 422)
+	Status int `json:"status"`
+	// Errors lists every failed check
+	Errors []FieldError `json:"errors"`
+	// RequestID matches the X-Request-ID response header and the server logs
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// FieldError is a single failed check.
+// @Description One invalid field and the reason code
+type FieldError struct {
+	// Field is the JSON name of the invalid field
+	Field string `json:"field" example:"end_date"`
+	// Code is a machine-readable reason: required, negative, before_start, too_long
+	Code string `json:"code" example:"before_start"`
+}
+
+// RestoreReport is the result of POST /users/{id}/restore.
+// @Description Records brought back from the last deletion of the user
+type RestoreReport struct {
+	// Restored is the number of records brought back
+	Restored int64 `json:"restored"`
+	// Conflicts are the records left in the trash because a live record has the same user, service and start date
+	Conflicts []models.UserInfo `json:"conflicts"`
+}
+
+// BulkReport is the result of POST /users/bulk.
+// @Description Per-item report of a bulk import
+type BulkReport struct {
+	// Mode is atomic or best_effort
+	Mode string `json:"mode"`
+	// Committed is false when an atomic import was rolled back
+	Committed bool `json:"committed"`
+	// Created is the number of new records
+	Created int `json:"created"`
+	// Updated is the number of overwritten records
+	Updated int `json:"updated"`
+	// Rejected is the number of items that were not saved because of their own errors
+	Rejected int `json:"rejected"`
+	// Results has one entry per input item, in input order
+	Results []BulkResult `json:"results"`
+}
+
+// BulkResult is the outcome of one input item.
+// @Description Outcome of one item of a bulk import
+type BulkResult struct {
+	// Line is the 1-based line (NDJSON) or array position (JSON)
+	Line int `json:"line"`
+	// Status is created, updated, rejected or skipped (not saved because an atomic import was rolled back)
+	Status string `json:"status"`
+	// ID of the saved record
+	ID string `json:"id,omitempty"`
+	// Reason the item was rejected
+	Reason string `json:"reason,omitempty"`
+	// Errors holds field-level validation errors, if any
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// Readiness is the result of GET /readyz.
+// @Description Overall readiness and the outcome of every dependency check
+type Readiness struct {
+	// Status is ok, fail or shutting_down
+	Status string `json:"status"`
+	// Checks by name: database, migrations, pool
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// CheckResult is the outcome of one readiness check.
+// @Description Outcome of a single dependency check
+type CheckResult struct {
+	// Status is ok or fail
+	Status string `json:"status"`
+	// Error explains a failed check
+	Error string `json:"error,omitempty"`
+	// DurationMS is how long the check took
+	DurationMS int64 `json:"duration_ms"`
+	// Details depend on the check (versions, pool counters)
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// ExchangeRates is the list of stored exchange rates.
+// @Description Exchange rates ordered by pair and date
+type ExchangeRates struct {
+	Rates []models.ExchangeRate `json:"rates"`
 }
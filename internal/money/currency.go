@@ -0,0 +1,38 @@
+// Package money knows ISO 4217 currency codes and converts amounts with
+// the stored exchange rates.
+package money
+
+import "strings"
+
+// DefaultCurrency is used when neither the request nor the config name one.
+const DefaultCurrency = "RUB"
+
+// iso4217 lists the active ISO 4217 alphabetic codes.
+var iso4217 = func() map[string]struct{} {
+	const codes = `AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BOV
+		BRL BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUP CVE CZK DJF DKK
+		DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF IDR ILS
+		INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD LSL LYD
+		MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD OMR
+		PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD SHP SLE SOS SRD
+		SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD USN UYI UYU UYW UZS
+		VED VES VND VUV WST XAF XAG XAU XBA XBB XBC XBD XCD XCG XDR XOF XPD XPF XPT XSU XTS XUA
+		XXX YER ZAR ZMW ZWG`
+	out := make(map[string]struct{})
+	for _, c := range strings.Fields(codes) {
+		out[c] = struct{}{}
+	}
+	return out
+}()
+
+// Normalize trims and upper-cases a currency code.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Valid reports whether code is an active ISO 4217 code. It expects a
+// normalized code.
+func Valid(code string) bool {
+	_, ok := iso4217[code]
+	return ok
+}
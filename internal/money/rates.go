@@ -0,0 +1,163 @@
+package money
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sort"
+	"time"
+	"user-aggregation/internal/models"
+)
+
+// ErrNoRate is returned when no stored rate converts between two
+// currencies on a date.
+var ErrNoRate = errors.New("no exchange rate")
+
+type pair struct{ base, quote string }
+
+type rate struct {
+	models.ExchangeRate
+	value *big.Rat
+}
+
+// Table is a set of exchange rates indexed for lookups by date.
+type Table struct {
+	pairs map[pair][]rate // by EffectiveFrom, ascending
+}
+
+// NewTable indexes rates. It fails on a rate that is not a positive
+// decimal number.
+func NewTable(rates []models.ExchangeRate) (*Table, error) {
+	t := &Table{pairs: make(map[pair][]rate)}
+	for _, r := range rates {
+		v, ok := ParseRate(string(r.Rate))
+		if !ok {
+			return nil, fmt.Errorf("money: invalid rate %q for %s/%s", r.Rate, r.Base, r.Quote)
+		}
+		k := pair{r.Base, r.Quote}
+		t.pairs[k] = append(t.pairs[k], rate{ExchangeRate: r, value: v})
+	}
+	for _, rs := range t.pairs {
+		sort.Slice(rs, func(i, j int) bool { return rs[i].EffectiveFrom.Before(rs[j].EffectiveFrom) })
+	}
+	return t, nil
+}
+
+// ParseRate parses a positive decimal rate.
+func ParseRate(s string) (*big.Rat, bool) {
+	v, ok := new(big.Rat).SetString(s)
+	if !ok || v.Sign() <= 0 {
+		return nil, false
+	}
+	return v, true
+}
+
+// at returns the latest rate of the pair in effect on day.
+func (t *Table) at(k pair, day time.Time) (rate, bool) {
+	rs := t.pairs[k]
+	i := sort.Search(len(rs), func(i int) bool { return rs[i].EffectiveFrom.After(day) })
+	if i == 0 {
+		return rate{}, false
+	}
+	return rs[i-1], true
+}
+
+// lookup finds the factor from -> to on the UTC date of at. A stored
+// quote -> base rate is used inverted; when both directions exist the more
+// recent one wins.
+func (t *Table) lookup(from, to string, at time.Time) (*big.Rat, rate, error) {
+	day := Day(at)
+	direct, okDirect := t.at(pair{from, to}, day)
+	inverse, okInverse := t.at(pair{to, from}, day)
+	switch {
+	case okDirect && (!okInverse || !inverse.EffectiveFrom.After(direct.EffectiveFrom)):
+		return direct.value, direct, nil
+	case okInverse:
+		return new(big.Rat).Inv(inverse.value), inverse, nil
+	}
+	return nil, rate{}, fmt.Errorf("%w from %s to %s on %s", ErrNoRate, from, to, day.Format(time.DateOnly))
+}
+
+// Day is the UTC midnight of t, the granularity of EffectiveFrom.
+func Day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Converter converts amounts into one target currency and remembers the
+// rates it used.
+type Converter struct {
+	table  *Table
+	target string
+	used   map[pair]map[time.Time]models.ExchangeRate
+}
+
+// To returns a converter into target.
+func (t *Table) To(target string) *Converter {
+	return &Converter{table: t, target: target, used: make(map[pair]map[time.Time]models.ExchangeRate)}
+}
+
+// Target is the currency amounts are converted into.
+func (c *Converter) Target() string {
+	return c.target
+}
+
+// Factor is what an amount in currency is multiplied by on the date of at.
+func (c *Converter) Factor(currency string, at time.Time) (*big.Rat, error) {
+	if currency == c.target {
+		return big.NewRat(1, 1), nil
+	}
+	f, r, err := c.table.lookup(currency, c.target, at)
+	if err != nil {
+		return nil, err
+	}
+	k := pair{r.Base, r.Quote}
+	if c.used[k] == nil {
+		c.used[k] = make(map[time.Time]models.ExchangeRate)
+	}
+	c.used[k][r.EffectiveFrom] = r.ExchangeRate
+	return f, nil
+}
+
+// Convert converts amount, rounding half away from zero.
+func (c *Converter) Convert(amount int64, currency string, at time.Time) (int64, error) {
+	f, err := c.Factor(currency, at)
+	if err != nil {
+		return 0, err
+	}
+	return Round(new(big.Rat).Mul(big.NewRat(amount, 1), f)), nil
+}
+
+// Used lists the rates used so far, ordered by pair and date.
+func (c *Converter) Used() []models.ExchangeRate {
+	var out []models.ExchangeRate
+	for _, byDay := range c.used {
+		for _, r := range byDay {
+			out = append(out, r)
+		}
+	}
+	slices.SortFunc(out, func(a, b models.ExchangeRate) int {
+		return cmp.Or(
+			cmp.Compare(a.Base, b.Base),
+			cmp.Compare(a.Quote, b.Quote),
+			a.EffectiveFrom.Compare(b.EffectiveFrom),
+		)
+	})
+	return out
+}
+
+// Round rounds r to the nearest integer, halves away from zero.
+func Round(r *big.Rat) int64 {
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	m.Abs(m).Lsh(m, 1)
+	if m.Cmp(r.Denom()) >= 0 {
+		if r.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q.Int64()
+}
@@ -0,0 +1,99 @@
+package money
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+	"user-aggregation/internal/models"
+
+	"github.com/stretchr/testify/require"
+)
+
+func day(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func table(t *testing.T, rates ...models.ExchangeRate) *Table {
+	t.Helper()
+	tb, err := NewTable(rates)
+	require.NoError(t, err)
+	return tb
+}
+
+func TestConverter_PicksRateInEffect(t *testing.T) {
+	tb := table(t,
+		models.ExchangeRate{Base: "USD", Quote: "RUB", Rate: "100", EffectiveFrom: day(2025, 2, 1)},
+		models.ExchangeRate{Base: "USD", Quote: "RUB", Rate: "90", EffectiveFrom: day(2025, 1, 1)},
+	)
+	c := tb.To("RUB")
+
+	_, err := c.Convert(1, "USD", day(2024, 12, 31))
+	require.True(t, errors.Is(err, ErrNoRate))
+
+	v, err := c.Convert(3, "USD", time.Date(2025, 1, 31, 23, 59, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, int64(270), v)
+
+	v, err = c.Convert(3, "USD", day(2025, 2, 1))
+	require.NoError(t, err)
+	require.Equal(t, int64(300), v)
+
+	v, err = c.Convert(5, "RUB", day(2000, 1, 1))
+	require.NoError(t, err)
+	require.Equal(t, int64(5), v, "same currency needs no rate")
+
+	used := c.Used()
+	require.Len(t, used, 2)
+	require.Equal(t, day(2025, 1, 1), used[0].EffectiveFrom)
+	require.Equal(t, day(2025, 2, 1), used[1].EffectiveFrom)
+}
+
+func TestConverter_Inverse(t *testing.T) {
+	tb := table(t,
+		models.ExchangeRate{Base: "USD", Quote: "RUB", Rate: "80", EffectiveFrom: day(2025, 1, 1)},
+		models.ExchangeRate{Base: "RUB", Quote: "USD", Rate: "0.01", EffectiveFrom: day(2025, 3, 1)},
+	)
+	c := tb.To("RUB")
+
+	v, err := c.Convert(2, "USD", day(2025, 2, 1))
+	require.NoError(t, err)
+	require.Equal(t, int64(160), v, "direct rate")
+
+	v, err = c.Convert(2, "USD", day(2025, 3, 1))
+	require.NoError(t, err)
+	require.Equal(t, int64(200), v, "newer inverse rate wins")
+
+	v, err = tb.To("USD").Convert(150, "RUB", day(2025, 2, 1))
+	require.NoError(t, err)
+	require.Equal(t, int64(2), v, "150/80 = 1.875")
+}
+
+func TestNewTable_BadRate(t *testing.T) {
+	_, err := NewTable([]models.ExchangeRate{{Base: "USD", Quote: "RUB", Rate: "-1"}})
+	require.Error(t, err)
+}
+
+func TestRound(t *testing.T) {
+	for _, tc := range []struct {
+		num, den int64
+		want     int64
+	}{
+		{5, 2, 3},
+		{-5, 2, -3},
+		{7, 3, 2},
+		{-7, 3, -2},
+		{8, 3, 3},
+		{4, 1, 4},
+		{0, 1, 0},
+	} {
+		require.Equal(t, tc.want, Round(big.NewRat(tc.num, tc.den)), "%d/%d", tc.num, tc.den)
+	}
+}
+
+func TestValid(t *testing.T) {
+	require.True(t, Valid(Normalize(" usd ")))
+	require.False(t, Valid("usd"))
+	require.False(t, Valid("ABC"))
+	require.False(t, Valid(""))
+}
@@ -0,0 +1,56 @@
+// Package purge permanently removes soft-deleted records once they have
+// been in the trash longer than the retention period.
+package purge
+
+import (
+	"context"
+	"log/slog"
+	"time"
+	"user-aggregation/internal/lib/reqctx"
+)
+
+// Actor is recorded in the audit log for rows removed by the job.
+const Actor = "system:purge"
+
+// Purger is the part of repo.Repo the job needs.
+type Purger interface {
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Run purges once right away and then every interval until ctx is done.
+// Failures are logged and retried on the next tick.
+func Run(ctx context.Context, log *slog.Logger, p Purger, retention, interval time.Duration) {
+	const op = "purge.run"
+	ctx = reqctx.WithActor(ctx, Actor)
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		Once(ctx, log, p, retention)
+
+		select {
+		case <-ctx.Done():
+			log.Info("purge job stopped", slog.String("op", op))
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// Once removes everything trashed more than retention ago.
+func Once(ctx context.Context, log *slog.Logger, p Purger, retention time.Duration) {
+	const op = "purge.once"
+
+	before := time.Now().Add(-retention)
+	n, err := p.PurgeDeleted(ctx, before)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Error("purge failed", slog.String("op", op), slog.Any("error", err))
+		}
+		return
+	}
+	if n > 0 {
+		log.Info("purged deleted records", slog.String("op", op), slog.Int64("count", n), slog.Time("before", before))
+	}
+}
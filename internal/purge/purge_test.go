@@ -0,0 +1,64 @@
+package purge
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+	"user-aggregation/internal/lib/reqctx"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakePurger struct {
+	mu     sync.Mutex
+	before []time.Time
+	actor  string
+	err    error
+}
+
+func (f *fakePurger) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.before = append(f.before, before)
+	f.actor = reqctx.Actor(ctx)
+	return 1, f.err
+}
+
+func (f *fakePurger) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.before)
+}
+
+func TestOnce_UsesRetention(t *testing.T) {
+	f := &fakePurger{}
+	Once(context.Background(), slog.Default(), f, 48*time.Hour)
+
+	require.Len(t, f.before, 1)
+	require.WithinDuration(t, time.Now().Add(-48*time.Hour), f.before[0], time.Minute)
+}
+
+func TestRun_TicksUntilCancelled(t *testing.T) {
+	f := &fakePurger{err: errors.New("db down")}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		Run(ctx, slog.Default(), f, time.Hour, time.Millisecond)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return f.calls() >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	f.mu.Lock()
+	require.Equal(t, Actor, f.actor)
+	f.mu.Unlock()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not stop after cancel")
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory keeps buckets in process. Each replica counts on its own.
+type Memory struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+type bucket struct {
+	rule    Rule
+	tokens  float64
+	updated time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+func (m *Memory) Take(_ context.Context, key string, rule Rule) (bool, float64, error) {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rule.Burst)}
+		m.buckets[key] = b
+	} else {
+		b.tokens = b.available(now)
+	}
+	b.rule = rule
+	b.updated = now
+
+	if b.tokens < 1 {
+		return false, b.tokens, nil
+	}
+	b.tokens--
+	return true, b.tokens, nil
+}
+
+// Sweep drops full buckets; a new bucket starts full anyway.
+func (m *Memory) Sweep(context.Context) error {
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, b := range m.buckets {
+		if b.available(now) >= float64(b.rule.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+	return nil
+}
+
+func (b *bucket) available(now time.Time) float64 {
+	elapsed := max(now.Sub(b.updated).Seconds(), 0)
+	return min(float64(b.rule.Burst), b.tokens+elapsed*b.rule.Rate)
+}
@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// BucketStore is the part of the postgres repo that Postgres needs.
+type BucketStore interface {
+	TakeToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error)
+	DeleteIdleBuckets(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Postgres keeps buckets in the database, so the limit holds across replicas.
+type Postgres struct {
+	db   BucketStore
+	idle time.Duration
+}
+
+// NewPostgres returns a store for rules. Sweep forgets a bucket once even
+// the slowest of the rules would have refilled it.
+func NewPostgres(db BucketStore, rules map[string]Rule) *Postgres {
+	idle := time.Minute
+	for _, r := range rules {
+		if r.Rate > 0 {
+			idle = max(idle, time.Duration(float64(r.Burst)/r.Rate*float64(time.Second)))
+		}
+	}
+	return &Postgres{db: db, idle: idle}
+}
+
+func (p *Postgres) Take(ctx context.Context, key string, rule Rule) (bool, float64, error) {
+	return p.db.TakeToken(ctx, key, rule.Rate, rule.Burst)
+}
+
+func (p *Postgres) Sweep(ctx context.Context) error {
+	_, err := p.db.DeleteIdleBuckets(ctx, time.Now().Add(-p.idle))
+	return err
+}
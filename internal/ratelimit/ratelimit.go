@@ -0,0 +1,145 @@
+// Package ratelimit throttles clients with token buckets, one bucket per
+// client and route group.
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+	"user-aggregation/internal/auth"
+	"user-aggregation/internal/transport/http/respond"
+)
+
+// Rule is a token bucket: Burst requests at once, refilled at Rate per second.
+type Rule struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute is a rule of n requests per minute with the given burst.
+func PerMinute(n, burst int) Rule {
+	return Rule{Rate: float64(n) / 60, Burst: burst}
+}
+
+// Store keeps the buckets.
+type Store interface {
+	// Take takes one token from the bucket for key and reports whether
+	// there was one, and how many tokens are left.
+	Take(ctx context.Context, key string, rule Rule) (allowed bool, tokens float64, err error)
+	// Sweep forgets buckets that have not been used for a while.
+	Sweep(ctx context.Context) error
+}
+
+// GroupIP is the rule WrapIP applies by client address to every request,
+// authenticated or not.
+const GroupIP = "ip"
+
+// Limiter applies per-group rules to HTTP handlers.
+// A nil *Limiter lets every request through.
+type Limiter struct {
+	log   *slog.Logger
+	store Store
+	rules map[string]Rule
+}
+
+func New(log *slog.Logger, store Store, rules map[string]Rule) *Limiter {
+	return &Limiter{log: log, store: store, rules: rules}
+}
+
+// Wrap limits next with the rule of group. Groups without a rule are not limited.
+//
+// The client is the authenticated principal when there is one, otherwise
+// the remote address, so Wrap belongs inside the auth middleware.
+func (l *Limiter) Wrap(group string, next http.Handler) http.Handler {
+	return l.wrap(group, clientKey, next)
+}
+
+// WrapIP limits next with the GroupIP rule by remote address alone. It
+// belongs outside the auth middleware, so that requests failing
+// authentication are throttled too.
+func (l *Limiter) WrapIP(next http.Handler) http.Handler {
+	return l.wrap(GroupIP, addrKey, next)
+}
+
+// wrap limits next with the rule of group, one bucket per key(r).
+func (l *Limiter) wrap(group string, key func(*http.Request) string, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	rule, ok := l.rules[group]
+	if !ok || rule.Rate <= 0 || rule.Burst <= 0 {
+		return next
+	}
+
+	const op = "ratelimit.wrap"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, tokens, err := l.store.Take(r.Context(), group+"|"+key(r), rule)
+		if err != nil {
+			// fail open: a broken limiter must not take the API down
+			l.log.Warn("rate limit check failed", slog.String("op", op), slog.String("group", group), slog.Any("error", err))
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(rule.Burst))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(int(math.Max(0, math.Floor(tokens)))))
+		h.Set("X-RateLimit-Reset", strconv.Itoa(seconds(float64(rule.Burst)-tokens, rule.Rate)))
+		if !allowed {
+			h.Set("Retry-After", strconv.Itoa(max(1, seconds(1-tokens, rule.Rate))))
+			respond.Error(w, r, op, http.StatusTooManyRequests, "rate limit exceeded", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Run sweeps the store every interval until ctx is done.
+func (l *Limiter) Run(ctx context.Context, interval time.Duration) {
+	const op = "ratelimit.run"
+	if l == nil {
+		return
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := l.store.Sweep(ctx); err != nil && ctx.Err() == nil {
+				l.log.Warn("rate limit sweep failed", slog.String("op", op), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// clientKey is the principal's subject, or "ip:<addr>" for anonymous callers.
+func clientKey(r *http.Request) string {
+	if p, ok := auth.FromContext(r.Context()); ok {
+		return p.Subject
+	}
+	return addrKey(r)
+}
+
+// addrKey is "ip:<addr>" of the remote address, without the port.
+func addrKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// seconds is how long it takes to refill n tokens, rounded up.
+func seconds(n, rate float64) int {
+	if n <= 0 {
+		return 0
+	}
+	return int(math.Ceil(n / rate))
+}
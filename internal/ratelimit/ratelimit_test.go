@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-aggregation/internal/auth"
+	"user-aggregation/internal/models/response"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory_TokenBucket(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMemory()
+	m.now = func() time.Time { return now }
+	rule := Rule{Rate: 1, Burst: 3}
+	ctx := context.Background()
+
+	for i := 2; i >= 0; i-- {
+		ok, tokens, err := m.Take(ctx, "k", rule)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.InDelta(t, float64(i), tokens, 1e-9)
+	}
+	ok, _, _ := m.Take(ctx, "k", rule)
+	require.False(t, ok)
+
+	// other keys have their own bucket
+	ok, _, _ = m.Take(ctx, "other", rule)
+	require.True(t, ok)
+
+	now = now.Add(1500 * time.Millisecond)
+	ok, tokens, _ := m.Take(ctx, "k", rule)
+	require.True(t, ok)
+	require.InDelta(t, 0.5, tokens, 1e-9)
+
+	// never refills past the burst
+	now = now.Add(time.Hour)
+	_, tokens, _ = m.Take(ctx, "k", rule)
+	require.InDelta(t, 2, tokens, 1e-9)
+
+	require.NoError(t, m.Sweep(ctx))
+	require.Len(t, m.buckets, 1, "full buckets are dropped")
+	now = now.Add(time.Second)
+	require.NoError(t, m.Sweep(ctx))
+	require.Empty(t, m.buckets)
+}
+
+func TestWrap(t *testing.T) {
+	l := New(slog.Default(), NewMemory(), map[string]Rule{"write": PerMinute(60, 2)})
+	h := l.Wrap("write", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	call := func(addr string, p *auth.Principal) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		req.RemoteAddr = addr
+		if p != nil {
+			req = req.WithContext(auth.WithPrincipal(req.Context(), *p))
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := call("10.0.0.1:1000", nil)
+	require.Equal(t, http.StatusNoContent, w.Code)
+	require.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	require.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+	require.Equal(t, "1", w.Header().Get("X-RateLimit-Reset"))
+
+	require.Equal(t, http.StatusNoContent, call("10.0.0.1:2000", nil).Code, "same IP, other port")
+	w = call("10.0.0.1:1000", nil)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+	require.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	var payload response.ErrorPayload
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &payload))
+	require.Equal(t, http.StatusTooManyRequests, payload.Status)
+	require.Equal(t, "rate limit exceeded", payload.Error)
+
+	// authenticated callers are counted by principal, not address
+	p := &auth.Principal{Subject: "apikey:importer"}
+	require.Equal(t, http.StatusNoContent, call("10.0.0.1:1000", p).Code)
+	require.Equal(t, http.StatusNoContent, call("10.0.0.2:1000", p).Code)
+	require.Equal(t, http.StatusTooManyRequests, call("10.0.0.3:1000", p).Code)
+}
+
+func TestWrapIP(t *testing.T) {
+	l := New(slog.Default(), NewMemory(), map[string]Rule{GroupIP: PerMinute(60, 2), "write": PerMinute(60, 2)})
+	denied := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	h := l.WrapIP(denied)
+
+	call := func(addr string) int {
+		req := httptest.NewRequest(http.MethodPost, "/users", nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// failed authentication is throttled by address
+	require.Equal(t, http.StatusUnauthorized, call("10.0.0.1:1000"))
+	require.Equal(t, http.StatusUnauthorized, call("10.0.0.1:2000"))
+	require.Equal(t, http.StatusTooManyRequests, call("10.0.0.1:1000"))
+	require.Equal(t, http.StatusUnauthorized, call("10.0.0.2:1000"))
+
+	// and apart from the route groups
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.RemoteAddr = "10.0.0.1:1000"
+	l.Wrap("write", denied).ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+type brokenStore struct{}
+
+func (brokenStore) Take(context.Context, string, Rule) (bool, float64, error) {
+	return false, 0, errors.New("db down")
+}
+func (brokenStore) Sweep(context.Context) error { return nil }
+
+func TestWrap_Passthrough(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+
+	for name, h := range map[string]http.Handler{
+		"nil limiter":  (*Limiter)(nil).Wrap("write", next),
+		"no rule":      New(slog.Default(), brokenStore{}, nil).Wrap("write", next),
+		"no ip rule":   New(slog.Default(), brokenStore{}, map[string]Rule{"write": PerMinute(1, 1)}).WrapIP(next),
+		"store failed": New(slog.Default(), brokenStore{}, map[string]Rule{"write": PerMinute(1, 1)}).Wrap("write", next),
+	} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users", nil))
+		require.Equal(t, http.StatusNoContent, w.Code, name)
+		require.Empty(t, w.Header().Get("X-RateLimit-Limit"), name)
+	}
+}
+
+func TestNewPostgres_IdleCoversSlowestRule(t *testing.T) {
+	p := NewPostgres(nil, map[string]Rule{"read": PerMinute(600, 100), "bulk": PerMinute(6, 2)})
+	require.Equal(t, time.Minute, p.idle)
+
+	p = NewPostgres(nil, map[string]Rule{"bulk": PerMinute(1, 10)})
+	require.Equal(t, 10*time.Minute, p.idle)
+}
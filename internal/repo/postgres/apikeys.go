@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"user-aggregation/internal/repo"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateAPIKey stores a new key by its hash and fills k.ID and k.CreatedAt.
+func (p *Repo) CreateAPIKey(ctx context.Context, k *repo.APIKey, hash []byte) error {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const q = `
+			INSERT INTO api_keys (name, prefix, key_hash, scopes)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at`
+	if err := p.pool.QueryRow(ctx, q, k.Name, k.Prefix, hash, k.Scopes).Scan(&k.ID, &k.CreatedAt); err != nil {
+		return fmt.Errorf("repo: insert api key: %w", classify(err))
+	}
+	return nil
+}
+
+// RevokeAPIKey marks a live key as revoked.
+func (p *Repo) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const q = `UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL`
+	ct, err := p.pool.Exec(ctx, q, id)
+	if err != nil {
+		return fmt.Errorf("repo: revoke api key: %w", classify(err))
+	}
+	if ct.RowsAffected() == 0 {
+		return repo.ErrNotFound
+	}
+	return nil
+}
+
+// ListAPIKeys returns all keys, revoked ones included, oldest first.
+func (p *Repo) ListAPIKeys(ctx context.Context) ([]repo.APIKey, error) {
+	ctx, cancel := p.withTimeout(ctx, queryRead)
+	defer cancel()
+
+	const q = `
+			SELECT id, name, prefix, scopes, created_at, revoked_at
+			FROM api_keys
+			ORDER BY created_at, id`
+	rows, err := p.pool.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("repo: list api keys: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var out []repo.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("repo: scan api key: %w", err)
+		}
+		out = append(out, k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: iterate api keys: %w", classify(err))
+	}
+	return out, nil
+}
+
+// APIKeyByHash finds a live key by the hash of its value.
+func (p *Repo) APIKeyByHash(ctx context.Context, hash []byte) (repo.APIKey, error) {
+	ctx, cancel := p.withTimeout(ctx, queryRead)
+	defer cancel()
+
+	const q = `
+			SELECT id, name, prefix, scopes, created_at, revoked_at
+			FROM api_keys
+			WHERE key_hash = $1 AND revoked_at IS NULL`
+	k, err := scanAPIKey(p.pool.QueryRow(ctx, q, hash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repo.APIKey{}, repo.ErrNotFound
+	}
+	if err != nil {
+		return repo.APIKey{}, fmt.Errorf("repo: select api key: %w", classify(err))
+	}
+	return k, nil
+}
+
+func scanAPIKey(r pgx.Row) (repo.APIKey, error) {
+	var k repo.APIKey
+	if err := r.Scan(&k.ID, &k.Name, &k.Prefix, &k.Scopes, &k.CreatedAt, &k.RevokedAt); err != nil {
+		return repo.APIKey{}, err
+	}
+	return k, nil
+}
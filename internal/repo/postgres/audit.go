@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"user-aggregation/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// History returns the audit entries of the user's records in the order they
+// were written, starting after the entry afterID (0 for the beginning).
+func (p *Repo) History(ctx context.Context, userID uuid.UUID, afterID int64, limit int) ([]models.AuditEntry, error) {
+	ctx, cancel := p.withTimeout(ctx, queryRead)
+	defer cancel()
+
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	const q = `
+			SELECT id, record_id, user_id, operation, old_value, new_value, actor, request_id, changed_at
+			FROM user_info_audit
+			WHERE user_id = $1 AND id > $2
+			ORDER BY id
+			LIMIT $3`
+	rows, err := p.pool.Query(ctx, q, userID, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repo: select history: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var out []models.AuditEntry
+	for rows.Next() {
+		var e models.AuditEntry
+		// old_value/new_value are to_jsonb(user_info), whose keys match the
+		// UserInfo JSON tags; NULL leaves the pointer nil
+		if err := rows.Scan(&e.ID, &e.RecordID, &e.UserID, &e.Operation, &e.OldValue, &e.NewValue,
+			&e.Actor, &e.RequestID, &e.ChangedAt); err != nil {
+			return nil, fmt.Errorf("repo: scan history: %w", err)
+		}
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: iterate history: %w", classify(err))
+	}
+	return out, nil
+}
@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/repo"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const bulkChunkSize = 500
+
+// BulkUpsert upserts items in one transaction, sending them in batches of
+// bulkChunkSize. IDs are written back into items.
+//
+// In atomic mode the first rejected row rolls everything back and its error
+// is returned. Otherwise rejected rows are recorded in their BulkResult and
+// the rest are committed.
+func (p *Repo) BulkUpsert(ctx context.Context, items []models.UserInfo, atomic bool) ([]repo.BulkResult, error) {
+	ctx, cancel := p.withTimeout(ctx, queryBulk)
+	defer cancel()
+
+	results := make([]repo.BulkResult, len(items))
+	if len(items) == 0 {
+		return results, nil
+	}
+
+	err := p.writeTx(ctx, func(tx pgx.Tx) error {
+		for start := 0; start < len(items); start += bulkChunkSize {
+			end := min(start+bulkChunkSize, len(items))
+			failed, err := upsertChunk(ctx, tx, items[start:end], results[start:end])
+			if err != nil {
+				return err
+			}
+			if failed < 0 {
+				continue
+			}
+			if atomic {
+				return fmt.Errorf("repo: bulk upsert item %d: %w", start+failed, results[start+failed].Err)
+			}
+			if err := upsertRows(ctx, tx, items[start:end], results[start:end]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return results, err
+}
+
+// upsertChunk sends the whole chunk as one batch inside a savepoint.
+// It returns the index of the first rejected row, or -1 if all went in;
+// in the former case the savepoint is rolled back.
+func upsertChunk(ctx context.Context, tx pgx.Tx, items []models.UserInfo, results []repo.BulkResult) (int, error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return -1, fmt.Errorf("repo: bulk savepoint: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for i := range items {
+		u := &items[i]
+		batch.Queue(upsertUserInfoSQL, u.ServiceName, u.Price, u.UserID, u.StartDate.Time, endDate(u), u.Currency, u.BillingPeriod)
+	}
+
+	br := sp.SendBatch(ctx, batch)
+	failed := -1
+	for i := range items {
+		if err := br.QueryRow().Scan(&items[i].ID, &results[i].Created); err != nil {
+			failed = i
+			results[i].Err = classify(err)
+			break
+		}
+	}
+	closeErr := br.Close()
+
+	if failed >= 0 {
+		_ = sp.Rollback(ctx)
+		return failed, nil
+	}
+	if closeErr != nil {
+		_ = sp.Rollback(ctx)
+		return -1, fmt.Errorf("repo: bulk batch: %w", classify(closeErr))
+	}
+	if err := sp.Commit(ctx); err != nil {
+		return -1, fmt.Errorf("repo: bulk release savepoint: %w", err)
+	}
+	return -1, nil
+}
+
+// upsertRows is the slow path for a chunk that had a bad row: every row gets
+// its own savepoint so one rejection does not abort the transaction.
+func upsertRows(ctx context.Context, tx pgx.Tx, items []models.UserInfo, results []repo.BulkResult) error {
+	for i := range items {
+		u := &items[i]
+		results[i] = repo.BulkResult{}
+
+		sp, err := tx.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("repo: bulk savepoint: %w", err)
+		}
+		err = sp.QueryRow(ctx, upsertUserInfoSQL, u.ServiceName, u.Price, u.UserID, u.StartDate.Time, endDate(u), u.Currency, u.BillingPeriod).
+			Scan(&u.ID, &results[i].Created)
+		if err != nil {
+			_ = sp.Rollback(ctx)
+			if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+			results[i].Err = classify(err)
+			continue
+		}
+		if err := sp.Commit(ctx); err != nil {
+			return fmt.Errorf("repo: bulk release savepoint: %w", err)
+		}
+	}
+	return nil
+}
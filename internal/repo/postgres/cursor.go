@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/repo"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// cursor is the keyset position of the last row of a page. It is handed to
+// clients base64-encoded and must be treated by them as opaque.
+type cursor struct {
+	Sort  repo.SortField  `json:"s"`
+	Desc  bool            `json:"d,omitempty"`
+	Value json.RawMessage `json:"v"`
+	ID    uuid.UUID       `json:"id"`
+}
+
+func encodeCursor(sort repo.SortField, desc bool, u models.UserInfo) (string, error) {
+	v, err := json.Marshal(sortValue(sort, u))
+	if err != nil {
+		return "", fmt.Errorf("repo: encode cursor: %w", err)
+	}
+	b, err := json.Marshal(cursor{Sort: sort, Desc: desc, Value: v, ID: u.ID})
+	if err != nil {
+		return "", fmt.Errorf("repo: encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeCursor returns the sort value and ID stored in s. The cursor must have
+// been issued for the same sort field and direction.
+func decodeCursor(s string, sort repo.SortField, desc bool) (any, uuid.UUID, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, uuid.Nil, errors.Join(repo.ErrBadInput, errors.New("malformed cursor"))
+	}
+	var c cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, uuid.Nil, errors.Join(repo.ErrBadInput, errors.New("malformed cursor"))
+	}
+	if c.Sort != sort || c.Desc != desc {
+		return nil, uuid.Nil, errors.Join(repo.ErrBadInput, errors.New("cursor was issued for a different sort order"))
+	}
+
+	var v any
+	switch sort {
+	case repo.SortByPrice:
+		var n int64
+		err = json.Unmarshal(c.Value, &n)
+		v = n
+	case repo.SortByStartDate:
+		var t time.Time
+		err = json.Unmarshal(c.Value, &t)
+		v = t
+	case repo.SortByEndDate:
+		// null is an open-ended row, compared as sortExpr sees it
+		var t *time.Time
+		err = json.Unmarshal(c.Value, &t)
+		if t != nil {
+			v = *t
+		} else {
+			v = pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}
+		}
+	case repo.SortByServiceName:
+		var str string
+		err = json.Unmarshal(c.Value, &str)
+		v = str
+	default:
+		err = fmt.Errorf("unknown sort field %q", sort)
+	}
+	if err != nil {
+		return nil, uuid.Nil, errors.Join(repo.ErrBadInput, errors.New("malformed cursor"))
+	}
+	return v, c.ID, nil
+}
+
+// sortExpr is what rows are ordered and compared by for sort. An open-ended
+// row sorts as if it ended at infinity, which keeps NULL out of the keyset
+// comparison; idx_user_info_end_id is built on the same expression.
+func sortExpr(sort repo.SortField) string {
+	if sort == repo.SortByEndDate {
+		return "COALESCE(end_date, 'infinity'::timestamptz)"
+	}
+	return string(sort)
+}
+
+func sortValue(sort repo.SortField, u models.UserInfo) any {
+	switch sort {
+	case repo.SortByPrice:
+		return u.Price
+	case repo.SortByEndDate:
+		return endDate(&u)
+	case repo.SortByServiceName:
+		return u.ServiceName
+	default:
+		return u.StartDate.Time
+	}
+}
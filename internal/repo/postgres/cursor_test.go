@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/repo"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor_RoundTrip(t *testing.T) {
+	end := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+	u := models.UserInfo{
+		ID:          uuid.New(),
+		ServiceName: "Netflix",
+		Price:       999,
+		StartDate:   models.Date{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		EndDate:     &models.EndDate{Time: end},
+	}
+
+	cases := map[repo.SortField]any{
+		repo.SortByPrice:       u.Price,
+		repo.SortByStartDate:   u.StartDate.Time,
+		repo.SortByEndDate:     end,
+		repo.SortByServiceName: u.ServiceName,
+	}
+	for sort, want := range cases {
+		c, err := encodeCursor(sort, true, u)
+		require.NoError(t, err)
+
+		v, id, err := decodeCursor(c, sort, true)
+		require.NoError(t, err, sort)
+		require.Equal(t, u.ID, id)
+		if wt, ok := want.(time.Time); ok {
+			require.True(t, wt.Equal(v.(time.Time)), sort)
+			continue
+		}
+		require.Equal(t, want, v, sort)
+	}
+}
+
+func TestCursor_OpenEnded(t *testing.T) {
+	u := models.UserInfo{ID: uuid.New(), StartDate: models.Date{Time: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	c, err := encodeCursor(repo.SortByEndDate, false, u)
+	require.NoError(t, err)
+
+	v, id, err := decodeCursor(c, repo.SortByEndDate, false)
+	require.NoError(t, err)
+	require.Equal(t, u.ID, id)
+	require.Equal(t, pgtype.Timestamptz{InfinityModifier: pgtype.Infinity, Valid: true}, v)
+}
+
+func TestCursor_Rejects(t *testing.T) {
+	c, err := encodeCursor(repo.SortByPrice, false, models.UserInfo{ID: uuid.New(), Price: 1})
+	require.NoError(t, err)
+
+	_, _, err = decodeCursor(c, repo.SortByPrice, true)
+	require.True(t, errors.Is(err, repo.ErrBadInput))
+
+	_, _, err = decodeCursor(c, repo.SortByServiceName, false)
+	require.True(t, errors.Is(err, repo.ErrBadInput))
+
+	_, _, err = decodeCursor("%%%", repo.SortByPrice, false)
+	require.True(t, errors.Is(err, repo.ErrBadInput))
+}
@@ -0,0 +1,64 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"user-aggregation/internal/repo"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SQLSTATE codes we translate into repo sentinels or otherwise handle.
+const (
+	codeUniqueViolation     = "23505"
+	codeCheckViolation      = "23514"
+	codeNotNullViolation    = "23502"
+	codeForeignKeyViolation = "23503"
+	codeInvalidText         = "22P02"
+	codeInvalidDatetime     = "22007"
+	codeDatetimeOverflow    = "22008"
+	codeNumericOutOfRange   = "22003"
+	codeQueryCanceled       = "57014"
+	codeUndefinedTable      = "42P01"
+)
+
+// constraintEndAfterStart is the CHECK (end_date >= start_date) on user_info.
+const constraintEndAfterStart = "user_info_end_after_start"
+
+// classify wraps Postgres errors with the matching repo sentinel so callers
+// can use errors.Is without knowing about pgconn. Other errors, and errors
+// that were already classified, pass through.
+func classify(err error) error {
+	for _, sentinel := range []error{repo.ErrConflict, repo.ErrConstraint, repo.ErrBadInput, repo.ErrTimeout} {
+		if errors.Is(err, sentinel) {
+			return err
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) || pgconn.Timeout(err) {
+		return fmt.Errorf("%w: %w", repo.ErrTimeout, err)
+	}
+
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	var sentinel error
+	switch pgErr.Code {
+	case codeUniqueViolation:
+		sentinel = repo.ErrConflict
+	case codeCheckViolation, codeNotNullViolation, codeForeignKeyViolation:
+		sentinel = repo.ErrConstraint
+		if pgErr.ConstraintName == constraintEndAfterStart {
+			sentinel = repo.ErrEndBeforeStart
+		}
+	case codeInvalidText, codeInvalidDatetime, codeDatetimeOverflow, codeNumericOutOfRange:
+		sentinel = repo.ErrBadInput
+	case codeQueryCanceled: // statement_timeout or a cancel request
+		sentinel = repo.ErrTimeout
+	default:
+		return err
+	}
+	return fmt.Errorf("%w: %w", sentinel, err)
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"user-aggregation/internal/repo"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassify(t *testing.T) {
+	cases := map[string]error{
+		"23505": repo.ErrConflict,
+		"23514": repo.ErrConstraint,
+		"23502": repo.ErrConstraint,
+		"22P02": repo.ErrBadInput,
+		"22008": repo.ErrBadInput,
+		"57014": repo.ErrTimeout,
+	}
+	for code, want := range cases {
+		pgErr := &pgconn.PgError{Code: code}
+		err := classify(fmt.Errorf("exec: %w", pgErr))
+
+		require.True(t, errors.Is(err, want), code)
+		var got *pgconn.PgError
+		require.True(t, errors.As(err, &got), "original error must stay reachable")
+	}
+
+	endBefore := classify(&pgconn.PgError{Code: "23514", ConstraintName: "user_info_end_after_start"})
+	require.ErrorIs(t, endBefore, repo.ErrEndBeforeStart)
+	require.ErrorIs(t, endBefore, repo.ErrConstraint)
+	require.NotErrorIs(t, classify(&pgconn.PgError{Code: "23514"}), repo.ErrEndBeforeStart)
+
+	deadline := classify(fmt.Errorf("query: %w", context.DeadlineExceeded))
+	require.ErrorIs(t, deadline, repo.ErrTimeout)
+	require.ErrorIs(t, deadline, context.DeadlineExceeded)
+	require.NotErrorIs(t, classify(context.Canceled), repo.ErrTimeout)
+
+	plain := errors.New("boom")
+	require.Same(t, plain, classify(plain))
+
+	other := &pgconn.PgError{Code: "40001"}
+	require.Equal(t, error(other), classify(other))
+
+	// already classified errors are not wrapped again
+	once := fmt.Errorf("tx: %w", classify(&pgconn.PgError{Code: "23505"}))
+	require.Equal(t, once.Error(), classify(once).Error())
+}
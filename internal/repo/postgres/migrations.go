@@ -0,0 +1,36 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SchemaVersion is the migration this code is written against, the highest
+// number in migrations/. Bump it together with every new migration.
+const SchemaVersion = 14
+
+// MigrationVersion returns the version golang-migrate recorded in
+// schema_migrations and whether the last migration failed half way.
+// A database without migrations reports version 0.
+func (p *Repo) MigrationVersion(ctx context.Context) (version int64, dirty bool, err error) {
+	const q = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+	err = p.pool.QueryRow(ctx, q).Scan(&version, &dirty)
+	if notMigrated(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("repo: read migration version: %w", classify(err))
+	}
+	return version, dirty, nil
+}
+
+// notMigrated reports whether err means no migration ran yet: golang-migrate
+// has not created schema_migrations, or has not written a version to it.
+func notMigrated(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) && pgErr.Code == codeUndefinedTable
+}
@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaVersion_MatchesMigrations(t *testing.T) {
+	files, err := filepath.Glob("../../../migrations/*.up.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+
+	var latest int64
+	for _, f := range files {
+		prefix, _, _ := strings.Cut(filepath.Base(f), "_")
+		v, err := strconv.ParseInt(prefix, 10, 64)
+		require.NoError(t, err, f)
+		latest = max(latest, v)
+	}
+	require.EqualValues(t, latest, SchemaVersion, "bump SchemaVersion with the new migration")
+}
+
+func TestNotMigrated(t *testing.T) {
+	require.True(t, notMigrated(pgx.ErrNoRows))
+	require.True(t, notMigrated(fmt.Errorf("wrapped: %w", &pgconn.PgError{Code: codeUndefinedTable})))
+	require.False(t, notMigrated(nil))
+	require.False(t, notMigrated(&pgconn.PgError{Code: codeQueryCanceled}))
+	require.False(t, notMigrated(errors.New("connection refused")))
+}
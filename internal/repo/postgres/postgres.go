@@ -4,33 +4,107 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+	"user-aggregation/internal/lib/reqctx"
 	"user-aggregation/internal/models"
 	"user-aggregation/internal/repo"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgconn/ctxwatch"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// upsertUserInfoSQL returns the row id and whether it was created;
+// xmax is 0 only for a freshly inserted tuple. A new record gets its first
+// price period from start_date; a changed price on an existing one takes
+// effect today (see user_info_prices).
+const upsertUserInfoSQL = `
+			WITH up AS (
+				INSERT INTO user_info (service_name, price, user_id, start_date, end_date, currency, billing_period)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (user_id, service_name, start_date) WHERE deleted_at IS NULL DO UPDATE
+				SET price = EXCLUDED.price,
+					end_date = EXCLUDED.end_date,
+					currency = EXCLUDED.currency,
+					billing_period = EXCLUDED.billing_period
+				RETURNING id, start_date, price, (xmax = 0) AS created
+			), pr AS (
+				INSERT INTO user_info_prices (subscription_id, effective_from, price)
+				SELECT id,
+					CASE WHEN created THEN (start_date AT TIME ZONE 'UTC')::date
+					ELSE GREATEST((now() AT TIME ZONE 'UTC')::date, (start_date AT TIME ZONE 'UTC')::date) END,
+					price
+				FROM up
+				WHERE created OR price IS DISTINCT FROM (
+					SELECT pp.price FROM user_info_prices pp
+					WHERE pp.subscription_id = up.id AND pp.effective_from <= (now() AT TIME ZONE 'UTC')::date
+					ORDER BY pp.effective_from DESC
+					LIMIT 1)
+				ON CONFLICT (subscription_id, effective_from) DO UPDATE SET price = EXCLUDED.price
+			)
+			SELECT id, created FROM up`
+
 type Repo struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	timeouts Timeouts
+}
+
+// Options tunes the connection pool. Zero values keep the pgx defaults
+// or whatever the pool_* parameters of the URL say.
+type Options struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// ApplicationName shows up in pg_stat_activity.
+	ApplicationName string
+	// Timeouts bound each repository call by its query class.
+	Timeouts Timeouts
 }
 
-func New(ctx context.Context, dbURL string, maxConns int32) (*Repo, error) {
+func New(ctx context.Context, dbURL string, o Options) (*Repo, error) {
 	cfg, err := pgxpool.ParseConfig(dbURL)
 	if err != nil {
 		return nil, fmt.Errorf("repo: parse db url: %w", err)
 	}
-	if maxConns > 0 {
-		cfg.MaxConns = maxConns
+	if o.MaxConns > 0 {
+		cfg.MaxConns = o.MaxConns
+	}
+	if o.MinConns > 0 {
+		cfg.MinConns = o.MinConns
+	}
+	if o.MaxConnLifetime > 0 {
+		cfg.MaxConnLifetime = o.MaxConnLifetime
+	}
+	if o.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = o.MaxConnIdleTime
 	}
+	if o.HealthCheckPeriod > 0 {
+		cfg.HealthCheckPeriod = o.HealthCheckPeriod
+	}
+	if o.ApplicationName != "" {
+		cfg.ConnConfig.RuntimeParams["application_name"] = o.ApplicationName
+	}
+	// statement_timeout backs up the per-call deadlines for statements
+	// whose cancel request got lost
+	if d := o.Timeouts.longest(); d > 0 {
+		cfg.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(d.Milliseconds(), 10)
+	}
+	// on a cancelled context, cancel the query instead of dropping the connection
+	cfg.ConnConfig.BuildContextWatcherHandler = func(c *pgconn.PgConn) ctxwatch.Handler {
+		return &pgconn.CancelRequestContextWatcherHandler{Conn: c, DeadlineDelay: time.Second}
+	}
+	cfg.ConnConfig.Tracer = queryTracer{}
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("repo: connect db: %w", err)
 	}
-	return &Repo{pool: pool}, nil
+	return &Repo{pool: pool, timeouts: o.Timeouts}, nil
 }
 
 func NewFromPool(pool *pgxpool.Pool) *Repo {
@@ -56,31 +130,74 @@ func (p *Repo) Close() {
 	}
 }
 
+// Stat returns the connection pool statistics.
+func (p *Repo) Stat() *pgxpool.Stat {
+	return p.pool.Stat()
+}
+
+// Insert creates a new record and fills u.ID. A record with the same
+// (user_id, service_name, start_date) is left untouched and ErrConflict is returned.
 func (p *Repo) Insert(ctx context.Context, u *models.UserInfo) error {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
 	if u == nil {
 		return errors.Join(repo.ErrBadInput, errors.New("nil user info"))
 	}
 	const q = `
-			INSERT INTO user_info (service_name, price, user_id, start_date, end_date)
-			VALUES ($1, $2, $3, $4, $5)
-			ON CONFLICT (user_id, service_name, start_date) DO UPDATE
-			SET price = EXCLUDED.price,
-    		end_date = EXCLUDED.end_date`
-	ct, err := p.pool.Exec(ctx, q, u.ServiceName, u.Price, u.UserID, u.StartDate, u.EndDate)
-	if err != nil {
-		return fmt.Errorf("repo: insert user_info: %w", err)
+			WITH ins AS (
+				INSERT INTO user_info (service_name, price, user_id, start_date, end_date, currency, billing_period)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				ON CONFLICT (user_id, service_name, start_date) WHERE deleted_at IS NULL DO NOTHING
+				RETURNING id, start_date, price
+			), pr AS (
+				INSERT INTO user_info_prices (subscription_id, effective_from, price)
+				SELECT id, (start_date AT TIME ZONE 'UTC')::date, price FROM ins
+			)
+			SELECT id FROM ins`
+	err := p.writeTx(ctx, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, q, u.ServiceName, u.Price, u.UserID, u.StartDate.Time, endDate(u), u.Currency, u.BillingPeriod).Scan(&u.ID)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return errors.Join(repo.ErrConflict, errors.New("subscription already exists"))
 	}
-	if ct.RowsAffected() == 0 {
-		return errors.Join(repo.ErrConflict, errors.New("no rows affected"))
+	if err != nil {
+		return fmt.Errorf("repo: insert user_info: %w", classify(err))
 	}
 	return nil
 }
 
+// Upsert creates the record or overwrites price, end_date, currency and
+// billing_period of the existing one with the same (user_id, service_name,
+// start_date). It fills u.ID and
+// reports whether a new row was created.
+func (p *Repo) Upsert(ctx context.Context, u *models.UserInfo) (bool, error) {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	if u == nil {
+		return false, errors.Join(repo.ErrBadInput, errors.New("nil user info"))
+	}
+	var created bool
+	err := p.writeTx(ctx, func(tx pgx.Tx) error {
+		return tx.QueryRow(ctx, upsertUserInfoSQL, u.ServiceName, u.Price, u.UserID, u.StartDate.Time, endDate(u), u.Currency, u.BillingPeriod).Scan(&u.ID, &created)
+	})
+	if err != nil {
+		return false, fmt.Errorf("repo: upsert user_info: %w", classify(err))
+	}
+	return created, nil
+}
+
+// DeleteByUserID moves all live records of the user to the trash, marked
+// with one deleted_batch so that RestoreByUserID brings back just them.
 func (p *Repo) DeleteByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
-	const q = `DELETE FROM user_info WHERE user_id = $1`
-	ct, err := p.pool.Exec(ctx, q, userID)
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const q = `UPDATE user_info SET deleted_at = now(), deleted_batch = $2 WHERE user_id = $1 AND deleted_at IS NULL`
+	ct, err := p.execWrite(ctx, q, userID, uuid.New())
 	if err != nil {
-		return 0, fmt.Errorf("repo: delete by user_id: %w", err)
+		return 0, fmt.Errorf("repo: delete by user_id: %w", classify(err))
 	}
 	n := ct.RowsAffected()
 	if n == 0 {
@@ -89,7 +206,13 @@ func (p *Repo) DeleteByUserID(ctx context.Context, userID uuid.UUID) (int64, err
 	return n, nil
 }
 
-func (p *Repo) UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int64, end *time.Time) (int64, error) {
+// UpdateUserInfo changes all live records of the user. A new price takes
+// effect from priceFrom (today if nil); earlier periods keep their price.
+// A set end with a nil Time makes the records open-ended.
+func (p *Repo) UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (int64, error) {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
 	sets := make([]string, 0, 2)
 	args := make([]any, 0, 3)
 
@@ -97,28 +220,37 @@ func (p *Repo) UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int6
 		args = append(args, *price)
 		sets = append(sets, fmt.Sprintf("price = $%d", len(args)))
 	}
-	if end != nil {
-		args = append(args, *end)
+	if end.Set {
+		args = append(args, end.Time)
 		sets = append(sets, fmt.Sprintf("end_date = $%d", len(args)))
 	}
 
 	if len(sets) == 0 {
-		return 0, fmt.Errorf("repo: patch user_info: no fields to update")
+		return 0, errors.Join(repo.ErrBadInput, errors.New("repo: patch user_info: no fields to update"))
 	}
 
 	args = append(args, userID)
 	q := fmt.Sprintf(`
 		UPDATE user_info
 		SET %s
-		WHERE user_id = $%d
+		WHERE user_id = $%d AND deleted_at IS NULL
 	`, strings.Join(sets, ", "), len(args))
 
-	ct, err := p.pool.Exec(ctx, q, args...)
+	var n int64
+	err := p.writeTx(ctx, func(tx pgx.Tx) error {
+		ct, err := tx.Exec(ctx, q, args...)
+		if err != nil {
+			return err
+		}
+		n = ct.RowsAffected()
+		if n == 0 || price == nil {
+			return nil
+		}
+		return setPrice(ctx, tx, "user_id", userID, *price, priceFrom)
+	})
 	if err != nil {
-		return 0, fmt.Errorf("repo: patch user_info: %w", err)
+		return 0, fmt.Errorf("repo: patch user_info: %w", classify(err))
 	}
-
-	n := ct.RowsAffected()
 	if n == 0 {
 		return 0, repo.ErrNotFound
 	}
@@ -126,13 +258,17 @@ func (p *Repo) UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int6
 }
 
 func (p *Repo) List(ctx context.Context) ([]models.UserInfo, error) {
+	ctx, cancel := p.withTimeout(ctx, queryRead)
+	defer cancel()
+
 	const q = `
-			SELECT service_name, price, user_id, start_date, end_date
+			SELECT id, service_name, price, currency, billing_period, user_id, start_date, end_date, deleted_at
 			FROM user_info
+			WHERE deleted_at IS NULL
 			ORDER BY user_id, service_name, start_date`
 	rows, err := p.pool.Query(ctx, q)
 	if err != nil {
-		return nil, fmt.Errorf("repo: list user_info: %w", err)
+		return nil, fmt.Errorf("repo: list user_info: %w", classify(err))
 	}
 	defer rows.Close()
 
@@ -145,20 +281,121 @@ func (p *Repo) List(ctx context.Context) ([]models.UserInfo, error) {
 		out = append(out, u)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("repo: iterate user_info: %w", err)
+		return nil, fmt.Errorf("repo: iterate user_info: %w", classify(err))
 	}
 	return out, nil
 }
 
+const defaultPageSize = 50
+
+func (p *Repo) ListPage(ctx context.Context, params repo.ListParams) (repo.Page, error) {
+	ctx, cancel := p.withTimeout(ctx, queryRead)
+	defer cancel()
+
+	sort := params.Sort
+	if sort == "" {
+		sort = repo.SortByStartDate
+	}
+	if !sort.Valid() {
+		return repo.Page{}, errors.Join(repo.ErrBadInput, fmt.Errorf("unknown sort field %q", sort))
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+
+	conds := make([]string, 0, 6)
+	args := make([]any, 0, 7)
+
+	if params.Deleted {
+		conds = append(conds, "deleted_at IS NOT NULL")
+	} else {
+		conds = append(conds, "deleted_at IS NULL")
+	}
+
+	if params.ServicePrefix != "" {
+		args = append(args, params.ServicePrefix)
+		conds = append(conds, fmt.Sprintf("starts_with(service_name, $%d)", len(args)))
+	}
+	if params.MinPrice != nil {
+		args = append(args, *params.MinPrice)
+		conds = append(conds, fmt.Sprintf("price >= $%d", len(args)))
+	}
+	if params.MaxPrice != nil {
+		args = append(args, *params.MaxPrice)
+		conds = append(conds, fmt.Sprintf("price <= $%d", len(args)))
+	}
+	if params.ActiveAt != nil && !params.ActiveAt.IsZero() {
+		args = append(args, *params.ActiveAt)
+		conds = append(conds, fmt.Sprintf("start_date <= $%d AND COALESCE(end_date, 'infinity') >= $%d", len(args), len(args)))
+	}
+
+	dir, cmp := "ASC", ">"
+	if params.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if params.Cursor != "" {
+		v, id, err := decodeCursor(params.Cursor, sort, params.Desc)
+		if err != nil {
+			return repo.Page{}, err
+		}
+		args = append(args, v, id)
+		conds = append(conds, fmt.Sprintf("(%s, id) %s ($%d, $%d)", sortExpr(sort), cmp, len(args)-1, len(args)))
+	}
+
+	// one extra row tells us whether there is a next page
+	args = append(args, limit+1)
+	q := fmt.Sprintf(`
+		SELECT id, service_name, price, currency, billing_period, user_id, start_date, end_date, deleted_at
+		FROM user_info
+		WHERE %s
+		ORDER BY %s %s, id %s
+		LIMIT $%d
+	`, strings.Join(conds, " AND "), sortExpr(sort), dir, dir, len(args))
+
+	rows, err := p.pool.Query(ctx, q, args...)
+	if err != nil {
+		return repo.Page{}, fmt.Errorf("repo: list page: %w", classify(err))
+	}
+	defer rows.Close()
+
+	items := make([]models.UserInfo, 0, limit+1)
+	for rows.Next() {
+		u, err := scanUserInfo(rows)
+		if err != nil {
+			return repo.Page{}, fmt.Errorf("repo: scan page: %w", err)
+		}
+		items = append(items, u)
+	}
+	if err := rows.Err(); err != nil {
+		return repo.Page{}, fmt.Errorf("repo: iterate page: %w", classify(err))
+	}
+
+	page := repo.Page{Items: items}
+	if len(items) > limit {
+		page.Items = items[:limit]
+		next, err := encodeCursor(sort, params.Desc, page.Items[limit-1])
+		if err != nil {
+			return repo.Page{}, err
+		}
+		page.NextCursor = next
+	}
+	return page, nil
+}
+
 func (p *Repo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.UserInfo, error) {
+	ctx, cancel := p.withTimeout(ctx, queryRead)
+	defer cancel()
+
 	const q = `
-			SELECT service_name, price, user_id, start_date, end_date
+			SELECT id, service_name, price, currency, billing_period, user_id, start_date, end_date, deleted_at
 			FROM user_info
-			WHERE user_id = $1
+			WHERE user_id = $1 AND deleted_at IS NULL
 			ORDER BY service_name, start_date`
 	rows, err := p.pool.Query(ctx, q, userID)
 	if err != nil {
-		return nil, fmt.Errorf("repo: select by user_id: %w", err)
+		return nil, fmt.Errorf("repo: select by user_id: %w", classify(err))
 	}
 	defer rows.Close()
 
@@ -171,21 +408,61 @@ func (p *Repo) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.User
 		out = append(out, u)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("repo: iterate by user_id: %w", err)
+		return nil, fmt.Errorf("repo: iterate by user_id: %w", classify(err))
 	}
 	return out, nil
 }
 
-func (p *Repo) FilterSum(
+// Stream calls fn for every row matching the FilterSum filters without
+// loading them all into memory. An error from fn stops the iteration and is
+// returned as is.
+func (p *Repo) Stream(
 	ctx context.Context,
 	userID *uuid.UUID,
 	serviceName *string,
 	start, end *time.Time,
-) (int64, error) {
+	fn func(models.UserInfo) error,
+) error {
+	ctx, cancel := p.withTimeout(ctx, queryReport)
+	defer cancel()
+
+	conds, args := summaryConds(userID, serviceName, start, end)
+
+	q := `
+		SELECT id, service_name, price, currency, billing_period, user_id, start_date, end_date, deleted_at
+		FROM user_info
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY user_id, service_name, start_date`
+
+	rows, err := p.pool.Query(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("repo: stream user_info: %w", classify(err))
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := scanUserInfo(rows)
+		if err != nil {
+			return fmt.Errorf("repo: scan stream: %w", err)
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("repo: iterate stream: %w", classify(err))
+	}
+	return nil
+}
+
+// summaryConds builds the WHERE clause shared by FilterSum, GroupedSum and
+// Stream: live rows overlapping [start, end] for the given user and service.
+// It only uses columns that user_info and user_info_periods have in common.
+func summaryConds(userID *uuid.UUID, serviceName *string, start, end *time.Time) ([]string, []any) {
 	conds := make([]string, 0, 5)
 	args := make([]any, 0, 5)
 
-	conds = append(conds, "1=1")
+	conds = append(conds, "deleted_at IS NULL")
 
 	if start != nil && !start.IsZero() {
 		args = append(args, *start)
@@ -203,36 +480,265 @@ func (p *Repo) FilterSum(
 		args = append(args, *serviceName)
 		conds = append(conds, fmt.Sprintf("service_name = $%d", len(args)))
 	}
+	return conds, args
+}
+
+func (p *Repo) GetByID(ctx context.Context, id uuid.UUID) (models.UserInfo, error) {
+	ctx, cancel := p.withTimeout(ctx, queryRead)
+	defer cancel()
+
+	const q = `
+			SELECT id, service_name, price, currency, billing_period, user_id, start_date, end_date, deleted_at
+			FROM user_info
+			WHERE id = $1 AND deleted_at IS NULL`
+	u, err := scanUserInfo(p.pool.QueryRow(ctx, q, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.UserInfo{}, repo.ErrNotFound
+	}
+	if err != nil {
+		return models.UserInfo{}, fmt.Errorf("repo: select by id: %w", classify(err))
+	}
+	return u, nil
+}
+
+// UpdateByID changes a single live record; price works as in UpdateUserInfo.
+func (p *Repo) UpdateByID(ctx context.Context, id uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (models.UserInfo, error) {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	sets := make([]string, 0, 2)
+	args := make([]any, 0, 3)
+
+	if price != nil {
+		args = append(args, *price)
+		sets = append(sets, fmt.Sprintf("price = $%d", len(args)))
+	}
+	if end.Set {
+		args = append(args, end.Time)
+		sets = append(sets, fmt.Sprintf("end_date = $%d", len(args)))
+	}
+
+	if len(sets) == 0 {
+		return models.UserInfo{}, errors.Join(repo.ErrBadInput, errors.New("repo: patch by id: no fields to update"))
+	}
+
+	args = append(args, id)
+	q := fmt.Sprintf(`
+		UPDATE user_info
+		SET %s
+		WHERE id = $%d AND deleted_at IS NULL
+		RETURNING id, service_name, price, currency, billing_period, user_id, start_date, end_date, deleted_at
+	`, strings.Join(sets, ", "), len(args))
+
+	var u models.UserInfo
+	err := p.writeTx(ctx, func(tx pgx.Tx) (err error) {
+		if u, err = scanUserInfo(tx.QueryRow(ctx, q, args...)); err != nil || price == nil {
+			return err
+		}
+		return setPrice(ctx, tx, "id", id, *price, priceFrom)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.UserInfo{}, repo.ErrNotFound
+	}
+	if err != nil {
+		return models.UserInfo{}, fmt.Errorf("repo: patch by id: %w", classify(err))
+	}
+	return u, nil
+}
 
-	q := "SELECT COALESCE(SUM(price), 0) FROM user_info WHERE " + strings.Join(conds, " AND ")
+// DeleteByID moves a single live record to the trash.
+func (p *Repo) DeleteByID(ctx context.Context, id uuid.UUID) error {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
 
-	var total int64
-	if err := p.pool.QueryRow(ctx, q, args...).Scan(&total); err != nil {
-		return 0, fmt.Errorf("repo: filter sum: %w", err)
+	const q = `UPDATE user_info SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`
+	ct, err := p.execWrite(ctx, q, id)
+	if err != nil {
+		return fmt.Errorf("repo: delete by id: %w", classify(err))
+	}
+	if ct.RowsAffected() == 0 {
+		return repo.ErrNotFound
 	}
-	return total, nil
+	return nil
+}
+
+// RestoreByUserID brings back the records trashed by the last DeleteByUserID
+// of the user. A record that clashes with a live one on (user_id,
+// service_name, start_date) stays in the trash and is reported in Conflicts.
+func (p *Repo) RestoreByUserID(ctx context.Context, userID uuid.UUID) (repo.RestoreResult, error) {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const (
+		lastBatch = `
+			SELECT deleted_batch
+			FROM user_info
+			WHERE user_id = $1 AND deleted_batch IS NOT NULL
+			ORDER BY deleted_at DESC
+			LIMIT 1`
+		restore = `
+			UPDATE user_info u
+			SET deleted_at = NULL, deleted_batch = NULL
+			WHERE u.deleted_batch = $1 AND NOT EXISTS (
+				SELECT 1 FROM user_info l
+				WHERE l.user_id = u.user_id AND l.service_name = u.service_name
+				  AND l.start_date = u.start_date AND l.deleted_at IS NULL
+			)`
+		conflicts = `
+			SELECT id, service_name, price, currency, billing_period, user_id, start_date, end_date, deleted_at
+			FROM user_info
+			WHERE deleted_batch = $1
+			ORDER BY service_name, start_date, id`
+	)
+
+	var res repo.RestoreResult
+	err := p.writeTx(ctx, func(tx pgx.Tx) error {
+		var batch uuid.UUID
+		if err := tx.QueryRow(ctx, lastBatch, userID).Scan(&batch); err != nil {
+			return err
+		}
+		ct, err := tx.Exec(ctx, restore, batch)
+		if err != nil {
+			return err
+		}
+		res.Restored = ct.RowsAffected()
+
+		rows, err := tx.Query(ctx, conflicts, batch)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			u, err := scanUserInfo(rows)
+			if err != nil {
+				return err
+			}
+			res.Conflicts = append(res.Conflicts, u)
+		}
+		return rows.Err()
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repo.RestoreResult{}, repo.ErrNotFound
+	}
+	if err != nil {
+		return repo.RestoreResult{}, fmt.Errorf("repo: restore by user_id: %w", classify(err))
+	}
+	return res, nil
+}
+
+// RestoreByID brings a single trashed record back, whichever way it was
+// deleted. A clash with a live record is ErrConflict.
+func (p *Repo) RestoreByID(ctx context.Context, id uuid.UUID) (models.UserInfo, error) {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const q = `
+		UPDATE user_info
+		SET deleted_at = NULL, deleted_batch = NULL
+		WHERE id = $1 AND deleted_at IS NOT NULL
+		RETURNING id, service_name, price, currency, billing_period, user_id, start_date, end_date, deleted_at`
+
+	var u models.UserInfo
+	err := p.writeTx(ctx, func(tx pgx.Tx) (err error) {
+		u, err = scanUserInfo(tx.QueryRow(ctx, q, id))
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.UserInfo{}, repo.ErrNotFound
+	}
+	if err != nil {
+		return models.UserInfo{}, fmt.Errorf("repo: restore by id: %w", classify(err))
+	}
+	return u, nil
+}
+
+// PurgeByUserID permanently removes every record of the user, live or trashed.
+func (p *Repo) PurgeByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const q = `DELETE FROM user_info WHERE user_id = $1`
+	ct, err := p.execWrite(ctx, q, userID)
+	if err != nil {
+		return 0, fmt.Errorf("repo: purge by user_id: %w", classify(err))
+	}
+	n := ct.RowsAffected()
+	if n == 0 {
+		return 0, repo.ErrNotFound
+	}
+	return n, nil
+}
+
+// PurgeDeleted permanently removes records trashed before the given moment.
+func (p *Repo) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const q = `DELETE FROM user_info WHERE deleted_at < $1`
+	ct, err := p.execWrite(ctx, q, before)
+	if err != nil {
+		return 0, fmt.Errorf("repo: purge deleted: %w", classify(err))
+	}
+	return ct.RowsAffected(), nil
 }
 
 func (p *Repo) WithTx(ctx context.Context, fn func(pgx.Tx) error) error {
 	tx, err := p.pool.BeginTx(ctx, pgx.TxOptions{})
 	if err != nil {
-		return fmt.Errorf("repo: begin tx: %w", err)
+		return fmt.Errorf("repo: begin tx: %w", classify(err))
 	}
 	defer func() { _ = tx.Rollback(ctx) }() 
 
 	if err := fn(tx); err != nil {
-		return err
+		return classify(err)
 	}
 	if err := tx.Commit(ctx); err != nil {
-		return fmt.Errorf("repo: commit tx: %w", err)
+		return fmt.Errorf("repo: commit tx: %w", classify(err))
 	}
 	return nil
 }
 
-func scanUserInfo(r pgx.Rows) (models.UserInfo, error) {
-	var u models.UserInfo
-	if err := r.Scan(&u.ServiceName, &u.Price, &u.UserID, &u.StartDate, &u.EndDate); err != nil {
+// writeTx runs fn in a transaction tagged with the actor and request ID from
+// ctx. The user_info audit triggers read them back with current_setting, so
+// the audit entry is committed together with the change.
+func (p *Repo) writeTx(ctx context.Context, fn func(pgx.Tx) error) error {
+	return p.WithTx(ctx, func(tx pgx.Tx) error {
+		const q = `SELECT set_config('app.actor', $1, true), set_config('app.request_id', $2, true)`
+		if _, err := tx.Exec(ctx, q, reqctx.Actor(ctx), reqctx.RequestID(ctx)); err != nil {
+			return fmt.Errorf("repo: set audit context: %w", err)
+		}
+		return fn(tx)
+	})
+}
+
+// execWrite runs a single write statement in its own writeTx.
+func (p *Repo) execWrite(ctx context.Context, q string, args ...any) (pgconn.CommandTag, error) {
+	var ct pgconn.CommandTag
+	err := p.writeTx(ctx, func(tx pgx.Tx) (err error) {
+		ct, err = tx.Exec(ctx, q, args...)
+		return err
+	})
+	return ct, err
+}
+
+func scanUserInfo(r pgx.Row) (models.UserInfo, error) {
+	var (
+		u   models.UserInfo
+		end *time.Time
+	)
+	if err := r.Scan(&u.ID, &u.ServiceName, &u.Price, &u.Currency, &u.BillingPeriod, &u.UserID, &u.StartDate.Time, &end, &u.DeletedAt); err != nil {
 		return models.UserInfo{}, err
 	}
+	if end != nil {
+		u.EndDate = &models.EndDate{Time: *end}
+	}
 	return u, nil
 }
+
+// endDate is the end_date argument for u: NULL when it is open-ended.
+func endDate(u *models.UserInfo) *time.Time {
+	if u.EndDate == nil {
+		return nil
+	}
+	return &u.EndDate.Time
+}
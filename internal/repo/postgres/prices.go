@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/repo"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// setPriceSQL starts a new price period for the live records whose %s is $1.
+// The period begins on the UTC date of $3 (today when NULL), but never
+// before the record itself; a period starting on the same day is replaced.
+const setPriceSQL = `
+	INSERT INTO user_info_prices (subscription_id, effective_from, price)
+	SELECT id,
+		GREATEST(
+			COALESCE(($3::timestamptz AT TIME ZONE 'UTC')::date, (now() AT TIME ZONE 'UTC')::date),
+			(start_date AT TIME ZONE 'UTC')::date),
+		$2
+	FROM user_info
+	WHERE %s = $1 AND deleted_at IS NULL
+	ON CONFLICT (subscription_id, effective_from) DO UPDATE SET price = EXCLUDED.price`
+
+func setPrice(ctx context.Context, tx pgx.Tx, keyCol string, key uuid.UUID, price int64, from *time.Time) error {
+	if _, err := tx.Exec(ctx, fmt.Sprintf(setPriceSQL, keyCol), key, price, from); err != nil {
+		return fmt.Errorf("repo: set price period: %w", err)
+	}
+	return nil
+}
+
+// PriceHistory returns the price periods of a live record, oldest first.
+func (p *Repo) PriceHistory(ctx context.Context, id uuid.UUID) ([]models.PricePeriod, error) {
+	ctx, cancel := p.withTimeout(ctx, queryRead)
+	defer cancel()
+
+	const q = `
+			SELECT pp.effective_from, pp.price
+			FROM user_info_prices pp
+			JOIN user_info u ON u.id = pp.subscription_id
+			WHERE u.id = $1 AND u.deleted_at IS NULL
+			ORDER BY pp.effective_from`
+	rows, err := p.pool.Query(ctx, q, id)
+	if err != nil {
+		return nil, fmt.Errorf("repo: select prices: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var out []models.PricePeriod
+	for rows.Next() {
+		var pp models.PricePeriod
+		if err := rows.Scan(&pp.EffectiveFrom, &pp.Price); err != nil {
+			return nil, fmt.Errorf("repo: scan prices: %w", err)
+		}
+		out = append(out, pp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: iterate prices: %w", classify(err))
+	}
+	// every record gets a period when it is created
+	if len(out) == 0 {
+		return nil, repo.ErrNotFound
+	}
+	return out, nil
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// bucketAvailSQL is the bucket's token count refilled up to now, capped at
+// the burst ($2). $3 is the refill rate in tokens per second.
+const bucketAvailSQL = `LEAST($2::float8, b.tokens + GREATEST(EXTRACT(EPOCH FROM now() - b.updated_at)::float8, 0) * $3::float8)`
+
+// TakeToken refills the bucket for key and takes one token from it if
+// there is one. The whole check is a single statement, so concurrent
+// replicas cannot both take the last token. A new bucket starts full.
+func (p *Repo) TakeToken(ctx context.Context, key string, rate float64, burst int) (bool, float64, error) {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const q = `
+			INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+			VALUES ($1, $2::float8 - 1, true, now())
+			ON CONFLICT (key) DO UPDATE SET
+				allowed    = ` + bucketAvailSQL + ` >= 1,
+				tokens     = ` + bucketAvailSQL + ` - CASE WHEN ` + bucketAvailSQL + ` >= 1 THEN 1 ELSE 0 END,
+				updated_at = now()
+			RETURNING allowed, tokens`
+
+	var (
+		allowed bool
+		tokens  float64
+	)
+	if err := p.pool.QueryRow(ctx, q, key, burst, rate).Scan(&allowed, &tokens); err != nil {
+		return false, 0, fmt.Errorf("repo: take token: %w", classify(err))
+	}
+	return allowed, tokens, nil
+}
+
+// DeleteIdleBuckets drops buckets untouched since before. A bucket that
+// has refilled completely behaves like a missing one.
+func (p *Repo) DeleteIdleBuckets(ctx context.Context, before time.Time) (int64, error) {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const q = `DELETE FROM rate_limit_buckets WHERE updated_at < $1`
+	ct, err := p.pool.Exec(ctx, q, before)
+	if err != nil {
+		return 0, fmt.Errorf("repo: delete idle buckets: %w", classify(err))
+	}
+	return ct.RowsAffected(), nil
+}
@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"user-aggregation/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExchangeRates returns every stored rate, ordered by pair and date.
+func (p *Repo) ExchangeRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	ctx, cancel := p.withTimeout(ctx, queryRead)
+	defer cancel()
+
+	const q = `
+			SELECT base, quote, rate::text, effective_from::timestamp AT TIME ZONE 'UTC'
+			FROM exchange_rates
+			ORDER BY base, quote, effective_from`
+	rows, err := p.pool.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("repo: select exchange rates: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var out []models.ExchangeRate
+	for rows.Next() {
+		var r models.ExchangeRate
+		var rate string
+		if err := rows.Scan(&r.Base, &r.Quote, &rate, &r.EffectiveFrom); err != nil {
+			return nil, fmt.Errorf("repo: scan exchange rates: %w", err)
+		}
+		r.Rate = trimRate(rate)
+		out = append(out, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: iterate exchange rates: %w", classify(err))
+	}
+	return out, nil
+}
+
+// SaveExchangeRates upserts rates by (base, quote, effective_from) in one
+// transaction.
+func (p *Repo) SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) error {
+	ctx, cancel := p.withTimeout(ctx, queryWrite)
+	defer cancel()
+
+	const q = `
+			INSERT INTO exchange_rates (base, quote, effective_from, rate)
+			VALUES ($1, $2, ($3::timestamptz AT TIME ZONE 'UTC')::date, $4::numeric)
+			ON CONFLICT (base, quote, effective_from) DO UPDATE SET rate = EXCLUDED.rate`
+	err := p.WithTx(ctx, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for _, r := range rates {
+			batch.Queue(q, r.Base, r.Quote, r.EffectiveFrom, string(r.Rate))
+		}
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil {
+		return fmt.Errorf("repo: save exchange rates: %w", classify(err))
+	}
+	return nil
+}
+
+// trimRate drops the padding zeros numeric(20,10) adds: 92.5000000000 -> 92.5.
+func trimRate(s string) json.Number {
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return json.Number(s)
+}
@@ -0,0 +1,20 @@
+package postgres
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrimRate(t *testing.T) {
+	cases := map[string]json.Number{
+		"92.5000000000": "92.5",
+		"1.0000000000":  "1",
+		"0.0108100000":  "0.01081",
+		"100":           "100",
+	}
+	for in, want := range cases {
+		require.Equal(t, want, trimRate(in), in)
+	}
+}
@@ -0,0 +1,40 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// ActiveByService counts live subscriptions that are running right now,
+// per service. Open-ended subscriptions count once they have started.
+func (p *Repo) ActiveByService(ctx context.Context) (map[string]int64, error) {
+	ctx, cancel := p.withTimeout(ctx, queryReport)
+	defer cancel()
+
+	const q = `
+			SELECT service_name, count(*)
+			FROM user_info
+			WHERE deleted_at IS NULL AND start_date <= now() AND COALESCE(end_date, 'infinity') >= now()
+			GROUP BY service_name`
+	rows, err := p.pool.Query(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("repo: count active subscriptions: %w", classify(err))
+	}
+	defer rows.Close()
+
+	out := make(map[string]int64)
+	for rows.Next() {
+		var (
+			service string
+			n       int64
+		)
+		if err := rows.Scan(&service, &n); err != nil {
+			return nil, fmt.Errorf("repo: scan active subscriptions: %w", err)
+		}
+		out[service] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: iterate active subscriptions: %w", classify(err))
+	}
+	return out, nil
+}
@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"user-aggregation/internal/repo"
+
+	"github.com/google/uuid"
+)
+
+// chargesFrom is the FROM clause of the summaries: one row per charge
+// (see charge_dates) of the price periods matching conds made in [lo, hi],
+// at the price of its period. A nil lo or hi leaves that side open; without
+// hi, open-ended subscriptions are charged up to now. It returns args with
+// the bounds appended.
+func chargesFrom(conds []string, args []any, lo, hi *time.Time, now time.Time) (string, []any) {
+	args = append(args, openBound(lo), openBound(hi), now)
+	n := len(args)
+	return fmt.Sprintf(`(
+			SELECT p.id, p.user_id, p.service_name, p.currency, p.price, c.charged_at
+			FROM user_info_periods p
+			CROSS JOIN LATERAL charge_dates(
+				p.billing_period, p.subscription_start, p.subscription_end,
+				GREATEST(p.start_date, $%d::timestamptz),
+				COALESCE(LEAST(p.end_date, $%d::timestamptz), $%d::timestamptz)
+			) AS c(charged_at)
+			WHERE %s
+		) charges`, n-2, n-1, n, strings.Join(conds, " AND ")), args
+}
+
+// openBound is t, or nil (SQL NULL) when t is unset.
+func openBound(t *time.Time) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// FilterSum adds up every charge (see package cost) made in [start, end],
+// each at the price in effect on its date. Each currency is summed
+// separately. Without an end, open-ended subscriptions count up to now.
+func (p *Repo) FilterSum(
+	ctx context.Context,
+	userID *uuid.UUID,
+	serviceName *string,
+	start, end *time.Time,
+) ([]repo.CurrencyTotal, error) {
+	ctx, cancel := p.withTimeout(ctx, queryReport)
+	defer cancel()
+
+	conds, args := summaryConds(userID, serviceName, start, end)
+	from, args := chargesFrom(conds, args, start, end, time.Now())
+
+	q := `
+		SELECT currency, SUM(price)::bigint
+		FROM ` + from + `
+		GROUP BY currency
+		ORDER BY currency`
+
+	rows, err := p.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: filter sum: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var out []repo.CurrencyTotal
+	for rows.Next() {
+		var t repo.CurrencyTotal
+		if err := rows.Scan(&t.Currency, &t.Total); err != nil {
+			return nil, fmt.Errorf("repo: scan filter sum: %w", err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: iterate filter sum: %w", classify(err))
+	}
+	return out, nil
+}
+
+// GroupedSum is FilterSum split by groupBy and currency, with count and
+// avg/min/max of the charges computed in the same aggregation. The count is
+// of distinct records charged in the range. GroupByMonth buckets charges by
+// the month they are made in.
+func (p *Repo) GroupedSum(
+	ctx context.Context,
+	groupBy repo.GroupBy,
+	userID *uuid.UUID,
+	serviceName *string,
+	start, end *time.Time,
+) ([]repo.GroupStats, error) {
+	ctx, cancel := p.withTimeout(ctx, queryReport)
+	defer cancel()
+
+	var key string
+	switch groupBy {
+	case repo.GroupByService:
+		key = "service_name"
+	case repo.GroupByUser:
+		key = "user_id::text"
+	case repo.GroupByMonth:
+		key = "to_char(charged_at AT TIME ZONE 'UTC', 'YYYY-MM')"
+	default:
+		return nil, errors.Join(repo.ErrBadInput, fmt.Errorf("unknown group_by %q", groupBy))
+	}
+
+	conds, args := summaryConds(userID, serviceName, start, end)
+	from, args := chargesFrom(conds, args, start, end, time.Now())
+
+	q := fmt.Sprintf(`
+		SELECT %s AS key,
+			currency,
+			COUNT(*),
+			SUM(price)::bigint,
+			COUNT(DISTINCT id),
+			ROUND(AVG(price), 2)::float8,
+			MIN(price),
+			MAX(price)
+		FROM %s
+		GROUP BY key, currency
+		ORDER BY key, currency
+	`, key, from)
+
+	rows, err := p.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: grouped sum: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var out []repo.GroupStats
+	for rows.Next() {
+		var g repo.GroupStats
+		if err := rows.Scan(&g.Key, &g.Currency, &g.Charges, &g.TotalCost, &g.SubscriptionCount, &g.AvgPrice, &g.MinPrice, &g.MaxPrice); err != nil {
+			return nil, fmt.Errorf("repo: scan grouped sum: %w", err)
+		}
+		out = append(out, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: iterate grouped sum: %w", classify(err))
+	}
+	return out, nil
+}
+
+// MonthlyCost adds up the cost of every calendar month of [from, to],
+// optionally split by service name or user ID. With repo.BasisCharges a
+// month gets the charges made in it: a yearly subscription only counts in
+// the month it renews in, a weekly one four or five times a month. With
+// repo.BasisProrated see proratedMonths. Each currency is a separate row.
+// Months without any cost are not returned.
+func (p *Repo) MonthlyCost(
+	ctx context.Context,
+	userID *uuid.UUID,
+	serviceName *string,
+	from, to time.Time,
+	groupBy repo.GroupBy,
+	basis repo.Basis,
+) ([]repo.MonthlyCost, error) {
+	ctx, cancel := p.withTimeout(ctx, queryReport)
+	defer cancel()
+
+	var key string
+	switch groupBy {
+	case repo.GroupByNone:
+		key = "''"
+	case repo.GroupByService:
+		key = "service_name"
+	case repo.GroupByUser:
+		key = "user_id::text"
+	default:
+		return nil, errors.Join(repo.ErrBadInput, fmt.Errorf("unknown group_by %q", groupBy))
+	}
+
+	first := monthStart(from)
+	last := monthStart(to).AddDate(0, 1, 0).Add(-time.Microsecond)
+	conds, args := summaryConds(userID, serviceName, &first, &last)
+
+	var q string
+	switch basis {
+	case repo.BasisCharges:
+		var src string
+		src, args = chargesFrom(conds, args, &first, &last, time.Now())
+		q = fmt.Sprintf(`
+			SELECT date_trunc('month', charged_at AT TIME ZONE 'UTC') AS month, %s AS key, currency, SUM(price)::bigint
+			FROM %s
+			GROUP BY month, key, currency
+			ORDER BY month, key, currency
+		`, key, src)
+	case repo.BasisProrated:
+		q, args = proratedMonths(key, conds, args, first, monthStart(to))
+	default:
+		return nil, errors.Join(repo.ErrBadInput, fmt.Errorf("unknown basis %q", basis))
+	}
+
+	rows, err := p.pool.Query(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: monthly cost: %w", classify(err))
+	}
+	defer rows.Close()
+
+	var out []repo.MonthlyCost
+	for rows.Next() {
+		var c repo.MonthlyCost
+		if err := rows.Scan(&c.Month, &c.Key, &c.Currency, &c.Cost); err != nil {
+			return nil, fmt.Errorf("repo: scan monthly cost: %w", err)
+		}
+		c.Month = monthStart(c.Month)
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: iterate monthly cost: %w", classify(err))
+	}
+	return out, nil
+}
+
+// proratedMonths is the MonthlyCost query for repo.BasisProrated. Every
+// price period matching conds is split into the months first..last and
+// each month gets its share of the price by covered days: price * days /
+// days_in_month for monthly, a twelfth of that for yearly and price *
+// days / 7 for weekly subscriptions. Both ends of a period are inclusive
+// calendar days in UTC, so a monthly 2025-01-15..2025-02-14 is 17/31 of
+// January plus 14/28 of February. A one-off price counts whole in the
+// month of its start. It returns args with the months appended.
+func proratedMonths(key string, conds []string, args []any, first, last time.Time) (string, []any) {
+	args = append(args, first, last)
+	n := len(args)
+	return fmt.Sprintf(`
+		WITH months AS (
+			SELECT m::date AS first_day, (m + interval '1 month')::date AS next_first
+			FROM generate_series($%d::timestamp, $%d::timestamp, interval '1 month') AS m
+		), periods AS (
+			SELECT user_id, service_name, currency, price, billing_period,
+				start_date = subscription_start AS first_period,
+				(start_date AT TIME ZONE 'UTC')::date AS start_day,
+				COALESCE((end_date AT TIME ZONE 'UTC')::date, 'infinity'::date) AS end_day
+			FROM user_info_periods
+			WHERE %s
+		), shares AS (
+			SELECT m.first_day, p.*,
+				(LEAST(p.end_day, m.next_first - 1) - GREATEST(p.start_day, m.first_day) + 1)::numeric AS days,
+				m.next_first - m.first_day AS month_days
+			FROM months m
+			JOIN periods p ON p.start_day < m.next_first AND p.end_day >= m.first_day
+		)
+		SELECT first_day, %s AS key, currency, ROUND(SUM(
+			CASE billing_period
+				WHEN 'weekly' THEN price * days / 7
+				WHEN 'yearly' THEN price * days / month_days / 12
+				WHEN 'one_off' THEN CASE WHEN first_period AND start_day >= first_day THEN price ELSE 0 END
+				ELSE price * days / month_days
+			END
+		))::bigint
+		FROM shares
+		GROUP BY first_day, key, currency
+		ORDER BY first_day, key, currency
+	`, n-1, n, strings.Join(conds, " AND "), key), args
+}
+
+// monthStart is the first instant of the UTC month of t.
+func monthStart(t time.Time) time.Time {
+	y, m, _ := t.UTC().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
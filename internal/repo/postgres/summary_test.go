@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+	"user-aggregation/internal/cost"
+	"user-aggregation/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChargesFrom(t *testing.T) {
+	svc := "Netflix"
+	end := time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	conds, args := summaryConds(nil, &svc, &time.Time{}, &end)
+	from, args := chargesFrom(conds, args, &time.Time{}, &end, now)
+
+	// filters first, then the charge bounds; a zero start is NULL
+	require.Equal(t, []any{end, svc, (*time.Time)(nil), &end, now}, args)
+	require.Contains(t, from, "GREATEST(p.start_date, $3::timestamptz)")
+	require.Contains(t, from, "COALESCE(LEAST(p.end_date, $4::timestamptz), $5::timestamptz)")
+	require.Contains(t, from, "WHERE deleted_at IS NULL AND start_date <= $1 AND service_name = $2")
+}
+
+func TestProratedMonths(t *testing.T) {
+	first := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	last := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := last.AddDate(0, 1, 0).Add(-time.Microsecond)
+
+	conds, args := summaryConds(nil, nil, &first, &end)
+	q, args := proratedMonths("service_name", conds, args, first, last)
+
+	require.Equal(t, []any{first, end, first, last}, args)
+	require.Contains(t, q, "generate_series($3::timestamp, $4::timestamp, interval '1 month')")
+	require.Contains(t, q, "WHERE deleted_at IS NULL AND COALESCE(end_date, 'infinity') >= $1 AND start_date <= $2")
+	require.Contains(t, q, "SELECT first_day, service_name AS key")
+}
+
+// TestChargeDates_MatchesCost checks the charge_dates SQL function against
+// package cost. It needs a migrated database in TEST_DATABASE_URL and only
+// reads from it.
+func TestChargeDates_MatchesCost(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, url)
+	require.NoError(t, err)
+	defer conn.Close(ctx)
+
+	at := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, time.UTC) }
+
+	for name, tc := range map[string]struct {
+		s      cost.Schedule
+		lo, hi time.Time
+	}{
+		"month end":          {cost.Schedule{Period: models.BillingMonthly, Start: at(2025, 1, 31, 0)}, at(2025, 1, 31, 0), at(2025, 12, 31, 0)},
+		"month end, leap":    {cost.Schedule{Period: models.BillingMonthly, Start: at(2023, 12, 30, 0)}, at(2023, 12, 30, 0), at(2024, 4, 1, 0)},
+		"time of day":        {cost.Schedule{Period: models.BillingMonthly, Start: at(2025, 1, 10, 15)}, at(2025, 3, 10, 15), at(2025, 6, 10, 14)},
+		"long history":       {cost.Schedule{Period: models.BillingMonthly, Start: at(2015, 5, 31, 0)}, at(2025, 2, 1, 0), at(2025, 5, 31, 0)},
+		"stop is exclusive":  {cost.Schedule{Period: models.BillingMonthly, Start: at(2025, 1, 10, 0), End: at(2025, 4, 10, 0)}, at(2025, 1, 10, 0), at(2025, 12, 31, 0)},
+		"yearly, 29 Feb":     {cost.Schedule{Period: models.BillingYearly, Start: at(2024, 2, 29, 0)}, at(2024, 2, 29, 0), at(2032, 3, 1, 0)},
+		"yearly, from later": {cost.Schedule{Period: models.BillingYearly, Start: at(2020, 7, 1, 0)}, at(2025, 1, 1, 0), at(2027, 12, 31, 0)},
+		"weekly":             {cost.Schedule{Period: models.BillingWeekly, Start: at(2025, 3, 27, 9)}, at(2025, 4, 1, 0), at(2025, 5, 31, 0)},
+		"one-off in range":   {cost.Schedule{Period: models.BillingOneOff, Start: at(2025, 6, 15, 0), End: at(2025, 6, 15, 0)}, at(2025, 6, 1, 0), at(2025, 6, 30, 0)},
+		"one-off outside":    {cost.Schedule{Period: models.BillingOneOff, Start: at(2025, 6, 15, 0)}, at(2025, 7, 1, 0), at(2025, 7, 31, 0)},
+		"range before start": {cost.Schedule{Period: models.BillingMonthly, Start: at(2025, 6, 15, 0)}, at(2025, 1, 1, 0), at(2025, 6, 14, 0)},
+	} {
+		var stop *time.Time
+		if !tc.s.End.IsZero() {
+			stop = &tc.s.End
+		}
+		rows, err := conn.Query(ctx, `SELECT d FROM charge_dates($1, $2, $3, $4, $5) AS d`,
+			string(tc.s.Period), tc.s.Start, stop, tc.lo, tc.hi)
+		require.NoError(t, err, name)
+		got, err := pgx.CollectRows(rows, func(r pgx.CollectableRow) (time.Time, error) {
+			var d time.Time
+			err := r.Scan(&d)
+			return d.UTC(), err
+		})
+		require.NoError(t, err, name)
+		if want := tc.s.Charges(tc.lo, tc.hi); len(want) > 0 {
+			require.Equal(t, want, got, name)
+		} else {
+			require.Empty(t, got, name)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"context"
+	"time"
+)
+
+// queryClass groups repository calls that share a timeout.
+type queryClass int
+
+const (
+	queryRead   queryClass = iota // lookups and listings
+	queryWrite                    // single-record changes
+	queryReport                   // aggregations and exports
+	queryBulk                     // bulk imports
+)
+
+// Timeouts bound a single repository call per query class. Zero means no
+// limit of its own; a deadline already on the context still applies.
+type Timeouts struct {
+	Read   time.Duration
+	Write  time.Duration
+	Report time.Duration
+	Bulk   time.Duration
+}
+
+func (t Timeouts) of(c queryClass) time.Duration {
+	switch c {
+	case queryWrite:
+		return t.Write
+	case queryReport:
+		return t.Report
+	case queryBulk:
+		return t.Bulk
+	default:
+		return t.Read
+	}
+}
+
+// longest is the largest class timeout, or 0 if any class is unlimited.
+func (t Timeouts) longest() time.Duration {
+	var out time.Duration
+	for _, d := range []time.Duration{t.Read, t.Write, t.Report, t.Bulk} {
+		if d <= 0 {
+			return 0
+		}
+		out = max(out, d)
+	}
+	return out
+}
+
+// withTimeout bounds ctx by the timeout of class c. When the deadline
+// passes, pgx sends a cancel request so the query stops on the server too.
+func (p *Repo) withTimeout(ctx context.Context, c queryClass) (context.Context, context.CancelFunc) {
+	d := p.timeouts.of(c)
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, d)
+}
@@ -0,0 +1,45 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeouts(t *testing.T) {
+	to := Timeouts{Read: time.Second, Write: 2 * time.Second, Report: 5 * time.Second, Bulk: 30 * time.Second}
+	require.Equal(t, time.Second, to.of(queryRead))
+	require.Equal(t, 2*time.Second, to.of(queryWrite))
+	require.Equal(t, 5*time.Second, to.of(queryReport))
+	require.Equal(t, 30*time.Second, to.of(queryBulk))
+	require.Equal(t, 30*time.Second, to.longest())
+
+	to.Bulk = 0
+	require.Zero(t, to.longest(), "an unlimited class leaves statement_timeout unset")
+}
+
+func TestWithTimeout(t *testing.T) {
+	p := &Repo{timeouts: Timeouts{Read: time.Minute}}
+
+	ctx, cancel := p.withTimeout(context.Background(), queryRead)
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+
+	// an earlier request deadline wins
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Second)
+	defer cancelParent()
+	ctx, cancel = p.withTimeout(parent, queryRead)
+	defer cancel()
+	deadline, _ = ctx.Deadline()
+	require.WithinDuration(t, time.Now().Add(time.Second), deadline, time.Second)
+
+	// no class timeout, no new deadline
+	ctx, cancel = p.withTimeout(context.Background(), queryWrite)
+	defer cancel()
+	_, ok = ctx.Deadline()
+	require.False(t, ok)
+}
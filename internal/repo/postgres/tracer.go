@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// queryTracer turns every statement and batch into a client span under
+// whatever span is in the query context. It uses the global tracer
+// provider, so it costs nothing until tracing is set up.
+type queryTracer struct{}
+
+var (
+	_ pgx.QueryTracer = queryTracer{}
+	_ pgx.BatchTracer = queryTracer{}
+)
+
+func (queryTracer) start(ctx context.Context, name string, attrs ...attribute.KeyValue) context.Context {
+	attrs = append(attrs, semconv.DBSystemNamePostgreSQL)
+	ctx, _ = otel.Tracer("user-aggregation/postgres").Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+func (queryTracer) end(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return t.start(ctx, "db "+sqlOperation(data.SQL), semconv.DBQueryText(data.SQL))
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	t.end(ctx, data.Err)
+}
+
+func (t queryTracer) TraceBatchStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	return t.start(ctx, "db BATCH", semconv.DBOperationBatchSize(data.Batch.Len()))
+}
+
+// TraceBatchQuery records each statement of a batch as an event; they all
+// run in one round trip, so separate spans would tell nothing more.
+func (queryTracer) TraceBatchQuery(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchQueryData) {
+	attrs := []attribute.KeyValue{semconv.DBQueryText(data.SQL)}
+	if data.Err != nil {
+		attrs = append(attrs, attribute.String("error", data.Err.Error()))
+	}
+	trace.SpanFromContext(ctx).AddEvent("query", trace.WithAttributes(attrs...))
+}
+
+func (t queryTracer) TraceBatchEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceBatchEndData) {
+	t.end(ctx, data.Err)
+}
+
+// sqlOperation is the first keyword of a statement (SELECT, INSERT, WITH, ...).
+func sqlOperation(sql string) string {
+	op, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	op, _, _ = strings.Cut(op, "\n")
+	op, _, _ = strings.Cut(op, "\t")
+	return strings.ToUpper(op)
+}
@@ -0,0 +1,14 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLOperation(t *testing.T) {
+	require.Equal(t, "SELECT", sqlOperation("\n\t\t\tSELECT id FROM user_info"))
+	require.Equal(t, "WITH", sqlOperation("WITH up AS (...) SELECT 1"))
+	require.Equal(t, "UPDATE", sqlOperation("update\nuser_info SET price = 1"))
+	require.Equal(t, "", sqlOperation("  "))
+}
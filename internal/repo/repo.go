@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 	"user-aggregation/internal/models"
 
@@ -11,11 +12,164 @@ import (
 
 type Repo interface {
 	Insert(ctx context.Context, u *models.UserInfo) error
+	Upsert(ctx context.Context, u *models.UserInfo) (created bool, err error)
+	BulkUpsert(ctx context.Context, items []models.UserInfo, atomic bool) ([]BulkResult, error)
 	DeleteByUserID(ctx context.Context, userID uuid.UUID) (int64, error)
-	UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int64, end *time.Time) (int64, error)
+	UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (int64, error)
 	List(ctx context.Context) ([]models.UserInfo, error)
+	ListPage(ctx context.Context, params ListParams) (Page, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.UserInfo, error)
-	FilterSum(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time) (int64, error)
+	Stream(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time, fn func(models.UserInfo) error) error
+	FilterSum(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time) ([]CurrencyTotal, error)
+	GroupedSum(ctx context.Context, groupBy GroupBy, userID *uuid.UUID, serviceName *string, start, end *time.Time) ([]GroupStats, error)
+	MonthlyCost(ctx context.Context, userID *uuid.UUID, serviceName *string, from, to time.Time, groupBy GroupBy, basis Basis) ([]MonthlyCost, error)
+
+	GetByID(ctx context.Context, id uuid.UUID) (models.UserInfo, error)
+	UpdateByID(ctx context.Context, id uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (models.UserInfo, error)
+	PriceHistory(ctx context.Context, id uuid.UUID) ([]models.PricePeriod, error)
+	DeleteByID(ctx context.Context, id uuid.UUID) error
+
+	RestoreByUserID(ctx context.Context, userID uuid.UUID) (RestoreResult, error)
+	RestoreByID(ctx context.Context, id uuid.UUID) (models.UserInfo, error)
+	PurgeByUserID(ctx context.Context, userID uuid.UUID) (int64, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+
+	History(ctx context.Context, userID uuid.UUID, afterID int64, limit int) ([]models.AuditEntry, error)
+
+	ExchangeRates(ctx context.Context) ([]models.ExchangeRate, error)
+	SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) error
+}
+
+// RestoreResult is the outcome of RestoreByUserID. Conflicts are the records
+// left in the trash because a live record has the same user, service and
+// start date.
+type RestoreResult struct {
+	Restored  int64
+	Conflicts []models.UserInfo
+}
+
+// BulkResult is the outcome of one item of BulkUpsert, at the same index
+// as the item. Err is set when the row was rejected by the database.
+type BulkResult struct {
+	Created bool
+	Err     error
+}
+
+// SortField is a column that ListPage can order by.
+type SortField string
+
+const (
+	SortByPrice       SortField = "price"
+	SortByStartDate   SortField = "start_date"
+	SortByEndDate     SortField = "end_date"
+	SortByServiceName SortField = "service_name"
+)
+
+// Valid reports whether f is one of the supported sort columns.
+func (f SortField) Valid() bool {
+	switch f {
+	case SortByPrice, SortByStartDate, SortByEndDate, SortByServiceName:
+		return true
+	}
+	return false
+}
+
+// ListParams describes one page of a keyset-paginated listing.
+// Zero values mean "no filter".
+type ListParams struct {
+	Limit  int
+	Cursor string // opaque, taken from Page.NextCursor of the previous page
+	Sort   SortField
+	Desc   bool
+
+	ServicePrefix string
+	MinPrice      *int64
+	MaxPrice      *int64
+	ActiveAt      *time.Time
+
+	Deleted bool // list the trash instead of live records
+}
+
+// Page is a slice of records plus the cursor for the next one.
+// NextCursor is empty on the last page.
+type Page struct {
+	Items      []models.UserInfo
+	NextCursor string
+}
+
+// GroupBy is an optional extra dimension for summary aggregations.
+type GroupBy string
+
+const (
+	GroupByNone    GroupBy = ""
+	GroupByService GroupBy = "service_name"
+	GroupByUser    GroupBy = "user_id"
+	GroupByMonth   GroupBy = "month"
+)
+
+// Valid reports whether g is a supported grouping.
+func (g GroupBy) Valid() bool {
+	switch g {
+	case GroupByNone, GroupByService, GroupByUser, GroupByMonth:
+		return true
+	}
+	return false
+}
+
+// Basis is how MonthlyCost puts the cost of a subscription into months.
+type Basis string
+
+const (
+	// BasisCharges counts every charge in full in the month it is made.
+	BasisCharges Basis = "charges"
+	// BasisProrated spreads the price over the days of the billing period:
+	// a month gets price * covered_days / days_in_period. One-off charges
+	// stay whole.
+	BasisProrated Basis = "prorated"
+)
+
+// Valid reports whether b is a supported basis.
+func (b Basis) Valid() bool {
+	return b == BasisCharges || b == BasisProrated
+}
+
+// CurrencyTotal is a sum of prices in one currency.
+type CurrencyTotal struct {
+	Currency string
+	Total    int64
+}
+
+// GroupStats is one row of a grouped summary. Key is the service name,
+// the user ID or the charge month (YYYY-MM) depending on GroupBy; prices
+// in different currencies are separate rows.
+type GroupStats struct {
+	Key               string
+	Currency          string
+	Charges           int64 // charges AvgPrice is taken over
+	TotalCost         int64
+	SubscriptionCount int64
+	AvgPrice          float64
+	MinPrice          int64
+	MaxPrice          int64
+}
+
+// MonthlyCost is the cost charged in one calendar month, optionally for
+// a single group key (service name or user ID).
+type MonthlyCost struct {
+	Month    time.Time // first day of the month, UTC
+	Key      string    // empty when not grouped
+	Currency string
+	Cost     int64
+}
+
+// APIKey is a stored API key. The key itself is never stored, only its hash.
+type APIKey struct {
+	ID        uuid.UUID
+	Name      string
+	Prefix    string // first characters of the key, for telling keys apart
+	Scopes    []string
+	CreatedAt time.Time
+	RevokedAt *time.Time
 }
 
 var (
@@ -23,4 +177,9 @@ var (
 	ErrConflict   = errors.New("conflict")
 	ErrBadInput   = errors.New("bad input")
 	ErrConstraint = errors.New("constraint violation")
+	ErrTimeout    = errors.New("timeout")
+
+	// ErrEndBeforeStart is the ErrConstraint of an end_date before the
+	// start_date of the stored subscription.
+	ErrEndBeforeStart = fmt.Errorf("%w: end_date before start_date", ErrConstraint)
 )
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"user-aggregation/internal/lib/validation"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/transport/http/respond"
+)
+
+const (
+	maxBulkItems     = 10000
+	maxBulkBodyBytes = 32 << 20
+	maxNDJSONLine    = 1 << 20
+
+	bulkModeAtomic     = "atomic"
+	bulkModeBestEffort = "best_effort"
+
+	bulkCreated  = "created"
+	bulkUpdated  = "updated"
+	bulkRejected = "rejected"
+	bulkSkipped  = "skipped"
+)
+
+// bulkItem is one decoded input item; err is set if it could not be decoded.
+type bulkItem struct {
+	line int
+	info models.UserInfo
+	err  error
+}
+
+// errBulkTooLarge is returned by the readers when the item limit is hit.
+var errBulkTooLarge = fmt.Errorf("too many items (max %d)", maxBulkItems)
+
+// BulkLoad godoc
+// @Summary Bulk import subscriptions
+// @Description Upserts many subscriptions at once from a JSON array or NDJSON (Content-Type: application/x-ndjson).
+// @Description atomic mode saves everything or nothing; best_effort saves every valid item and reports the rest.
+// @Tags users
+// @Accept json
+// @Accept application/x-ndjson
+// @Produce json
+// @Param mode query string false "atomic (default) or best_effort"
+// @Param items body []models.UserInfo true "Subscriptions"
+// @Success 200 {object} response.BulkReport
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 422 {object} response.BulkReport "atomic import rolled back"
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /users/bulk [post]
+func (h *HTTP) BulkLoad(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.bulk_load"
+
+	mode, ok := parseBulkMode(r)
+	if !ok {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid mode (use atomic or best_effort)", nil)
+		return
+	}
+
+	defer r.Body.Close()
+	body := http.MaxBytesReader(w, r.Body, maxBulkBodyBytes)
+
+	var (
+		items []bulkItem
+		err   error
+	)
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt == "application/x-ndjson" || mt == "application/ndjson" {
+		items, err = readNDJSON(body)
+	} else {
+		items, err = readJSONArray(body)
+	}
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid bulk body: "+err.Error(), err)
+		return
+	}
+
+	h.importItems(w, r, op, mode, items)
+}
+
+func parseBulkMode(r *http.Request) (string, bool) {
+	switch mode := r.URL.Query().Get("mode"); mode {
+	case "":
+		return bulkModeAtomic, true
+	case bulkModeAtomic, bulkModeBestEffort:
+		return mode, true
+	default:
+		return "", false
+	}
+}
+
+// importItems validates and upserts decoded items and writes the report.
+// Items that failed to decode are reported as rejected.
+func (h *HTTP) importItems(w http.ResponseWriter, r *http.Request, op, mode string, items []bulkItem) {
+	ctx := r.Context()
+	atomic := mode == bulkModeAtomic
+
+	report := response.BulkReport{
+		Mode:    mode,
+		Results: make([]response.BulkResult, len(items)),
+	}
+
+	valid := make([]models.UserInfo, 0, len(items))
+	validIdx := make([]int, 0, len(items))
+	for i, it := range items {
+		report.Results[i].Line = it.line
+		if it.err != nil {
+			rejectItem(&report, i, it.err.Error(), nil)
+			continue
+		}
+		h.withDefaults(&it.info)
+		if errs := validation.UserInfo(&it.info); len(errs) > 0 {
+			rejectItem(&report, i, "validation failed", errs)
+			continue
+		}
+		valid = append(valid, it.info)
+		validIdx = append(validIdx, i)
+	}
+
+	if atomic && report.Rejected > 0 {
+		skipPending(&report)
+		respond.Writer(w, r, op, http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	results, err := h.DB.BulkUpsert(ctx, valid, atomic)
+	rowFailed := false
+	for j, res := range results {
+		if res.Err != nil {
+			rowFailed = true
+			reason := respond.Reason(res.Err)
+			if reason == "" {
+				reason = "database error"
+			}
+			rejectItem(&report, validIdx[j], reason, nil)
+		}
+	}
+	if err != nil && !(atomic && rowFailed) {
+		respond.RepoError(w, r, op, "failed to import", err)
+		return
+	}
+	if err != nil {
+		skipPending(&report)
+		respond.Writer(w, r, op, http.StatusUnprocessableEntity, report)
+		return
+	}
+
+	report.Committed = true
+	for j, res := range results {
+		i := validIdx[j]
+		if report.Results[i].Status != "" {
+			continue
+		}
+		report.Results[i].ID = valid[j].ID.String()
+		if res.Created {
+			report.Results[i].Status = bulkCreated
+			report.Created++
+		} else {
+			report.Results[i].Status = bulkUpdated
+			report.Updated++
+		}
+	}
+	respond.Writer(w, r, op, http.StatusOK, report)
+}
+
+func rejectItem(report *response.BulkReport, i int, reason string, errs validation.Errors) {
+	report.Results[i].Status = bulkRejected
+	report.Results[i].Reason = reason
+	if len(errs) > 0 {
+		report.Results[i].Errors = respond.FieldErrors(errs)
+	}
+	report.Rejected++
+}
+
+// skipPending marks every item without an outcome as skipped.
+func skipPending(report *response.BulkReport) {
+	for i := range report.Results {
+		if report.Results[i].Status == "" {
+			report.Results[i].Status = bulkSkipped
+		}
+	}
+}
+
+func readNDJSON(body io.Reader) ([]bulkItem, error) {
+	sc := bufio.NewScanner(body)
+	sc.Buffer(make([]byte, 0, 64*1024), maxNDJSONLine)
+
+	var items []bulkItem
+	line := 0
+	for sc.Scan() {
+		line++
+		raw := bytes.TrimSpace(sc.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		if len(items) == maxBulkItems {
+			return nil, errBulkTooLarge
+		}
+
+		it := bulkItem{line: line}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&it.info); err != nil {
+			it.err = fmt.Errorf("invalid JSON: %w", err)
+		} else if dec.More() {
+			it.err = errors.New("invalid JSON: more than one value on the line")
+		}
+		items = append(items, it)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// readJSONArray streams the array element by element. A value of the wrong
+// shape only rejects that element; broken JSON fails the whole body.
+func readJSONArray(body io.Reader) ([]bulkItem, error) {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return nil, errors.New("expected a JSON array")
+	}
+
+	var items []bulkItem
+	for dec.More() {
+		if len(items) == maxBulkItems {
+			return nil, errBulkTooLarge
+		}
+		it := bulkItem{line: len(items) + 1}
+		if err := dec.Decode(&it.info); err != nil {
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, err
+			}
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, err
+			}
+			it.err = fmt.Errorf("invalid JSON: %w", err)
+		}
+		items = append(items, it)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
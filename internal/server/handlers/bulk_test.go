@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func bulkLine(svc string, price int64) string {
+	return fmt.Sprintf(`{"service_name":%q,"price":%d,"user_id":%q,"start_date":"2025-01-01T00:00:00Z","end_date":"2025-12-31T00:00:00Z"}`,
+		svc, price, uuid.New())
+}
+
+func decodeReport(t *testing.T, w *httptest.ResponseRecorder) response.BulkReport {
+	t.Helper()
+	var out response.BulkReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	return out
+}
+
+func TestBulkLoad_NDJSON_BestEffort(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	body := strings.Join([]string{
+		bulkLine("A", 100),
+		"",
+		bulkLine("B", -1),
+		`{"service_name":`,
+		bulkLine("C", 300),
+		bulkLine("D", 400),
+	}, "\n")
+
+	m.On("BulkUpsert", mock.Anything, mock.MatchedBy(func(items []models.UserInfo) bool {
+		return len(items) == 3 && items[0].ServiceName == "A" && items[1].ServiceName == "C" && items[2].ServiceName == "D"
+	}), false).
+		Return([]repo.BulkResult{
+			{Created: true},
+			{Created: false},
+			{Err: fmt.Errorf("%w: 23514", repo.ErrConstraint)},
+		}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/bulk?mode=best_effort", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	h.BulkLoad(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	out := decodeReport(t, w)
+	require.True(t, out.Committed)
+	require.Equal(t, 1, out.Created)
+	require.Equal(t, 1, out.Updated)
+	require.Equal(t, 3, out.Rejected)
+
+	require.Len(t, out.Results, 5)
+	byLine := map[int]response.BulkResult{}
+	for _, r := range out.Results {
+		byLine[r.Line] = r
+	}
+	require.Equal(t, "created", byLine[1].Status)
+	require.Equal(t, "rejected", byLine[3].Status)
+	require.Equal(t, []response.FieldError{{Field: "price", Code: "negative"}}, byLine[3].Errors)
+	require.Equal(t, "rejected", byLine[4].Status)
+	require.Contains(t, byLine[4].Reason, "invalid JSON")
+	require.Equal(t, "updated", byLine[5].Status)
+	require.Equal(t, "rejected", byLine[6].Status)
+	require.Equal(t, "constraint violation", byLine[6].Reason)
+	m.AssertExpectations(t)
+}
+
+func TestBulkLoad_JSONArray_AtomicRejectsInvalid(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	body := "[" + bulkLine("A", 100) + "," + bulkLine("", 100) + "]"
+	req := httptest.NewRequest(http.MethodPost, "/users/bulk", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	h.BulkLoad(w, req)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	out := decodeReport(t, w)
+	require.Equal(t, "atomic", out.Mode)
+	require.False(t, out.Committed)
+	require.Equal(t, "skipped", out.Results[0].Status)
+	require.Equal(t, "rejected", out.Results[1].Status)
+	m.AssertNotCalled(t, "BulkUpsert", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBulkLoad_AtomicRolledBack(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	rowErr := fmt.Errorf("%w: 23514", repo.ErrConstraint)
+	m.On("BulkUpsert", mock.Anything, mock.Anything, true).
+		Return([]repo.BulkResult{{Created: true}, {Err: rowErr}}, fmt.Errorf("repo: bulk upsert item 1: %w", rowErr)).
+		Once()
+
+	body := "[" + bulkLine("A", 100) + "," + bulkLine("B", 200) + "]"
+	req := httptest.NewRequest(http.MethodPost, "/users/bulk?mode=atomic", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.BulkLoad(w, req)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	out := decodeReport(t, w)
+	require.False(t, out.Committed)
+	require.Equal(t, 0, out.Created)
+	require.Equal(t, "skipped", out.Results[0].Status)
+	require.Equal(t, "rejected", out.Results[1].Status)
+	m.AssertExpectations(t)
+}
+
+func TestBulkLoad_BadBody(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	for _, body := range []string{`{"not":"an array"}`, `[{"service_name":"A",`, `[` + bulkLine("A", 1) + ` oops]`} {
+		req := httptest.NewRequest(http.MethodPost, "/users/bulk", bytes.NewReader([]byte(body)))
+		w := httptest.NewRecorder()
+
+		h.BulkLoad(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users/bulk?mode=yolo", strings.NewReader("[]"))
+	w := httptest.NewRecorder()
+	h.BulkLoad(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	m.AssertNotCalled(t, "BulkUpsert", mock.Anything, mock.Anything, mock.Anything)
+}
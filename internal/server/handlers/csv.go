@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"user-aggregation/internal/lib/reqctx"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/transport/http/respond"
+
+	"github.com/google/uuid"
+)
+
+const csvFlushEvery = 500
+
+// csvColumns is the export header.
+var csvColumns = []string{"id", "user_id", "service_name", "price", "currency", "billing_period", "start_date", "end_date"}
+
+// csvRequired are the columns import needs; id is ignored and a missing
+// currency or billing_period means the default one.
+var csvRequired = []string{"user_id", "service_name", "price", "start_date", "end_date"}
+
+// csvDateFormats maps the date_format parameter to a time layout.
+var csvDateFormats = map[string]string{
+	"rfc3339":    time.RFC3339,
+	"yyyy-mm-dd": "2006-01-02",
+	"dd.mm.yyyy": "02.01.2006",
+}
+
+// csvDelimiters maps the delimiter parameter to a separator. Names are
+// accepted because a bare ';' is not a valid query string character.
+var csvDelimiters = map[string]rune{
+	"":          ',',
+	",":         ',',
+	"comma":     ',',
+	";":         ';',
+	"semicolon": ';',
+	"\t":        '\t',
+	"tab":       '\t',
+	"|":         '|',
+	"pipe":      '|',
+}
+
+type csvOptions struct {
+	comma      rune
+	dateLayout string
+}
+
+// ExportCSV godoc
+// @Summary Export subscriptions as CSV
+// @Description Streams matching records as CSV. Takes the same filters as /summary.
+// @Tags users
+// @Produce text/csv
+// @Param service_name query string false "Filter by service name"
+// @Param user_id query string false "Filter by user ID (UUID)"
+// @Param start_date query string false "Filter by start date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)"
+// @Param end_date query string false "Filter by end date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its last moment)"
+// @Param delimiter query string false "comma (default), semicolon, tab or pipe"
+// @Param date_format query string false "rfc3339 (default), yyyy-mm-dd or dd.mm.yyyy"
+// @Success 200 {string} string "CSV with header id,user_id,service_name,price,currency,billing_period,start_date,end_date"
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /users/export.csv [get]
+func (h *HTTP) ExportCSV(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.export_csv"
+	ctx := r.Context()
+
+	q := r.URL.Query()
+
+	opts, field, err := parseCSVOptions(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field, err)
+		return
+	}
+	userID, serviceName, field, err := parseSummaryFilters(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field, err)
+		return
+	}
+	startDate, endDate, field, err := parseSummaryRange(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field+" (use "+models.DateInputs+")", err)
+		return
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.comma
+
+	// Headers go out with the first row so that a failing query can still
+	// be reported as a normal JSON error.
+	started := false
+	begin := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="subscriptions.csv"`)
+		w.WriteHeader(http.StatusOK)
+		return cw.Write(csvColumns)
+	}
+
+	rows := 0
+	err = h.DB.Stream(ctx, userID, serviceName, startDate, endDate, func(u models.UserInfo) error {
+		if err := begin(); err != nil {
+			return err
+		}
+		if err := cw.Write(csvRecord(u, opts)); err != nil {
+			return err
+		}
+		rows++
+		if rows%csvFlushEvery == 0 {
+			cw.Flush()
+			if f, ok := w.(http.Flusher); ok {
+				f.Flush()
+			}
+			return cw.Error()
+		}
+		return nil
+	})
+	if err == nil {
+		err = begin()
+	}
+	if err != nil {
+		if !started {
+			respond.RepoError(w, r, op, "failed to export", err)
+			return
+		}
+		// too late for a status code; the client sees a truncated file
+		reqctx.Logger(ctx).ErrorContext(ctx, "csv export aborted", slog.String("op", op), slog.Int("rows", rows), slog.Any("error", err))
+		return
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		reqctx.Logger(ctx).WarnContext(ctx, "write csv failed", slog.String("op", op), slog.Any("error", err))
+		return
+	}
+	reqctx.Logger(ctx).InfoContext(ctx, "response", slog.String("op", op), slog.Int("status", http.StatusOK), slog.Int("rows", rows))
+}
+
+// ImportCSV godoc
+// @Summary Import subscriptions from CSV
+// @Description Upserts records from a CSV file. Columns are matched by header name (case-insensitive, any order):
+// @Description user_id, service_name, price, start_date, end_date are required; currency and billing_period are optional; other columns are ignored.
+// @Description An empty end_date makes the subscription open-ended.
+// @Description Errors are reported by CSV line number (the header is line 1).
+// @Tags users
+// @Accept text/csv
+// @Produce json
+// @Param mode query string false "atomic (default) or best_effort"
+// @Param delimiter query string false "comma (default), semicolon, tab or pipe"
+// @Param date_format query string false "rfc3339 (default), yyyy-mm-dd or dd.mm.yyyy"
+// @Success 200 {object} response.BulkReport
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 422 {object} response.BulkReport "atomic import rolled back"
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /users/import.csv [post]
+func (h *HTTP) ImportCSV(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.import_csv"
+
+	mode, ok := parseBulkMode(r)
+	if !ok {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid mode (use atomic or best_effort)", nil)
+		return
+	}
+	opts, field, err := parseCSVOptions(r.URL.Query())
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field, err)
+		return
+	}
+
+	defer r.Body.Close()
+	items, err := readCSV(http.MaxBytesReader(w, r.Body, maxBulkBodyBytes), opts)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid CSV: "+err.Error(), err)
+		return
+	}
+
+	h.importItems(w, r, op, mode, items)
+}
+
+func parseCSVOptions(q url.Values) (csvOptions, string, error) {
+	comma, ok := csvDelimiters[strings.ToLower(q.Get("delimiter"))]
+	if !ok {
+		return csvOptions{}, "delimiter", errors.New("use comma, semicolon, tab or pipe")
+	}
+
+	df := strings.ToLower(q.Get("date_format"))
+	if df == "" {
+		df = "rfc3339"
+	}
+	layout, ok := csvDateFormats[df]
+	if !ok {
+		return csvOptions{}, "date_format", errors.New("use rfc3339, yyyy-mm-dd or dd.mm.yyyy")
+	}
+	return csvOptions{comma: comma, dateLayout: layout}, "", nil
+}
+
+func csvRecord(u models.UserInfo, opts csvOptions) []string {
+	var end string
+	if u.EndDate != nil {
+		end = u.EndDate.UTC().Format(opts.dateLayout)
+	}
+	return []string{
+		u.ID.String(),
+		u.UserID.String(),
+		u.ServiceName,
+		strconv.FormatInt(u.Price, 10),
+		u.Currency,
+		string(u.BillingPeriod),
+		u.StartDate.UTC().Format(opts.dateLayout),
+		end,
+	}
+}
+
+// readCSV maps rows to UserInfo by header name. A row that cannot be parsed
+// is returned with its error; a missing or broken header fails the file.
+func readCSV(body io.Reader, opts csvOptions) ([]bulkItem, error) {
+	cr := csv.NewReader(body)
+	cr.Comma = opts.comma
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("empty file")
+		}
+		return nil, err
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Excel BOM
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, dup := cols[name]; dup {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		cols[name] = i
+	}
+	for _, name := range csvRequired {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	var items []bulkItem
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			if len(items) == maxBulkItems {
+				return nil, errBulkTooLarge
+			}
+			items = append(items, bulkItem{line: perr.StartLine, err: fmt.Errorf("invalid CSV: %w", perr.Err)})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == maxBulkItems {
+			return nil, errBulkTooLarge
+		}
+
+		line, _ := cr.FieldPos(0)
+		it := bulkItem{line: line}
+		it.info, it.err = csvUserInfo(rec, cols, opts)
+		items = append(items, it)
+	}
+	return items, nil
+}
+
+func csvUserInfo(rec []string, cols map[string]int, opts csvOptions) (models.UserInfo, error) {
+	get := func(name string) string {
+		i, ok := cols[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	var (
+		u   models.UserInfo
+		err error
+	)
+	if u.UserID, err = uuid.Parse(get("user_id")); err != nil {
+		return u, fmt.Errorf("column user_id: %w", err)
+	}
+	u.ServiceName = get("service_name")
+	if u.Price, err = strconv.ParseInt(get("price"), 10, 64); err != nil {
+		return u, errors.New("column price: not an integer")
+	}
+	u.Currency = get("currency")
+	u.BillingPeriod = models.BillingPeriod(strings.ToLower(get("billing_period")))
+	if u.StartDate.Time, err = time.Parse(opts.dateLayout, get("start_date")); err != nil {
+		return u, fmt.Errorf("column start_date: %w", err)
+	}
+	// an empty end_date is an open-ended subscription
+	if s := get("end_date"); s != "" {
+		end, err := time.Parse(opts.dateLayout, s)
+		if err != nil {
+			return u, fmt.Errorf("column end_date: %w", err)
+		}
+		// a date without time ends with its day, as in models.ParseEndDate
+		if opts.dateLayout != time.RFC3339 {
+			end = end.AddDate(0, 0, 1).Add(-time.Microsecond)
+		}
+		u.EndDate = &models.EndDate{Time: end}
+	}
+	return u, nil
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportCSV_SemicolonRuDates(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id, openID, uid := uuid.New(), uuid.New(), uuid.New()
+	end := time.Date(2025, 12, 31, 0, 0, 0, 0, time.UTC)
+	rows := []models.UserInfo{{
+		ID: id, UserID: uid, ServiceName: "Яндекс Плюс", Price: 399, Currency: "RUB", BillingPeriod: models.BillingYearly,
+		StartDate: models.Date{Time: time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+		EndDate:   &models.EndDate{Time: end},
+	}, {
+		ID: openID, UserID: uid, ServiceName: "Яндекс Плюс", Price: 449, Currency: "RUB", BillingPeriod: models.BillingMonthly,
+		StartDate: models.Date{Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}}
+	m.On("Stream", mock.Anything, (*uuid.UUID)(nil),
+		mock.MatchedBy(func(p *string) bool { return p != nil && *p == "Яндекс Плюс" }),
+		(*time.Time)(nil), (*time.Time)(nil)).
+		Return(rows, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/users/export.csv?delimiter=semicolon&date_format=DD.MM.YYYY&service_name=%D0%AF%D0%BD%D0%B4%D0%B5%D0%BA%D1%81+%D0%9F%D0%BB%D1%8E%D1%81", nil)
+	w := httptest.NewRecorder()
+
+	h.ExportCSV(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	require.Equal(t,
+		"id;user_id;service_name;price;currency;billing_period;start_date;end_date\n"+
+			id.String()+";"+uid.String()+";Яндекс Плюс;399;RUB;yearly;01.07.2025;31.12.2025\n"+
+			openID.String()+";"+uid.String()+";Яндекс Плюс;449;RUB;monthly;01.01.2026;\n",
+		w.Body.String())
+	m.AssertExpectations(t)
+}
+
+func TestExportCSV_QueryFails(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.On("Stream", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return([]models.UserInfo{}, errors.New("connection refused")).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/export.csv", nil)
+	w := httptest.NewRecorder()
+
+	h.ExportCSV(w, req)
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+func TestExportCSV_BadParams(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	for _, q := range []string{"delimiter=x", "date_format=mm/dd/yy", "user_id=bad", "start_date=01.01.2025"} {
+		req := httptest.NewRequest(http.MethodGet, "/users/export.csv?"+q, nil)
+		w := httptest.NewRecorder()
+
+		h.ExportCSV(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	m.AssertNotCalled(t, "Stream", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImportCSV_HeaderMapped(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New().String()
+	body := "\ufeffPrice;Service_Name;Comment;End_Date;Start_Date;User_ID\n" +
+		"399;Яндекс Плюс;ok;31.12.2025;01.07.2025;" + uid + "\n" +
+		"abc;Кинопоиск;bad price;31.12.2025;01.07.2025;" + uid + "\n" +
+		"100;IVI;bad date;2025-12-31;01.07.2025;" + uid + "\n" +
+		"299;Okko;open-ended;;01.07.2025;" + uid + "\n"
+
+	m.On("BulkUpsert", mock.Anything, mock.MatchedBy(func(items []models.UserInfo) bool {
+		return len(items) == 2 &&
+			items[0].ServiceName == "Яндекс Плюс" && items[0].Price == 399 && items[0].Currency == "RUB" &&
+			items[0].BillingPeriod == models.BillingMonthly &&
+			items[0].StartDate.Equal(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)) &&
+			items[0].EndDate != nil && items[0].EndDate.Equal(time.Date(2025, 12, 31, 23, 59, 59, 999999000, time.UTC)) &&
+			items[1].ServiceName == "Okko" && items[1].EndDate == nil
+	}), false).
+		Return([]repo.BulkResult{{Created: true}, {Created: true}}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPost,
+		"/users/import.csv?mode=best_effort&delimiter=semicolon&date_format=dd.mm.yyyy", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.ImportCSV(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	out := decodeReport(t, w)
+	require.Equal(t, 2, out.Created)
+	require.Equal(t, 2, out.Rejected)
+	require.Equal(t, []response.BulkResult{
+		{Line: 2, Status: "created", ID: uuid.Nil.String()},
+		{Line: 3, Status: "rejected", Reason: "column price: not an integer"},
+		{Line: 4, Status: "rejected", Reason: out.Results[2].Reason},
+		{Line: 5, Status: "created", ID: uuid.Nil.String()},
+	}, out.Results)
+	require.Contains(t, out.Results[2].Reason, "column end_date")
+	m.AssertExpectations(t)
+}
+
+func TestImportCSV_MissingColumn(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	body := "user_id,service_name,price,start_date\n" + uuid.New().String() + ",A,1,2025-01-01T00:00:00Z\n"
+	req := httptest.NewRequest(http.MethodPost, "/users/import.csv", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.ImportCSV(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Contains(t, w.Body.String(), `missing column \"end_date\"`)
+	m.AssertNotCalled(t, "BulkUpsert", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImportCSV_Currency(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+	h.DefaultCurrency = "EUR"
+
+	uid := uuid.New().String()
+	body := "user_id,service_name,price,currency,start_date,end_date\n" +
+		uid + ",A,1,usd,2025-01-01T00:00:00Z,2025-02-01T00:00:00Z\n" +
+		uid + ",B,1,,2025-01-01T00:00:00Z,2025-02-01T00:00:00Z\n" +
+		uid + ",C,1,XYZ,2025-01-01T00:00:00Z,2025-02-01T00:00:00Z\n"
+
+	m.On("BulkUpsert", mock.Anything, mock.MatchedBy(func(items []models.UserInfo) bool {
+		return len(items) == 2 && items[0].Currency == "USD" && items[1].Currency == "EUR"
+	}), false).
+		Return([]repo.BulkResult{{Created: true}, {Created: true}}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/import.csv?mode=best_effort", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.ImportCSV(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	out := decodeReport(t, w)
+	require.Equal(t, 2, out.Created)
+	require.Equal(t, []response.FieldError{{Field: "currency", Code: "invalid"}}, out.Results[2].Errors)
+	m.AssertExpectations(t)
+}
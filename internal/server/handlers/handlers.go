@@ -2,11 +2,17 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
-	"time"
+	"net/url"
+	"strconv"
+	"strings"
+	"user-aggregation/internal/auth"
+	"user-aggregation/internal/lib/validation"
 	"user-aggregation/internal/models"
 	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/money"
 	"user-aggregation/internal/repo"
 	"user-aggregation/internal/transport/http/respond"
 
@@ -14,25 +20,49 @@ import (
 	"github.com/gorilla/mux"
 )
 
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
 type HTTP struct {
 	Logger *slog.Logger
 	DB     repo.Repo
+	// DefaultCurrency is stored for records that do not name a currency.
+	DefaultCurrency string
 }
 
 func New(logger *slog.Logger, db repo.Repo) *HTTP {
-	return &HTTP{Logger: logger, DB: db}
+	return &HTTP{Logger: logger, DB: db, DefaultCurrency: money.DefaultCurrency}
+}
+
+// withDefaults normalizes the service name and currency of u and fills in
+// the default currency and billing period.
+func (h *HTTP) withDefaults(u *models.UserInfo) {
+	u.ServiceName = strings.TrimSpace(u.ServiceName)
+	u.Currency = money.Normalize(u.Currency)
+	if u.Currency == "" {
+		u.Currency = h.DefaultCurrency
+	}
+	if u.BillingPeriod == "" {
+		u.BillingPeriod = models.BillingMonthly
+	}
 }
 
 // LoadNewInfo godoc
 // @Summary Create new user info
-// @Description Create a new user subscription information record
+// @Description Create a new user subscription information record. A duplicate (user_id, service_name, start_date) is rejected with 409.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param userInfo body models.UserInfo true "User subscription information"
 // @Success 201 {object} models.UserInfo
 // @Failure 400 {object} response.ErrorPayload
+// @Failure 409 {object} response.ErrorPayload
+// @Failure 422 {object} response.ValidationError
 // @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
 // @Router /users [post]
 func (h *HTTP) LoadNewInfo(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.load_new_info"
@@ -42,16 +72,82 @@ func (h *HTTP) LoadNewInfo(w http.ResponseWriter, r *http.Request) {
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(&userInfo); err != nil {
-		respond.Error(w, h.Logger, op, http.StatusBadRequest, "invalid JSON body", err)
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid JSON body", err)
+		return
+	}
+
+	h.withDefaults(&userInfo)
+	if errs := validation.UserInfo(&userInfo); len(errs) > 0 {
+		respond.Invalid(w, r, op, errs)
 		return
 	}
 
 	if err := h.DB.Insert(ctx, &userInfo); err != nil {
-		respond.Error(w, h.Logger, op, http.StatusInternalServerError, "failed to save record", err)
+		respond.RepoError(w, r, op, "failed to save record", err)
+		return
+	}
+
+	respond.Writer(w, r, op, http.StatusCreated, userInfo)
+}
+
+// UpsertSubscription godoc
+// @Summary Create or overwrite user subscription
+// @Description Idempotent upsert by (user_id, service_name, start_date): creates the record or overwrites its price, end date, currency and billing period
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Param userInfo body models.UserInfo true "Subscription (user_id may be omitted, it is taken from the path)"
+// @Success 200 {object} models.UserInfo "Existing record updated"
+// @Success 201 {object} models.UserInfo "New record created"
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 422 {object} response.ValidationError
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /users/{id}/subscriptions [put]
+func (h *HTTP) UpsertSubscription(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.upsert_subscription"
+	ctx := r.Context()
+
+	id, err := parseUUIDVar(r, "id")
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid user_id", err)
+		return
+	}
+	defer r.Body.Close()
+
+	var userInfo models.UserInfo
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&userInfo); err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid JSON body", err)
+		return
+	}
+
+	if userInfo.UserID != uuid.Nil && userInfo.UserID != id {
+		respond.Error(w, r, op, http.StatusBadRequest, "user_id in body does not match path", nil)
+		return
+	}
+	userInfo.UserID = id
+
+	h.withDefaults(&userInfo)
+	if errs := validation.UserInfo(&userInfo); len(errs) > 0 {
+		respond.Invalid(w, r, op, errs)
 		return
 	}
 
-	respond.Writer(w, h.Logger, op, http.StatusCreated, userInfo)
+	created, err := h.DB.Upsert(ctx, &userInfo)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to save record", err)
+		return
+	}
+
+	code := http.StatusOK
+	if created {
+		code = http.StatusCreated
+	}
+	respond.Writer(w, r, op, code, userInfo)
 }
 
 // GetInfo godoc
@@ -64,6 +160,8 @@ func (h *HTTP) LoadNewInfo(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {object} response.ErrorPayload
 // @Failure 404 {object} response.ErrorPayload
 // @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
 // @Router /users/{id} [get]
 func (h *HTTP) GetInfo(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.get_info"
@@ -71,29 +169,34 @@ func (h *HTTP) GetInfo(w http.ResponseWriter, r *http.Request) {
 
 	id, err := parseUUIDVar(r, "id")
 	if err != nil {
-		respond.Error(w, h.Logger, op, http.StatusBadRequest, "invalid user_id", err)
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid user_id", err)
 		return
 	}
 
 	users, err := h.DB.GetByUserID(ctx, id)
 	if err != nil {
-		respond.Error(w, h.Logger, op, http.StatusInternalServerError, "failed to fetch records", err)
+		respond.RepoError(w, r, op, "failed to fetch records", err)
 		return
 	}
 
-	respond.Writer(w, h.Logger, op, http.StatusOK, users)
+	respond.Writer(w, r, op, http.StatusOK, users)
 }
 
 // DeleteInfo godoc
 // @Summary Delete user info by ID
-// @Description Delete all subscription information for a specific user
+// @Description Move all subscription records of a user to the trash (see /users/trash and /users/{id}/restore).
+// @Description With permanent=true the records, including already trashed ones, are removed for good.
 // @Tags users
 // @Produce json
 // @Param id path string true "User ID (UUID)"
+// @Param permanent query bool false "Delete permanently instead of moving to the trash (requires admin)"
 // @Success 200 {integer} int64 "Number of deleted records, but not in json. this will need to be done"
 // @Failure 400 {object} response.ErrorPayload
+// @Failure 403 {object} response.ErrorPayload "permanent without the admin scope"
 // @Failure 404 {object} response.ErrorPayload
 // @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
 // @Router /users/{id} [delete]
 func (h *HTTP) DeleteInfo(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.delete_info"
@@ -101,56 +204,100 @@ func (h *HTTP) DeleteInfo(w http.ResponseWriter, r *http.Request) {
 
 	id, err := parseUUIDVar(r, "id")
 	if err != nil {
-		respond.Error(w, h.Logger, op, http.StatusBadRequest, "invalid user_id", err)
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid user_id", err)
 		return
 	}
 
-	n, err := h.DB.DeleteByUserID(ctx, id)
-	if err != nil {
-		if err == repo.ErrNotFound {
-			respond.Error(w, h.Logger, op, http.StatusNotFound, "nothing to delete", err)
+	permanent := false
+	if s := r.URL.Query().Get("permanent"); s != "" {
+		if permanent, err = strconv.ParseBool(s); err != nil {
+			respond.Error(w, r, op, http.StatusBadRequest, "invalid permanent (use true or false)", err)
 			return
 		}
-		respond.Error(w, h.Logger, op, http.StatusInternalServerError, "failed to delete", err)
+	}
+
+	// checked here rather than by routing, so that it sees exactly the query
+	// that was parsed; without authentication there is no principal
+	if p, ok := auth.FromContext(ctx); permanent && ok && !p.Has(auth.ScopeAdmin) {
+		respond.Error(w, r, op, http.StatusForbidden, "missing scope "+string(auth.ScopeAdmin), nil)
 		return
 	}
 
-	respond.Writer(w, h.Logger, op, http.StatusOK, map[string]any{"deleted": n})
+	var n int64
+	if permanent {
+		n, err = h.DB.PurgeByUserID(ctx, id)
+	} else {
+		n, err = h.DB.DeleteByUserID(ctx, id)
+	}
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to delete", err)
+		return
+	}
+
+	respond.Writer(w, r, op, http.StatusOK, map[string]any{"deleted": n})
 }
 
 // GetAllInfo godoc
 // @Summary List of all users
-// @Description Get all user subscription information records
+// @Description Get subscription records page by page (keyset pagination)
 // @Tags users
 // @Produce json
-// @Success 200 {array} models.UserInfo
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Opaque cursor from next_cursor of the previous page"
+// @Param sort query string false "Sort field: price, start_date (default), end_date, service_name"
+// @Param order query string false "Sort direction: asc (default) or desc"
+// @Param service_name query string false "Filter by service name prefix"
+// @Param min_price query int false "Filter by minimum price (inclusive)"
+// @Param max_price query int false "Filter by maximum price (inclusive)"
+// @Param active_at query string false "Only subscriptions active at this moment (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its first moment)"
+// @Success 200 {object} response.UserInfoPage
+// @Failure 400 {object} response.ErrorPayload
 // @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
 // @Router /users [get]
 func (h *HTTP) GetAllInfo(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.get_all_info"
 	ctx := r.Context()
 
-	listInfo, err := h.DB.List(ctx)
+	params, msg, err := parseListParams(r.URL.Query())
+	if msg != "" {
+		respond.Error(w, r, op, http.StatusBadRequest, msg, err)
+		return
+	}
+
+	page, err := h.DB.ListPage(ctx, params)
 	if err != nil {
-		respond.Error(w, h.Logger, op, http.StatusInternalServerError, "failed to list", err)
+		respond.RepoError(w, r, op, "failed to list", err)
 		return
 	}
 
-	respond.Writer(w, h.Logger, op, http.StatusOK, listInfo)
+	out := response.UserInfoPage{
+		Items:      page.Items,
+		NextCursor: page.NextCursor,
+	}
+	if out.Items == nil {
+		out.Items = []models.UserInfo{}
+	}
+	respond.Writer(w, r, op, http.StatusOK, out)
 }
 
 // PatchUserInfo godoc
 // @Summary Update user info
 // @Description Partially update user subscription information (price and/or end date)
+// @Description A new price applies from effective_from (today by default); earlier periods keep their price.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID (UUID)"
-// @Param update body models.UpdateUserInfo true "Update fields (price and/or end_date)"
+// @Param update body models.UpdateUserInfo true "Update fields (price and/or end_date; end_date null makes the subscription open-ended; effective_from dates the price change)"
 // @Success 200 {integer} int64 "Number of updated records, but not in json, this will need to be done"
 // @Failure 400 {object} response.ErrorPayload
 // @Failure 404 {object} response.ErrorPayload
+// @Failure 422 {object} response.ValidationError
 // @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
 // @Router /users/{id} [patch]
 func (h *HTTP) PatchUserInfo(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.patch_user_info"
@@ -158,7 +305,7 @@ func (h *HTTP) PatchUserInfo(w http.ResponseWriter, r *http.Request) {
 
 	id, err := parseUUIDVar(r, "id")
 	if err != nil {
-		respond.Error(w, h.Logger, op, http.StatusBadRequest, "invalid user_id", err)
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid user_id", err)
 		return
 	}
 	defer r.Body.Close()
@@ -167,45 +314,56 @@ func (h *HTTP) PatchUserInfo(w http.ResponseWriter, r *http.Request) {
 	dec := json.NewDecoder(r.Body)
 	dec.DisallowUnknownFields()
 	if err := dec.Decode(&patch); err != nil {
-		respond.Error(w, h.Logger, op, http.StatusBadRequest, "invalid JSON body", err)
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid JSON body", err)
+		return
+	}
+
+	if patch.Price == nil && !patch.EndDate.Set {
+		respond.Error(w, r, op, http.StatusBadRequest, "no fields to update", nil)
 		return
 	}
 
-	if patch.Price == nil && patch.EndDate == nil {
-		respond.Error(w, h.Logger, op, http.StatusBadRequest, "no fields to update", nil)
+	if errs := validation.Update(&patch); len(errs) > 0 {
+		respond.Invalid(w, r, op, errs)
 		return
 	}
 
-	if patch.EndDate != nil {
-		t := patch.EndDate.UTC().Truncate(time.Second)
-		patch.EndDate = &t
+	if patch.EndDate.Time != nil {
+		t := patch.EndDate.Time.UTC()
+		patch.EndDate.Time = &t
+	}
+	if patch.EffectiveFrom != nil {
+		t := patch.EffectiveFrom.UTC()
+		patch.EffectiveFrom = &t
 	}
 
-	ui, err := h.DB.UpdateUserInfo(ctx, id, patch.Price, patch.EndDate)
+	ui, err := h.DB.UpdateUserInfo(ctx, id, patch.Price, patch.EndDate, patch.EffectiveFrom)
 	if err != nil {
-		if err == repo.ErrNotFound {
-			respond.Error(w, h.Logger, op, http.StatusNotFound, "nothing to update", err)
-			return
-		}
-		respond.Error(w, h.Logger, op, http.StatusInternalServerError, "failed to update user", err)
+		respond.RepoError(w, r, op, "failed to update user", err)
 		return
 	}
 
-	respond.Writer(w, h.Logger, op, http.StatusOK, ui)
+	respond.Writer(w, r, op, http.StatusOK, ui)
 }
 
 // GetFilterSummary godoc
 // @Summary Get filtered summary
-// @Description Get total cost summary with optional filters
+// @Description Get total cost of the charges made in [start_date, end_date] with optional filters. A subscription is charged on its start date and then every billing period, at the price in effect on the charge date.
+// @Description Totals are split by currency; with currency set they are also converted and summed.
 // @Tags summary
 // @Produce json
 // @Param service_name query string false "Filter by service name"
 // @Param user_id query string false "Filter by user ID (UUID)"
-// @Param start_date query string false "Filter by start date (RFC3339 format)"
-// @Param end_date query string false "Filter by end date (RFC3339 format)"
+// @Param start_date query string false "Filter by start date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)"
+// @Param end_date query string false "Filter by end date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its last moment)"
+// @Param currency query string false "Convert the totals into this currency (ISO 4217)"
+// @Param rate_date query string false "Date of the exchange rates, YYYY-MM-DD (default today, UTC)"
 // @Success 200 {object} response.Summary
 // @Failure 400 {object} response.ErrorPayload
+// @Failure 422 {object} response.ErrorPayload "no exchange rate for a currency"
 // @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
 // @Router /summary [get]
 func (h *HTTP) GetFilterSummary(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.get_filter_summary"
@@ -213,49 +371,112 @@ func (h *HTTP) GetFilterSummary(w http.ResponseWriter, r *http.Request) {
 
 	q := r.URL.Query()
 
-	var serviceName *string
-	if s := q.Get("service_name"); s != "" {
-		serviceName = &s
+	userID, serviceName, field, err := parseSummaryFilters(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field, err)
+		return
+	}
+	startDate, endDate, field, err := parseSummaryRange(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field+" (use "+models.DateInputs+")", err)
+		return
 	}
 
-	var userID *uuid.UUID
-	if uid := q.Get("user_id"); uid != "" {
-		if parsed, err := uuid.Parse(uid); err == nil {
-			userID = &parsed
-		} else {
-			respond.Error(w, h.Logger, op, http.StatusBadRequest, "invalid user_id", err)
-			return
-		}
+	target, rateDate, field, err := parseConversion(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field, err)
+		return
 	}
 
-	var startDate, endDate *time.Time
-	if s := q.Get("start_date"); s != "" {
-		if t, err := time.Parse(time.RFC3339, s); err == nil {
-			startDate = &t
-		} else {
-			respond.Error(w, h.Logger, op, http.StatusBadRequest, "invalid start_date (use RFC3339)", err)
-			return
-		}
+	totals, err := h.DB.FilterSum(ctx, userID, serviceName, startDate, endDate)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to calculate summary", err)
+		return
 	}
-	if s := q.Get("end_date"); s != "" {
-		if t, err := time.Parse(time.RFC3339, s); err == nil {
-			endDate = &t
-		} else {
-			respond.Error(w, h.Logger, op, http.StatusBadRequest, "invalid end_date (use RFC3339)", err)
-			return
-		}
+
+	out := response.Summary{
+		Totals: make([]response.CurrencyTotal, 0, len(totals)),
+	}
+	for _, t := range totals {
+		out.Totals = append(out.Totals, response.CurrencyTotal{Currency: t.Currency, TotalCost: t.Total})
 	}
 
-	total, err := h.DB.FilterSum(ctx, userID, serviceName, startDate, endDate)
+	conv, err := h.converter(ctx, target)
 	if err != nil {
-		respond.Error(w, h.Logger, op, http.StatusInternalServerError, "failed to calculate summary", err)
+		respond.RepoError(w, r, op, "failed to load exchange rates", err)
 		return
 	}
+	if conv != nil {
+		var sum int64
+		for _, t := range totals {
+			v, err := conv.Convert(t.Total, t.Currency, rateDate)
+			if err != nil {
+				conversionError(w, r, op, err)
+				return
+			}
+			sum += v
+		}
+		out.TotalCost = &sum
+		out.Currency = conv.Target()
+		out.Rates = conv.Used()
+	}
+	respond.Writer(w, r, op, http.StatusOK, out)
+}
 
-	out := response.Summary{
-		TotalCost: total,
+// parseListParams reads the pagination, sorting and filter query parameters
+// shared by the listing endpoints. On failure it returns a non-empty client
+// message.
+func parseListParams(q url.Values) (repo.ListParams, string, error) {
+	params := repo.ListParams{
+		Limit:         defaultPageLimit,
+		Cursor:        q.Get("cursor"),
+		Sort:          repo.SortByStartDate,
+		ServicePrefix: q.Get("service_name"),
+	}
+
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageLimit {
+			return params, fmt.Sprintf("invalid limit (use 1..%d)", maxPageLimit), err
+		}
+		params.Limit = n
+	}
+	if s := q.Get("sort"); s != "" {
+		params.Sort = repo.SortField(s)
+		if !params.Sort.Valid() {
+			return params, "invalid sort (use price, start_date, end_date or service_name)", nil
+		}
+	}
+	switch q.Get("order") {
+	case "", "asc":
+	case "desc":
+		params.Desc = true
+	default:
+		return params, "invalid order (use asc or desc)", nil
+	}
+	if s := q.Get("min_price"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return params, "invalid min_price", err
+		}
+		params.MinPrice = &n
+	}
+	if s := q.Get("max_price"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return params, "invalid max_price", err
+		}
+		params.MaxPrice = &n
 	}
-	respond.Writer(w, h.Logger, op, http.StatusOK, out)
+	if s := q.Get("active_at"); s != "" {
+		t, err := models.ParseDate(s)
+		if err != nil {
+			return params, "invalid active_at (use " + models.DateInputs + ")", err
+		}
+		params.ActiveAt = &t
+	}
+
+	return params, "", nil
 }
 
 func parseUUIDVar(r *http.Request, key string) (uuid.UUID, error) {
@@ -3,9 +3,12 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 	"user-aggregation/internal/models"
@@ -28,14 +31,18 @@ func toJSON(v any) *bytes.Reader {
 	return bytes.NewReader(b)
 }
 
+func tomorrow() *models.EndDate {
+	return &models.EndDate{Time: time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)}
+}
+
 func TestLoadNewInfo_OK(t *testing.T) {
 	m := new(mocks.RepoMock)
 	h := New(slog.Default(), m)
 
 	u := models.UserInfo{
 		UserID: uuid.New(), ServiceName: "Netflix", Price: 999,
-		StartDate: time.Now().UTC().Truncate(time.Second),
-		EndDate:   time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second),
+		StartDate: models.Date{Time: time.Now().UTC().Truncate(time.Second)},
+		EndDate:   tomorrow(),
 	}
 
 	m.On("Insert", mock.Anything, mock.AnythingOfType("*models.UserInfo")).
@@ -51,6 +58,49 @@ func TestLoadNewInfo_OK(t *testing.T) {
 	m.AssertExpectations(t)
 }
 
+func TestLoadNewInfo_MonthDates(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.On("Insert", mock.Anything, mock.MatchedBy(func(u *models.UserInfo) bool {
+		return u.StartDate.Equal(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)) &&
+			u.EndDate != nil && u.EndDate.Equal(time.Date(2025, 9, 30, 23, 59, 59, 999999000, time.UTC))
+	})).
+		Return(nil).
+		Once()
+
+	body := `{"user_id":"` + uuid.NewString() + `","service_name":"Netflix","price":999,"start_date":"07-2025","end_date":"2025-09"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.LoadNewInfo(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.Contains(t, w.Body.String(), `"start_date":"2025-07-01T00:00:00Z"`)
+	m.AssertExpectations(t)
+}
+
+func TestLoadNewInfo_TrimsServiceName(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.On("Insert", mock.Anything, mock.MatchedBy(func(u *models.UserInfo) bool {
+		return u.ServiceName == "Netflix"
+	})).
+		Return(nil).
+		Once()
+
+	body := `{"user_id":"` + uuid.NewString() + `","service_name":"  Netflix\t","price":999,"start_date":"2025-07-01T00:00:00Z"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.LoadNewInfo(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.Contains(t, w.Body.String(), `"service_name":"Netflix"`)
+	m.AssertExpectations(t)
+}
+
 func TestLoadNewInfo_BadJSON(t *testing.T) {
 	m := new(mocks.RepoMock)
 	h := New(slog.Default(), m)
@@ -149,8 +199,8 @@ func TestGetAllInfo_OK(t *testing.T) {
 	m := new(mocks.RepoMock)
 	h := New(slog.Default(), m)
 
-	m.On("List", mock.Anything).
-		Return([]models.UserInfo{{ServiceName: "X"}}, nil).
+	m.On("ListPage", mock.Anything, repo.ListParams{Limit: 50, Sort: repo.SortByStartDate}).
+		Return(repo.Page{Items: []models.UserInfo{{ServiceName: "X"}}, NextCursor: "next"}, nil).
 		Once()
 
 	req := httptest.NewRequest(http.MethodGet, "/users", nil)
@@ -158,6 +208,94 @@ func TestGetAllInfo_OK(t *testing.T) {
 
 	h.GetAllInfo(w, req)
 	require.Equal(t, http.StatusOK, w.Code)
+
+	var out response.UserInfoPage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Len(t, out.Items, 1)
+	require.Equal(t, "next", out.NextCursor)
+	m.AssertExpectations(t)
+}
+
+func TestGetAllInfo_Params(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	at := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	m.On("ListPage", mock.Anything, mock.MatchedBy(func(p repo.ListParams) bool {
+		return p.Limit == 10 && p.Cursor == "abc" &&
+			p.Sort == repo.SortByPrice && p.Desc &&
+			p.ServicePrefix == "Net" &&
+			p.MinPrice != nil && *p.MinPrice == 100 &&
+			p.MaxPrice != nil && *p.MaxPrice == 900 &&
+			p.ActiveAt != nil && p.ActiveAt.Equal(at)
+	})).
+		Return(repo.Page{}, nil).
+		Once()
+
+	url := "/users?limit=10&cursor=abc&sort=price&order=desc&service_name=Net" +
+		"&min_price=100&max_price=900&active_at=" + at.Format(time.RFC3339)
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	w := httptest.NewRecorder()
+
+	h.GetAllInfo(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"items":[]}`, w.Body.String())
+	m.AssertExpectations(t)
+}
+
+func TestGetAllInfo_ActiveAtForms(t *testing.T) {
+	for _, v := range []string{"2025-03-01T03:00:00%2B03:00", "2025-03-01", "2025-03", "03-2025"} {
+		m := new(mocks.RepoMock)
+		h := New(slog.Default(), m)
+
+		at := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		m.On("ListPage", mock.Anything, mock.MatchedBy(func(p repo.ListParams) bool {
+			return p.ActiveAt != nil && p.ActiveAt.Equal(at)
+		})).
+			Return(repo.Page{}, nil).
+			Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/users?active_at="+v, nil)
+		w := httptest.NewRecorder()
+
+		h.GetAllInfo(w, req)
+		require.Equal(t, http.StatusOK, w.Code, v)
+		m.AssertExpectations(t)
+	}
+}
+
+func TestGetAllInfo_BadParams(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	for _, q := range []string{
+		"limit=0", "limit=501", "limit=x",
+		"sort=user_id", "order=up",
+		"min_price=cheap", "max_price=1.5",
+		"active_at=yesterday",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/users?"+q, nil)
+		w := httptest.NewRecorder()
+
+		h.GetAllInfo(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	m.AssertNotCalled(t, "ListPage", mock.Anything, mock.Anything)
+}
+
+func TestGetAllInfo_BadCursor(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.On("ListPage", mock.Anything, mock.Anything).
+		Return(repo.Page{}, errors.Join(repo.ErrBadInput, errors.New("malformed cursor"))).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/users?cursor=garbage", nil)
+	w := httptest.NewRecorder()
+
+	h.GetAllInfo(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
 	m.AssertExpectations(t)
 }
 
@@ -171,8 +309,9 @@ func TestPatchUserInfo_EndOnly_OK(t *testing.T) {
 	m.
 		On("UpdateUserInfo", mock.Anything, uid,
 			(*int64)(nil), // важно: явное nil нужного типа
-			mock.MatchedBy(func(t *time.Time) bool {
-				if t == nil {
+			mock.MatchedBy(func(e models.NullableTime) bool {
+				t := e.Time
+				if !e.Set || t == nil {
 					return false
 				}
 				// Нормализуем секунды, чтобы не споткнуться о наносекунды и зону
@@ -180,11 +319,12 @@ func TestPatchUserInfo_EndOnly_OK(t *testing.T) {
 				want := end.UTC().Truncate(time.Second)
 				return got.Equal(want)
 			}),
+			(*time.Time)(nil),
 		).
 		Return(int64(1), nil).
 		Once()
 
-	body := models.UpdateUserInfo{EndDate: &end}
+	body := models.UpdateUserInfo{EndDate: models.NullableTime{Set: true, Time: &end}}
 	req := httptest.NewRequest(http.MethodPatch, "/users/"+uid.String(), toJSON(body))
 	req = withVars(req, "id", uid.String())
 	w := httptest.NewRecorder()
@@ -205,6 +345,7 @@ func TestPatchUserInfo_PriceOnly_OK(t *testing.T) {
 	m.
 		On("UpdateUserInfo", mock.Anything, uid,
 			mock.MatchedBy(func(p *int64) bool { return p != nil && *p == price }),
+			models.NullableTime{},
 			(*time.Time)(nil),
 		).
 		Return(int64(1), nil). // не 2, если хендлер ждёт “ровно 1 обновлена”
@@ -232,19 +373,21 @@ func TestPatchUserInfo_BothFields_OK(t *testing.T) {
 	m.
 		On("UpdateUserInfo", mock.Anything, uid,
 			mock.MatchedBy(func(p *int64) bool { return p != nil && *p == price }),
-			mock.MatchedBy(func(t *time.Time) bool {
-				if t == nil {
+			mock.MatchedBy(func(e models.NullableTime) bool {
+				t := e.Time
+				if !e.Set || t == nil {
 					return false
 				}
 				got := t.UTC().Truncate(time.Second)
 				want := end.UTC().Truncate(time.Second)
 				return got.Equal(want)
 			}),
+			(*time.Time)(nil),
 		).
 		Return(int64(1), nil).
 		Once()
 
-	body := models.UpdateUserInfo{Price: &price, EndDate: &end}
+	body := models.UpdateUserInfo{Price: &price, EndDate: models.NullableTime{Set: true, Time: &end}}
 	req := httptest.NewRequest(http.MethodPatch, "/users/"+uid.String(), toJSON(body))
 	req = withVars(req, "id", uid.String())
 	w := httptest.NewRecorder()
@@ -255,6 +398,26 @@ func TestPatchUserInfo_BothFields_OK(t *testing.T) {
 	m.AssertExpectations(t)
 }
 
+func TestPatchUserInfo_ClearEnd_OK(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	m.
+		On("UpdateUserInfo", mock.Anything, uid, (*int64)(nil), models.NullableTime{Set: true}, (*time.Time)(nil)).
+		Return(int64(2), nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/"+uid.String(), bytes.NewReader([]byte(`{"end_date": null}`)))
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.PatchUserInfo(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	m.AssertExpectations(t)
+}
+
 func TestPatchUserInfo_NoFields_BadRequest(t *testing.T) {
 	m := new(mocks.RepoMock)
 	h := New(slog.Default(), m)
@@ -270,6 +433,30 @@ func TestPatchUserInfo_NoFields_BadRequest(t *testing.T) {
 	m.AssertNotCalled(t, "UpdateUserInfo", mock.Anything, mock.Anything, mock.Anything)
 }
 
+func TestPatchUserInfo_EndBeforeStart(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	end := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.On("UpdateUserInfo", mock.Anything, uid, (*int64)(nil), mock.Anything, (*time.Time)(nil)).
+		Return(int64(0), fmt.Errorf("repo: patch user_info: %w", repo.ErrEndBeforeStart)).
+		Once()
+
+	body := models.UpdateUserInfo{EndDate: models.NullableTime{Set: true, Time: &end}}
+	req := httptest.NewRequest(http.MethodPatch, "/users/"+uid.String(), toJSON(body))
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.PatchUserInfo(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var out response.ValidationError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, []response.FieldError{{Field: "end_date", Code: "before_start"}}, out.Errors)
+	m.AssertExpectations(t)
+}
+
 func TestPatchUserInfo_BadJSON(t *testing.T) {
 	m := new(mocks.RepoMock)
 	h := New(slog.Default(), m)
@@ -307,7 +494,7 @@ func TestGetFilterSummary_OK(t *testing.T) {
 	svc := "Netflix"
 	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
 	end := start.Add(72 * time.Hour)
-	total := int64(1500)
+	totals := []repo.CurrencyTotal{{Currency: "RUB", Total: 1500}, {Currency: "USD", Total: 10}}
 
 	m.
 		On("FilterSum", mock.Anything,
@@ -316,7 +503,7 @@ func TestGetFilterSummary_OK(t *testing.T) {
 			mock.MatchedBy(func(p *time.Time) bool { return p != nil && p.Equal(start) }),
 			mock.MatchedBy(func(p *time.Time) bool { return p != nil && p.Equal(end) }),
 		).
-		Return(total, nil).
+		Return(totals, nil).
 		Once()
 
 	url := "/summary?user_id=" + uid.String() +
@@ -333,7 +520,60 @@ func TestGetFilterSummary_OK(t *testing.T) {
 	// при желании — проверим JSON
 	var out response.Summary
 	_ = json.Unmarshal(w.Body.Bytes(), &out)
-	require.Equal(t, total, out.TotalCost)
+	require.Nil(t, out.TotalCost)
+	require.Equal(t, []response.CurrencyTotal{{Currency: "RUB", TotalCost: 1500}, {Currency: "USD", TotalCost: 10}}, out.Totals)
+	m.AssertExpectations(t)
+}
+
+func TestGetFilterSummary_MonthRange(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.
+		On("FilterSum", mock.Anything, (*uuid.UUID)(nil), (*string)(nil),
+			mock.MatchedBy(func(p *time.Time) bool { return p != nil && p.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) }),
+			mock.MatchedBy(func(p *time.Time) bool {
+				return p != nil && p.Equal(time.Date(2025, 3, 31, 23, 59, 59, 999999000, time.UTC))
+			}),
+		).
+		Return([]repo.CurrencyTotal{}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/summary?start_date=01-2025&end_date=2025-03", nil)
+	w := httptest.NewRecorder()
+
+	h.GetFilterSummary(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	m.AssertExpectations(t)
+}
+
+func TestGetFilterSummary_Converted(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.On("FilterSum", mock.Anything, (*uuid.UUID)(nil), (*string)(nil), (*time.Time)(nil), (*time.Time)(nil)).
+		Return([]repo.CurrencyTotal{{Currency: "EUR", Total: 3}, {Currency: "RUB", Total: 1500}}, nil).
+		Once()
+	// only RUB/EUR is stored, so EUR -> RUB goes through the inverse
+	m.On("ExchangeRates", mock.Anything).
+		Return([]models.ExchangeRate{
+			{Base: "RUB", Quote: "EUR", Rate: "0.01", EffectiveFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/summary?currency=RUB&rate_date=2025-06-01", nil)
+	w := httptest.NewRecorder()
+
+	h.GetFilterSummary(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var out response.Summary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.NotNil(t, out.TotalCost)
+	require.Equal(t, int64(1800), *out.TotalCost)
+	require.Equal(t, "RUB", out.Currency)
+	require.Len(t, out.Rates, 1)
+	require.Equal(t, "EUR", out.Rates[0].Quote)
 	m.AssertExpectations(t)
 }
 
@@ -365,3 +605,149 @@ func TestGetFilterSummary_BadDates(t *testing.T) {
 
 	m.AssertNotCalled(t, "FilterSum", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 }
+
+func TestLoadNewInfo_Invalid(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, -1, 0)
+	u := models.UserInfo{
+		UserID: uuid.New(), ServiceName: "Netflix", Price: 999,
+		StartDate: models.Date{Time: start},
+		EndDate:   &models.EndDate{Time: end},
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/users", toJSON(u))
+	w := httptest.NewRecorder()
+
+	h.LoadNewInfo(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var out response.ValidationError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, []response.FieldError{{Field: "end_date", Code: "before_start"}}, out.Errors)
+	m.AssertNotCalled(t, "Insert", mock.Anything, mock.Anything)
+}
+
+func TestPatchUserInfo_NegativePrice(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	req := httptest.NewRequest(http.MethodPatch, "/users/"+uid.String(), bytes.NewReader([]byte(`{"price": -10}`)))
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.PatchUserInfo(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.JSONEq(t, `{"error":"validation failed","op":"handlers.patch_user_info","status":422,
+		"errors":[{"field":"price","code":"negative"}]}`, w.Body.String())
+	m.AssertNotCalled(t, "UpdateUserInfo", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLoadNewInfo_Conflict(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	u := models.UserInfo{
+		UserID: uuid.New(), ServiceName: "Netflix", Price: 999,
+		StartDate: models.Date{Time: time.Now().UTC().Truncate(time.Second)},
+		EndDate:   tomorrow(),
+	}
+
+	m.On("Insert", mock.Anything, mock.AnythingOfType("*models.UserInfo")).
+		Return(errors.Join(repo.ErrConflict, errors.New("subscription already exists"))).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/users", toJSON(u))
+	w := httptest.NewRecorder()
+
+	h.LoadNewInfo(w, req)
+
+	require.Equal(t, http.StatusConflict, w.Code)
+	m.AssertExpectations(t)
+}
+
+func TestUpsertSubscription(t *testing.T) {
+	for _, tc := range []struct {
+		created bool
+		code    int
+	}{
+		{created: true, code: http.StatusCreated},
+		{created: false, code: http.StatusOK},
+	} {
+		m := new(mocks.RepoMock)
+		h := New(slog.Default(), m)
+
+		uid := uuid.New()
+		start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		body := map[string]any{
+			"service_name": "Netflix",
+			"price":        999,
+			"start_date":   start,
+			"end_date":     start.AddDate(1, 0, 0),
+		}
+
+		m.On("Upsert", mock.Anything, mock.MatchedBy(func(u *models.UserInfo) bool {
+			return u.UserID == uid && u.ServiceName == "Netflix" && u.Price == 999
+		})).
+			Return(tc.created, nil).
+			Once()
+
+		req := httptest.NewRequest(http.MethodPut, "/users/"+uid.String()+"/subscriptions", toJSON(body))
+		req = withVars(req, "id", uid.String())
+		w := httptest.NewRecorder()
+
+		h.UpsertSubscription(w, req)
+
+		require.Equal(t, tc.code, w.Code)
+		m.AssertExpectations(t)
+	}
+}
+
+func TestUpsertSubscription_UserMismatch(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	u := models.UserInfo{
+		UserID: uuid.New(), ServiceName: "Netflix", Price: 999,
+		StartDate: models.Date{Time: start}, EndDate: &models.EndDate{Time: end},
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/users/"+uid.String()+"/subscriptions", toJSON(u))
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.UpsertSubscription(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	m.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything)
+}
+
+func TestLoadNewInfo_ConstraintViolation(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	u := models.UserInfo{
+		UserID: uuid.New(), ServiceName: "Netflix", Price: 999,
+		StartDate: models.Date{Time: time.Now().UTC().Truncate(time.Second)},
+		EndDate:   tomorrow(),
+	}
+
+	m.On("Insert", mock.Anything, mock.AnythingOfType("*models.UserInfo")).
+		Return(fmt.Errorf("repo: insert user_info: %w", repo.ErrConstraint)).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/users", toJSON(u))
+	w := httptest.NewRecorder()
+
+	h.LoadNewInfo(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	m.AssertExpectations(t)
+}
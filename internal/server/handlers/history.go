@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/transport/http/respond"
+)
+
+// GetHistory godoc
+// @Summary Change history of user info
+// @Description Get the audit timeline of all subscription records of a user, oldest first:
+// @Description every insert, update, delete, restore and purge with old and new values, actor and request ID.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Opaque cursor from next_cursor of the previous page"
+// @Success 200 {object} response.HistoryPage
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /users/{id}/history [get]
+func (h *HTTP) GetHistory(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.get_history"
+	ctx := r.Context()
+
+	id, err := parseUUIDVar(r, "id")
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid user_id", err)
+		return
+	}
+
+	q := r.URL.Query()
+	limit := defaultPageLimit
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxPageLimit {
+			respond.Error(w, r, op, http.StatusBadRequest, fmt.Sprintf("invalid limit (use 1..%d)", maxPageLimit), err)
+			return
+		}
+		limit = n
+	}
+	var after int64
+	if s := q.Get("cursor"); s != "" {
+		after, err = strconv.ParseInt(s, 10, 64)
+		if err != nil || after < 0 {
+			respond.Error(w, r, op, http.StatusBadRequest, "invalid cursor", err)
+			return
+		}
+	}
+
+	// one extra entry tells us whether there is a next page
+	items, err := h.DB.History(ctx, id, after, limit+1)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to fetch history", err)
+		return
+	}
+
+	out := response.HistoryPage{Items: items}
+	if len(items) > limit {
+		out.Items = items[:limit]
+		out.NextCursor = strconv.FormatInt(out.Items[limit-1].ID, 10)
+	}
+	if out.Items == nil {
+		out.Items = []models.AuditEntry{}
+	}
+	respond.Writer(w, r, op, http.StatusOK, out)
+}
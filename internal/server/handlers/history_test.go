@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHistory_Pages(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	price := models.UserInfo{UserID: uid, ServiceName: "Netflix", Price: 400}
+	m.On("History", mock.Anything, uid, int64(7), 3).
+		Return([]models.AuditEntry{
+			{ID: 8, UserID: uid, Operation: "insert", NewValue: &price, Actor: "ip:10.0.0.1", RequestID: "req-1"},
+			{ID: 9, UserID: uid, Operation: "update", OldValue: &price, NewValue: &price, Actor: "ip:10.0.0.1"},
+			{ID: 12, UserID: uid, Operation: "delete"},
+		}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+uid.String()+"/history?limit=2&cursor=7", nil)
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.GetHistory(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var out response.HistoryPage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Len(t, out.Items, 2)
+	require.Equal(t, "9", out.NextCursor)
+	require.Nil(t, out.Items[0].OldValue)
+	require.Equal(t, int64(400), out.Items[0].NewValue.Price)
+	require.Equal(t, "req-1", out.Items[0].RequestID)
+	m.AssertExpectations(t)
+}
+
+func TestGetHistory_Empty(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	m.On("History", mock.Anything, uid, int64(0), defaultPageLimit+1).
+		Return([]models.AuditEntry(nil), nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+uid.String()+"/history", nil)
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.GetHistory(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"items":[]}`, w.Body.String())
+	m.AssertExpectations(t)
+}
+
+func TestGetHistory_BadParams(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New().String()
+	for _, tc := range []struct{ id, query string }{
+		{"bad", ""},
+		{uid, "limit=0"},
+		{uid, "limit=501"},
+		{uid, "cursor=abc"},
+		{uid, "cursor=-1"},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+tc.id+"/history?"+tc.query, nil)
+		req = withVars(req, "id", tc.id)
+		w := httptest.NewRecorder()
+
+		h.GetHistory(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, tc)
+	}
+	m.AssertNotCalled(t, "History", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"time"
 	"user-aggregation/internal/models"
+	"user-aggregation/internal/repo"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -19,13 +20,23 @@ func (m *RepoMock) Insert(ctx context.Context, u *models.UserInfo) error {
 	return args.Error(0)
 }
 
+func (m *RepoMock) Upsert(ctx context.Context, u *models.UserInfo) (bool, error) {
+	args := m.Called(ctx, u)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *RepoMock) BulkUpsert(ctx context.Context, items []models.UserInfo, atomic bool) ([]repo.BulkResult, error) {
+	args := m.Called(ctx, items, atomic)
+	return args.Get(0).([]repo.BulkResult), args.Error(1)
+}
+
 func (m *RepoMock) DeleteByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).(int64), args.Error(1)
 }
 
-func (m *RepoMock) UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int64, end *time.Time) (int64, error) {
-	args := m.Called(ctx, userID, price, end)
+func (m *RepoMock) UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (int64, error) {
+	args := m.Called(ctx, userID, price, end, priceFrom)
 	return args.Get(0).(int64), args.Error(1)
 }
 
@@ -34,12 +45,93 @@ func (m *RepoMock) List(ctx context.Context) ([]models.UserInfo, error) {
 	return args.Get(0).([]models.UserInfo), args.Error(1)
 }
 
+func (m *RepoMock) ListPage(ctx context.Context, params repo.ListParams) (repo.Page, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(repo.Page), args.Error(1)
+}
+
 func (m *RepoMock) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.UserInfo, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).([]models.UserInfo), args.Error(1)
 }
 
-func (m *RepoMock) FilterSum(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time) (int64, error) {
+func (m *RepoMock) FilterSum(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time) ([]repo.CurrencyTotal, error) {
+	args := m.Called(ctx, userID, serviceName, start, end)
+	return args.Get(0).([]repo.CurrencyTotal), args.Error(1)
+}
+
+func (m *RepoMock) GroupedSum(ctx context.Context, groupBy repo.GroupBy, userID *uuid.UUID, serviceName *string, start, end *time.Time) ([]repo.GroupStats, error) {
+	args := m.Called(ctx, groupBy, userID, serviceName, start, end)
+	return args.Get(0).([]repo.GroupStats), args.Error(1)
+}
+
+func (m *RepoMock) MonthlyCost(ctx context.Context, userID *uuid.UUID, serviceName *string, from, to time.Time, groupBy repo.GroupBy, basis repo.Basis) ([]repo.MonthlyCost, error) {
+	args := m.Called(ctx, userID, serviceName, from, to, groupBy, basis)
+	return args.Get(0).([]repo.MonthlyCost), args.Error(1)
+}
+
+func (m *RepoMock) GetByID(ctx context.Context, id uuid.UUID) (models.UserInfo, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.UserInfo), args.Error(1)
+}
+
+func (m *RepoMock) UpdateByID(ctx context.Context, id uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (models.UserInfo, error) {
+	args := m.Called(ctx, id, price, end, priceFrom)
+	return args.Get(0).(models.UserInfo), args.Error(1)
+}
+
+func (m *RepoMock) DeleteByID(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// Stream feeds the rows from the first return value to fn.
+func (m *RepoMock) Stream(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time, fn func(models.UserInfo) error) error {
 	args := m.Called(ctx, userID, serviceName, start, end)
+	for _, u := range args.Get(0).([]models.UserInfo) {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *RepoMock) RestoreByUserID(ctx context.Context, userID uuid.UUID) (repo.RestoreResult, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(repo.RestoreResult), args.Error(1)
+}
+
+func (m *RepoMock) RestoreByID(ctx context.Context, id uuid.UUID) (models.UserInfo, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(models.UserInfo), args.Error(1)
+}
+
+func (m *RepoMock) PurgeByUserID(ctx context.Context, userID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *RepoMock) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
 	return args.Get(0).(int64), args.Error(1)
 }
+
+func (m *RepoMock) History(ctx context.Context, userID uuid.UUID, afterID int64, limit int) ([]models.AuditEntry, error) {
+	args := m.Called(ctx, userID, afterID, limit)
+	return args.Get(0).([]models.AuditEntry), args.Error(1)
+}
+
+func (m *RepoMock) PriceHistory(ctx context.Context, id uuid.UUID) ([]models.PricePeriod, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).([]models.PricePeriod), args.Error(1)
+}
+
+func (m *RepoMock) ExchangeRates(ctx context.Context) ([]models.ExchangeRate, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]models.ExchangeRate), args.Error(1)
+}
+
+func (m *RepoMock) SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) error {
+	args := m.Called(ctx, rates)
+	return args.Error(0)
+}
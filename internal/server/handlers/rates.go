@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+	"user-aggregation/internal/lib/validation"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/money"
+	"user-aggregation/internal/transport/http/respond"
+)
+
+const maxExchangeRates = 10000
+
+// GetExchangeRates godoc
+// @Summary List exchange rates
+// @Description All stored exchange rates, ordered by pair and date
+// @Tags rates
+// @Produce json
+// @Success 200 {object} response.ExchangeRates
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /exchange-rates [get]
+func (h *HTTP) GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.get_exchange_rates"
+
+	rates, err := h.DB.ExchangeRates(r.Context())
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to load exchange rates", err)
+		return
+	}
+	if rates == nil {
+		rates = []models.ExchangeRate{}
+	}
+	respond.Writer(w, r, op, http.StatusOK, response.ExchangeRates{Rates: rates})
+}
+
+// PutExchangeRates godoc
+// @Summary Save exchange rates
+// @Description Creates or overwrites rates by (base, quote, effective_from). effective_from is truncated to the UTC date.
+// @Description A rate is used from its date until the next rate of the same pair; the inverse pair is derived when missing.
+// @Tags rates
+// @Accept json
+// @Produce json
+// @Param rates body []models.ExchangeRate true "Exchange rates"
+// @Success 200 {object} response.ExchangeRates
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 422 {object} response.ValidationError
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /exchange-rates [put]
+func (h *HTTP) PutExchangeRates(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.put_exchange_rates"
+	defer r.Body.Close()
+
+	var rates []models.ExchangeRate
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBulkBodyBytes))
+	dec.DisallowUnknownFields()
+	dec.UseNumber()
+	if err := dec.Decode(&rates); err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid JSON body", err)
+		return
+	}
+	if len(rates) > maxExchangeRates {
+		respond.Error(w, r, op, http.StatusBadRequest, "too many rates (max 10000)", nil)
+		return
+	}
+
+	for i := range rates {
+		rates[i].Base = money.Normalize(rates[i].Base)
+		rates[i].Quote = money.Normalize(rates[i].Quote)
+		if !rates[i].EffectiveFrom.IsZero() {
+			rates[i].EffectiveFrom = money.Day(rates[i].EffectiveFrom)
+		}
+	}
+	if errs := validation.ExchangeRates(rates); len(errs) > 0 {
+		respond.Invalid(w, r, op, errs)
+		return
+	}
+
+	if err := h.DB.SaveExchangeRates(r.Context(), rates); err != nil {
+		respond.RepoError(w, r, op, "failed to save exchange rates", err)
+		return
+	}
+	respond.Writer(w, r, op, http.StatusOK, response.ExchangeRates{Rates: rates})
+}
+
+// parseConversion reads the currency and rate_date parameters of the
+// summary endpoints. An empty target means no conversion; rate_date
+// defaults to today (UTC). On error it also returns the offending field.
+func parseConversion(q url.Values) (string, time.Time, string, error) {
+	at := money.Day(time.Now())
+
+	target := money.Normalize(q.Get("currency"))
+	if target != "" && !money.Valid(target) {
+		return "", at, "currency", errors.New("not an ISO 4217 code")
+	}
+	if s := q.Get("rate_date"); s != "" {
+		t, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			return "", at, "rate_date", err
+		}
+		at = t
+	}
+	return target, at, "", nil
+}
+
+// converter loads the stored rates into a converter to target, or returns
+// nil when target is empty.
+func (h *HTTP) converter(ctx context.Context, target string) (*money.Converter, error) {
+	if target == "" {
+		return nil, nil
+	}
+	rates, err := h.DB.ExchangeRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	table, err := money.NewTable(rates)
+	if err != nil {
+		return nil, err
+	}
+	return table.To(target), nil
+}
+
+// conversionError answers a failed conversion: a missing rate is the
+// client's to fix, anything else is ours.
+func conversionError(w http.ResponseWriter, r *http.Request, op string, err error) {
+	if errors.Is(err, money.ErrNoRate) {
+		respond.Error(w, r, op, http.StatusUnprocessableEntity, err.Error(), err)
+		return
+	}
+	respond.RepoError(w, r, op, "failed to convert summary", err)
+}
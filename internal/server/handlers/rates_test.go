@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPutExchangeRates_Normalizes(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	want := []models.ExchangeRate{{
+		Base: "USD", Quote: "RUB", Rate: "92.5",
+		EffectiveFrom: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+	}}
+	m.On("SaveExchangeRates", mock.Anything, want).Return(nil).Once()
+
+	body := `[{"base":" usd","quote":"rub","rate":92.5,"effective_from":"2025-03-01T22:30:00+03:00"}]`
+	req := httptest.NewRequest(http.MethodPut, "/exchange-rates", strings.NewReader(body))
+	w := httptest.NewRecorder()
+
+	h.PutExchangeRates(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var out response.ExchangeRates
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, want[0].EffectiveFrom, out.Rates[0].EffectiveFrom.UTC())
+	m.AssertExpectations(t)
+}
+
+func TestPutExchangeRates_Invalid(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	for body, code := range map[string]int{
+		`{"base":"USD"}`: http.StatusBadRequest,
+		`[]`:             http.StatusUnprocessableEntity,
+		`[{"base":"USD","quote":"USD","rate":1,"effective_from":"2025-01-01T00:00:00Z"}]`: http.StatusUnprocessableEntity,
+		`[{"base":"USD","quote":"RUB","rate":0,"effective_from":"2025-01-01T00:00:00Z"}]`: http.StatusUnprocessableEntity,
+		`[{"base":"USD","quote":"RUB","rate":1}]`:                                         http.StatusUnprocessableEntity,
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/exchange-rates", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		h.PutExchangeRates(w, req)
+		require.Equal(t, code, w.Code, body)
+	}
+	m.AssertNotCalled(t, "SaveExchangeRates", mock.Anything, mock.Anything)
+}
+
+func TestGetFilterSummary_BadConversion(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	for _, q := range []string{"currency=rubles", "currency=USD&rate_date=01.01.2025"} {
+		req := httptest.NewRequest(http.MethodGet, "/summary?"+q, nil)
+		w := httptest.NewRecorder()
+
+		h.GetFilterSummary(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	m.AssertNotCalled(t, "FilterSum", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"user-aggregation/internal/lib/validation"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/transport/http/respond"
+)
+
+// GetSubscription godoc
+// @Summary Get subscription by ID
+// @Description Get a single subscription record by its ID
+// @Tags subscriptions
+// @Produce json
+// @Param subscription_id path string true "Subscription ID (UUID)"
+// @Success 200 {object} models.UserInfo
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 404 {object} response.ErrorPayload
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /subscriptions/{subscription_id} [get]
+func (h *HTTP) GetSubscription(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.get_subscription"
+	ctx := r.Context()
+
+	id, err := parseUUIDVar(r, "subscription_id")
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid subscription_id", err)
+		return
+	}
+
+	ui, err := h.DB.GetByID(ctx, id)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to fetch record", err)
+		return
+	}
+
+	respond.Writer(w, r, op, http.StatusOK, ui)
+}
+
+// PatchSubscription godoc
+// @Summary Update subscription
+// @Description Partially update a single subscription record (price and/or end date)
+// @Description A new price applies from effective_from (today by default); earlier periods keep their price.
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param subscription_id path string true "Subscription ID (UUID)"
+// @Param update body models.UpdateUserInfo true "Update fields (price and/or end_date; end_date null makes the subscription open-ended; effective_from dates the price change)"
+// @Success 200 {object} models.UserInfo
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 404 {object} response.ErrorPayload
+// @Failure 422 {object} response.ValidationError
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /subscriptions/{subscription_id} [patch]
+func (h *HTTP) PatchSubscription(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.patch_subscription"
+	ctx := r.Context()
+
+	id, err := parseUUIDVar(r, "subscription_id")
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid subscription_id", err)
+		return
+	}
+	defer r.Body.Close()
+
+	var patch models.UpdateUserInfo
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&patch); err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid JSON body", err)
+		return
+	}
+
+	if patch.Price == nil && !patch.EndDate.Set {
+		respond.Error(w, r, op, http.StatusBadRequest, "no fields to update", nil)
+		return
+	}
+
+	if errs := validation.Update(&patch); len(errs) > 0 {
+		respond.Invalid(w, r, op, errs)
+		return
+	}
+
+	if patch.EndDate.Time != nil {
+		t := patch.EndDate.Time.UTC()
+		patch.EndDate.Time = &t
+	}
+	if patch.EffectiveFrom != nil {
+		t := patch.EffectiveFrom.UTC()
+		patch.EffectiveFrom = &t
+	}
+
+	ui, err := h.DB.UpdateByID(ctx, id, patch.Price, patch.EndDate, patch.EffectiveFrom)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to update subscription", err)
+		return
+	}
+
+	respond.Writer(w, r, op, http.StatusOK, ui)
+}
+
+// DeleteSubscription godoc
+// @Summary Delete subscription
+// @Description Delete a single subscription record by its ID
+// @Tags subscriptions
+// @Produce json
+// @Param subscription_id path string true "Subscription ID (UUID)"
+// @Success 200 {object} map[string]int64 "Number of deleted records"
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 404 {object} response.ErrorPayload
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /subscriptions/{subscription_id} [delete]
+func (h *HTTP) DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.delete_subscription"
+	ctx := r.Context()
+
+	id, err := parseUUIDVar(r, "subscription_id")
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid subscription_id", err)
+		return
+	}
+
+	if err := h.DB.DeleteByID(ctx, id); err != nil {
+		respond.RepoError(w, r, op, "failed to delete", err)
+		return
+	}
+
+	respond.Writer(w, r, op, http.StatusOK, map[string]any{"deleted": 1})
+}
+
+// GetPriceHistory godoc
+// @Summary Price history of a subscription
+// @Description Get the price periods of a subscription, oldest first. Each price applies from effective_from until the next period starts.
+// @Tags subscriptions
+// @Produce json
+// @Param subscription_id path string true "Subscription ID (UUID)"
+// @Success 200 {array} models.PricePeriod
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 404 {object} response.ErrorPayload
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /subscriptions/{subscription_id}/prices [get]
+func (h *HTTP) GetPriceHistory(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.get_price_history"
+	ctx := r.Context()
+
+	id, err := parseUUIDVar(r, "subscription_id")
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid subscription_id", err)
+		return
+	}
+
+	prices, err := h.DB.PriceHistory(ctx, id)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to fetch prices", err)
+		return
+	}
+
+	respond.Writer(w, r, op, http.StatusOK, prices)
+}
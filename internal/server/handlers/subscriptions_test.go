@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSubscription_OK(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	expected := models.UserInfo{ID: id, UserID: uuid.New(), ServiceName: "A", Price: 10}
+
+	m.On("GetByID", mock.Anything, id).
+		Return(expected, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions/"+id.String(), nil)
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.GetSubscription(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var out models.UserInfo
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, id, out.ID)
+	m.AssertExpectations(t)
+}
+
+func TestGetSubscription_NotFound(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	m.On("GetByID", mock.Anything, id).
+		Return(models.UserInfo{}, repo.ErrNotFound).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions/"+id.String(), nil)
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.GetSubscription(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	m.AssertExpectations(t)
+}
+
+func TestGetSubscription_BadUUID(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions/bad", nil)
+	req = withVars(req, "subscription_id", "bad")
+	w := httptest.NewRecorder()
+
+	h.GetSubscription(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	m.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+}
+
+func TestPatchSubscription_OK(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	price := int64(1999)
+	end := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Second)
+
+	m.
+		On("UpdateByID", mock.Anything, id,
+			mock.MatchedBy(func(p *int64) bool { return p != nil && *p == price }),
+			mock.MatchedBy(func(e models.NullableTime) bool { return e.Set && e.Time != nil && e.Time.Equal(end) }),
+			(*time.Time)(nil),
+		).
+		Return(models.UserInfo{ID: id, Price: price, EndDate: &models.EndDate{Time: end}}, nil).
+		Once()
+
+	body := models.UpdateUserInfo{Price: &price, EndDate: models.NullableTime{Set: true, Time: &end}}
+	req := httptest.NewRequest(http.MethodPatch, "/subscriptions/"+id.String(), toJSON(body))
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.PatchSubscription(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	m.AssertExpectations(t)
+}
+
+func TestPatchSubscription_EndOfPeriod(t *testing.T) {
+	// the last instant of a day or month is kept as POST stores it
+	for in, want := range map[string]time.Time{
+		"2025-06":    time.Date(2025, 6, 30, 23, 59, 59, 999999000, time.UTC),
+		"2025-06-15": time.Date(2025, 6, 15, 23, 59, 59, 999999000, time.UTC),
+	} {
+		m := new(mocks.RepoMock)
+		h := New(slog.Default(), m)
+
+		id := uuid.New()
+		m.On("UpdateByID", mock.Anything, id, (*int64)(nil),
+			mock.MatchedBy(func(e models.NullableTime) bool { return e.Set && e.Time != nil && e.Time.Equal(want) }),
+			(*time.Time)(nil),
+		).
+			Return(models.UserInfo{ID: id}, nil).
+			Once()
+
+		req := httptest.NewRequest(http.MethodPatch, "/subscriptions/"+id.String(), bytes.NewReader([]byte(`{"end_date":"`+in+`"}`)))
+		req = withVars(req, "subscription_id", id.String())
+		w := httptest.NewRecorder()
+
+		h.PatchSubscription(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, in)
+		m.AssertExpectations(t)
+	}
+}
+
+func TestPatchSubscription_NoFields_BadRequest(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	req := httptest.NewRequest(http.MethodPatch, "/subscriptions/"+id.String(), bytes.NewReader([]byte(`{}`)))
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.PatchSubscription(w, req)
+
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	m.AssertNotCalled(t, "UpdateByID", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestPatchSubscription_NotFound(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	price := int64(100)
+	m.On("UpdateByID", mock.Anything, id, mock.Anything, models.NullableTime{}, (*time.Time)(nil)).
+		Return(models.UserInfo{}, repo.ErrNotFound).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPatch, "/subscriptions/"+id.String(), toJSON(models.UpdateUserInfo{Price: &price}))
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.PatchSubscription(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	m.AssertExpectations(t)
+}
+
+func TestPatchSubscription_EndBeforeStart(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	end := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.On("UpdateByID", mock.Anything, id, (*int64)(nil), mock.Anything, (*time.Time)(nil)).
+		Return(models.UserInfo{}, fmt.Errorf("repo: patch by id: %w", repo.ErrEndBeforeStart)).
+		Once()
+
+	body := models.UpdateUserInfo{EndDate: models.NullableTime{Set: true, Time: &end}}
+	req := httptest.NewRequest(http.MethodPatch, "/subscriptions/"+id.String(), toJSON(body))
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.PatchSubscription(w, req)
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var out response.ValidationError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, []response.FieldError{{Field: "end_date", Code: "before_start"}}, out.Errors)
+	m.AssertExpectations(t)
+}
+
+func TestDeleteSubscription_OK(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	m.On("DeleteByID", mock.Anything, id).
+		Return(nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodDelete, "/subscriptions/"+id.String(), nil)
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.DeleteSubscription(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	m.AssertExpectations(t)
+	m.AssertNotCalled(t, "DeleteByUserID", mock.Anything, mock.Anything)
+}
+
+func TestDeleteSubscription_NotFound(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	m.On("DeleteByID", mock.Anything, id).
+		Return(repo.ErrNotFound).
+		Once()
+
+	req := httptest.NewRequest(http.MethodDelete, "/subscriptions/"+id.String(), nil)
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.DeleteSubscription(w, req)
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	m.AssertExpectations(t)
+}
+
+func TestGetPriceHistory_OK(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	m.On("PriceHistory", mock.Anything, id).
+		Return([]models.PricePeriod{
+			{EffectiveFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Price: 400},
+			{EffectiveFrom: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Price: 500},
+		}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/subscriptions/"+id.String()+"/prices", nil)
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.GetPriceHistory(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `[
+		{"effective_from":"2025-01-01T00:00:00Z","price":400},
+		{"effective_from":"2025-03-01T00:00:00Z","price":500}
+	]`, w.Body.String())
+	m.AssertExpectations(t)
+}
+
+func TestPatchSubscription_EffectiveFrom(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	id := uuid.New()
+	price := int64(500)
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.FixedZone("MSK", 3*3600))
+
+	m.On("UpdateByID", mock.Anything, id,
+		mock.MatchedBy(func(p *int64) bool { return p != nil && *p == price }),
+		models.NullableTime{},
+		mock.MatchedBy(func(t *time.Time) bool { return t != nil && t.Equal(from) && t.Location() == time.UTC }),
+	).
+		Return(models.UserInfo{ID: id, Price: price}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPatch, "/subscriptions/"+id.String(),
+		toJSON(models.UpdateUserInfo{Price: &price, EffectiveFrom: &from}))
+	req = withVars(req, "subscription_id", id.String())
+	w := httptest.NewRecorder()
+
+	h.PatchSubscription(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	m.AssertExpectations(t)
+
+	// a date without a price is rejected
+	req = httptest.NewRequest(http.MethodPatch, "/subscriptions/"+id.String(),
+		bytes.NewReader([]byte(`{"end_date":"2025-12-31T00:00:00Z","effective_from":"2025-03-01T00:00:00Z"}`)))
+	req = withVars(req, "subscription_id", id.String())
+	w = httptest.NewRecorder()
+
+	h.PatchSubscription(w, req)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
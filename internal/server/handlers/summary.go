@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/money"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/transport/http/respond"
+
+	"github.com/google/uuid"
+)
+
+const (
+	monthLayout      = "2006-01"
+	maxSummaryMonths = 120
+)
+
+// GetMonthlySummary godoc
+// @Summary Get monthly cost breakdown
+// @Description Cost of each calendar month of [from, to]. With basis=charges (default) a subscription is charged its price on the start date and then every billing period, and a month gets the charges made in it. With basis=prorated a month gets price * covered_days / days_in_period instead (one-off prices stay whole). Either way each day is at the price in effect on it.
+// @Tags summary
+// @Produce json
+// @Param from query string true "First month: the month of this date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)"
+// @Param to query string true "Last month, inclusive: the month of this date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)"
+// @Param service_name query string false "Filter by service name"
+// @Param user_id query string false "Filter by user ID (UUID)"
+// @Param group_by query string false "Split each month by service_name or user_id"
+// @Param basis query string false "charges (default) or prorated"
+// @Param currency query string false "Convert each month into this currency at the rates of its first day (ISO 4217)"
+// @Success 200 {object} response.MonthlySummary
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 422 {object} response.ErrorPayload "no exchange rate for a currency"
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /summary/monthly [get]
+func (h *HTTP) GetMonthlySummary(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.get_monthly_summary"
+	ctx := r.Context()
+
+	q := r.URL.Query()
+
+	from, err := models.ParseDate(q.Get("from"))
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid from (use "+models.DateInputs+")", err)
+		return
+	}
+	to, err := models.ParseEndDate(q.Get("to"))
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid to (use "+models.DateInputs+")", err)
+		return
+	}
+	from, to = truncMonth(from), truncMonth(to)
+	if to.Before(from) {
+		respond.Error(w, r, op, http.StatusBadRequest, "to must not be before from", nil)
+		return
+	}
+	if monthsBetween(from, to) > maxSummaryMonths {
+		respond.Error(w, r, op, http.StatusBadRequest, "range is too long (max 120 months)", nil)
+		return
+	}
+
+	groupBy := repo.GroupBy(q.Get("group_by"))
+	if !groupBy.Valid() || groupBy == repo.GroupByMonth {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid group_by (use service_name or user_id)", nil)
+		return
+	}
+
+	basis := repo.BasisCharges
+	if b := q.Get("basis"); b != "" {
+		basis = repo.Basis(b)
+	}
+	if !basis.Valid() {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid basis (use charges or prorated)", nil)
+		return
+	}
+
+	userID, serviceName, field, err := parseSummaryFilters(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field, err)
+		return
+	}
+	// rate_date is ignored: every month has its own rates
+	target, _, field, err := parseConversion(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field, err)
+		return
+	}
+
+	costs, err := h.DB.MonthlyCost(ctx, userID, serviceName, from, to, groupBy, basis)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to calculate summary", err)
+		return
+	}
+
+	conv, err := h.converter(ctx, target)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to load exchange rates", err)
+		return
+	}
+
+	out := response.MonthlySummary{
+		From:    from.Format(monthLayout),
+		To:      to.Format(monthLayout),
+		GroupBy: string(groupBy),
+		Basis:   string(basis),
+		Months:  make([]response.MonthCost, 0, monthsBetween(from, to)),
+	}
+	idx := make(map[string]int)
+	for m := from; !m.After(to); m = m.AddDate(0, 1, 0) {
+		idx[m.Format(monthLayout)] = len(out.Months)
+		mc := response.MonthCost{Month: m.Format(monthLayout), Totals: []response.CurrencyTotal{}}
+		if conv != nil {
+			mc.TotalCost = new(int64)
+		}
+		out.Months = append(out.Months, mc)
+	}
+	for _, c := range costs {
+		i, ok := idx[c.Month.Format(monthLayout)]
+		if !ok {
+			continue
+		}
+		mc := &out.Months[i]
+		mc.Totals = addTotal(mc.Totals, c.Currency, c.Cost)
+
+		group := response.GroupCost{Key: c.Key, Currency: c.Currency, TotalCost: c.Cost}
+		if conv != nil {
+			// each month at the rates in effect on its first day
+			v, err := conv.Convert(c.Cost, c.Currency, c.Month)
+			if err != nil {
+				conversionError(w, r, op, err)
+				return
+			}
+			*mc.TotalCost += v
+			group.Currency, group.TotalCost = conv.Target(), v
+		}
+		if groupBy != repo.GroupByNone {
+			mc.Groups = addGroupCost(mc.Groups, group)
+		}
+	}
+	if conv != nil {
+		out.Currency = conv.Target()
+		out.Rates = conv.Used()
+	}
+
+	respond.Writer(w, r, op, http.StatusOK, out)
+}
+
+// addTotal adds cost to the total of currency, appending it if new.
+func addTotal(totals []response.CurrencyTotal, currency string, cost int64) []response.CurrencyTotal {
+	for i := range totals {
+		if totals[i].Currency == currency {
+			totals[i].TotalCost += cost
+			return totals
+		}
+	}
+	return append(totals, response.CurrencyTotal{Currency: currency, TotalCost: cost})
+}
+
+// addGroupCost adds g to the group with the same key and currency,
+// appending it if new. Converted rows of one key end up merged.
+func addGroupCost(groups []response.GroupCost, g response.GroupCost) []response.GroupCost {
+	for i := range groups {
+		if groups[i].Key == g.Key && groups[i].Currency == g.Currency {
+			groups[i].TotalCost += g.TotalCost
+			return groups
+		}
+	}
+	return append(groups, g)
+}
+
+// GetGroupedSummary godoc
+// @Summary Get grouped summary
+// @Description Total cost, count and avg/min/max charge per service, user or charge month. Takes the same filters as /summary.
+// @Tags summary
+// @Produce json
+// @Param group_by query string true "Group by service_name, user_id or month (month of the charge)"
+// @Param service_name query string false "Filter by service name"
+// @Param user_id query string false "Filter by user ID (UUID)"
+// @Param start_date query string false "Filter by start date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY)"
+// @Param end_date query string false "Filter by end date (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its last moment)"
+// @Param currency query string false "Convert into this currency, merging the currencies of each group (ISO 4217)"
+// @Param rate_date query string false "Date of the exchange rates, YYYY-MM-DD (default today, UTC)"
+// @Success 200 {object} response.GroupedSummary
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 422 {object} response.ErrorPayload "no exchange rate for a currency"
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /summary/grouped [get]
+func (h *HTTP) GetGroupedSummary(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.get_grouped_summary"
+	ctx := r.Context()
+
+	q := r.URL.Query()
+
+	groupBy := repo.GroupBy(q.Get("group_by"))
+	if groupBy == repo.GroupByNone || !groupBy.Valid() {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid group_by (use service_name, user_id or month)", nil)
+		return
+	}
+
+	userID, serviceName, field, err := parseSummaryFilters(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field, err)
+		return
+	}
+	startDate, endDate, field, err := parseSummaryRange(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field+" (use "+models.DateInputs+")", err)
+		return
+	}
+
+	target, rateDate, field, err := parseConversion(q)
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid "+field, err)
+		return
+	}
+
+	stats, err := h.DB.GroupedSum(ctx, groupBy, userID, serviceName, startDate, endDate)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to calculate summary", err)
+		return
+	}
+	conv, err := h.converter(ctx, target)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to load exchange rates", err)
+		return
+	}
+
+	out := response.GroupedSummary{
+		GroupBy: string(groupBy),
+		Groups:  make([]response.GroupStats, 0, len(stats)),
+	}
+	if conv != nil {
+		if out.Groups, err = convertGroups(conv, stats, rateDate); err != nil {
+			conversionError(w, r, op, err)
+			return
+		}
+		out.Currency = conv.Target()
+		out.Rates = conv.Used()
+		respond.Writer(w, r, op, http.StatusOK, out)
+		return
+	}
+	for _, g := range stats {
+		out.Groups = append(out.Groups, response.GroupStats{
+			Key:               g.Key,
+			Currency:          g.Currency,
+			TotalCost:         g.TotalCost,
+			ChargeCount:       g.Charges,
+			SubscriptionCount: g.SubscriptionCount,
+			AvgPrice:          g.AvgPrice,
+			MinPrice:          g.MinPrice,
+			MaxPrice:          g.MaxPrice,
+		})
+	}
+	respond.Writer(w, r, op, http.StatusOK, out)
+}
+
+// convertGroups merges the per-currency rows of each key into one row in
+// the converter's currency. The average is recomputed from the converted
+// totals so that every charge weighs the same.
+func convertGroups(conv *money.Converter, stats []repo.GroupStats, at time.Time) ([]response.GroupStats, error) {
+	type acc struct {
+		sum     *big.Rat // exact converted total, for the average
+		charges int64
+	}
+	out := make([]response.GroupStats, 0, len(stats))
+	accs := make([]acc, 0, len(stats))
+	idx := make(map[string]int)
+	for _, g := range stats {
+		f, err := conv.Factor(g.Currency, at)
+		if err != nil {
+			return nil, err
+		}
+		in := func(v int64) int64 { return money.Round(new(big.Rat).Mul(big.NewRat(v, 1), f)) }
+
+		i, ok := idx[g.Key]
+		if !ok {
+			i = len(out)
+			idx[g.Key] = i
+			out = append(out, response.GroupStats{Key: g.Key, Currency: conv.Target(), MinPrice: in(g.MinPrice), MaxPrice: in(g.MaxPrice)})
+			accs = append(accs, acc{sum: new(big.Rat)})
+		}
+		o := &out[i]
+		o.TotalCost += in(g.TotalCost)
+		o.ChargeCount += g.Charges
+		o.SubscriptionCount += g.SubscriptionCount
+		o.MinPrice = min(o.MinPrice, in(g.MinPrice))
+		o.MaxPrice = max(o.MaxPrice, in(g.MaxPrice))
+		accs[i].sum.Add(accs[i].sum, new(big.Rat).Mul(big.NewRat(g.TotalCost, 1), f))
+		accs[i].charges += g.Charges
+	}
+	for i, a := range accs {
+		if a.charges == 0 {
+			continue
+		}
+		avg, _ := new(big.Rat).Quo(a.sum, big.NewRat(a.charges, 1)).Float64()
+		out[i].AvgPrice = math.Round(avg*100) / 100
+	}
+	return out, nil
+}
+
+// parseSummaryFilters reads the user_id and service_name filters shared by
+// the summary endpoints. On error it also returns the offending field name.
+func parseSummaryFilters(q url.Values) (*uuid.UUID, *string, string, error) {
+	var serviceName *string
+	if s := q.Get("service_name"); s != "" {
+		serviceName = &s
+	}
+
+	var userID *uuid.UUID
+	if uid := q.Get("user_id"); uid != "" {
+		parsed, err := uuid.Parse(uid)
+		if err != nil {
+			return nil, nil, "user_id", err
+		}
+		userID = &parsed
+	}
+	return userID, serviceName, "", nil
+}
+
+// parseSummaryRange reads the start_date/end_date filters in any of
+// models.DateInputs; a month-only end_date covers the whole month.
+// On error it also returns the offending field name.
+func parseSummaryRange(q url.Values) (*time.Time, *time.Time, string, error) {
+	var start, end *time.Time
+	if s := q.Get("start_date"); s != "" {
+		t, err := models.ParseDate(s)
+		if err != nil {
+			return nil, nil, "start_date", err
+		}
+		start = &t
+	}
+	if s := q.Get("end_date"); s != "" {
+		t, err := models.ParseEndDate(s)
+		if err != nil {
+			return nil, nil, "end_date", err
+		}
+		end = &t
+	}
+	return start, end, "", nil
+}
+
+// truncMonth is the first instant of the UTC month of t.
+func truncMonth(t time.Time) time.Time {
+	y, m, _ := t.UTC().Date()
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthsBetween counts calendar months in [from, to], both inclusive.
+func monthsBetween(from, to time.Time) int {
+	return (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func month(y int, m time.Month) time.Time {
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestGetMonthlySummary_OK(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.On("MonthlyCost", mock.Anything, (*uuid.UUID)(nil), (*string)(nil),
+		month(2025, time.January), month(2025, time.March), repo.GroupByNone, repo.BasisCharges).
+		Return([]repo.MonthlyCost{
+			{Month: month(2025, time.January), Currency: "RUB", Cost: 100},
+			{Month: month(2025, time.March), Currency: "RUB", Cost: 50},
+		}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/summary/monthly?from=2025-01&to=2025-03", nil)
+	w := httptest.NewRecorder()
+
+	h.GetMonthlySummary(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var out response.MonthlySummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, "charges", out.Basis)
+	require.Equal(t, []response.MonthCost{
+		{Month: "2025-01", Totals: []response.CurrencyTotal{{Currency: "RUB", TotalCost: 100}}},
+		{Month: "2025-02", Totals: []response.CurrencyTotal{}},
+		{Month: "2025-03", Totals: []response.CurrencyTotal{{Currency: "RUB", TotalCost: 50}}},
+	}, out.Months)
+	m.AssertExpectations(t)
+}
+
+func TestGetMonthlySummary_Grouped(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	m.On("MonthlyCost", mock.Anything,
+		mock.MatchedBy(func(p *uuid.UUID) bool { return p != nil && *p == uid }),
+		(*string)(nil),
+		month(2024, time.December), month(2025, time.January), repo.GroupByService, repo.BasisProrated).
+		Return([]repo.MonthlyCost{
+			{Month: month(2024, time.December), Key: "A", Currency: "RUB", Cost: 10},
+			{Month: month(2024, time.December), Key: "B", Currency: "RUB", Cost: 20},
+			{Month: month(2024, time.December), Key: "B", Currency: "USD", Cost: 2},
+			{Month: month(2025, time.January), Key: "A", Currency: "RUB", Cost: 5},
+		}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/summary/monthly?from=2024-12&to=2025-01&group_by=service_name&basis=prorated&user_id="+uid.String(), nil)
+	w := httptest.NewRecorder()
+
+	h.GetMonthlySummary(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var out response.MonthlySummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, "service_name", out.GroupBy)
+	require.Equal(t, "prorated", out.Basis)
+	require.Len(t, out.Months, 2)
+	require.Nil(t, out.Months[0].TotalCost)
+	require.Equal(t, []response.CurrencyTotal{{Currency: "RUB", TotalCost: 30}, {Currency: "USD", TotalCost: 2}}, out.Months[0].Totals)
+	require.Equal(t, []response.GroupCost{
+		{Key: "A", Currency: "RUB", TotalCost: 10},
+		{Key: "B", Currency: "RUB", TotalCost: 20},
+		{Key: "B", Currency: "USD", TotalCost: 2},
+	}, out.Months[0].Groups)
+	require.Equal(t, []response.CurrencyTotal{{Currency: "RUB", TotalCost: 5}}, out.Months[1].Totals)
+	m.AssertExpectations(t)
+}
+
+func TestGetMonthlySummary_DateForms(t *testing.T) {
+	for _, q := range []string{
+		"from=07-2025&to=08-2025",
+		"from=2025-07-15&to=2025-08-31",
+		"from=2025-07-01T00:00:00Z&to=2025-08-31T23:59:59Z",
+		// months of the UTC instants: 1 July 01:00 and 31 August 22:00
+		"from=2025-06-30T22:00:00-03:00&to=2025-09-01T01:00:00%2B03:00",
+	} {
+		m := new(mocks.RepoMock)
+		h := New(slog.Default(), m)
+
+		m.On("MonthlyCost", mock.Anything, (*uuid.UUID)(nil), (*string)(nil),
+			month(2025, time.July), month(2025, time.August), repo.GroupByNone, repo.BasisCharges).
+			Return([]repo.MonthlyCost{}, nil).
+			Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/summary/monthly?"+q, nil)
+		w := httptest.NewRecorder()
+
+		h.GetMonthlySummary(w, req)
+		require.Equal(t, http.StatusOK, w.Code, q)
+
+		var out response.MonthlySummary
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+		require.Equal(t, "2025-07", out.From, q)
+		require.Equal(t, "2025-08", out.To, q)
+		m.AssertExpectations(t)
+	}
+}
+
+func TestGetMonthlySummary_BadParams(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	for _, q := range []string{
+		"",
+		"from=2025-01",
+		"from=2025-13&to=2025-12",
+		"from=2025/01&to=2025-12",
+		"from=2025-06&to=2025-01",
+		"from=2000-01&to=2025-01",
+		"from=2025-01&to=2025-12&group_by=price",
+		"from=2025-01&to=2025-12&group_by=month",
+		"from=2025-01&to=2025-12&user_id=bad",
+		"from=2025-01&to=2025-12&basis=daily",
+		"from=2025-01&to=2025-12&currency=XYZ",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/summary/monthly?"+q, nil)
+		w := httptest.NewRecorder()
+
+		h.GetMonthlySummary(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	m.AssertNotCalled(t, "MonthlyCost", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetGroupedSummary_OK(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	m.On("GroupedSum", mock.Anything, repo.GroupByMonth,
+		(*uuid.UUID)(nil),
+		mock.MatchedBy(func(p *string) bool { return p != nil && *p == "Netflix" }),
+		mock.MatchedBy(func(p *time.Time) bool { return p != nil && p.Equal(start) }),
+		(*time.Time)(nil)).
+		Return([]repo.GroupStats{
+			{Key: "2025-01", Currency: "RUB", Charges: 2, TotalCost: 300, SubscriptionCount: 2, AvgPrice: 150, MinPrice: 100, MaxPrice: 200},
+		}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/summary/grouped?group_by=month&service_name=Netflix&start_date="+start.Format(time.RFC3339), nil)
+	w := httptest.NewRecorder()
+
+	h.GetGroupedSummary(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"group_by":"month","groups":[
+		{"key":"2025-01","currency":"RUB","total_cost":300,"charge_count":2,"subscription_count":2,"avg_price":150,"min_price":100,"max_price":200}
+	]}`, w.Body.String())
+	m.AssertExpectations(t)
+}
+
+func TestGetGroupedSummary_BadParams(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	for _, q := range []string{
+		"",
+		"group_by=price",
+		"group_by=user_id&user_id=bad",
+		"group_by=user_id&start_date=2025/01",
+		"group_by=user_id&end_date=tomorrow",
+		"group_by=user_id&currency=dollars",
+		"group_by=user_id&currency=USD&rate_date=2025-01",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/summary/grouped?"+q, nil)
+		w := httptest.NewRecorder()
+
+		h.GetGroupedSummary(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, q)
+	}
+	m.AssertNotCalled(t, "GroupedSum", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func rates() []models.ExchangeRate {
+	return []models.ExchangeRate{
+		{Base: "USD", Quote: "RUB", Rate: "90", EffectiveFrom: month(2024, time.December)},
+		{Base: "USD", Quote: "RUB", Rate: "100", EffectiveFrom: month(2025, time.January)},
+	}
+}
+
+func TestGetMonthlySummary_Converted(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.On("MonthlyCost", mock.Anything, (*uuid.UUID)(nil), (*string)(nil),
+		month(2024, time.December), month(2025, time.January), repo.GroupByUser, repo.BasisCharges).
+		Return([]repo.MonthlyCost{
+			{Month: month(2024, time.December), Key: "u1", Currency: "RUB", Cost: 10},
+			{Month: month(2024, time.December), Key: "u1", Currency: "USD", Cost: 2},
+			{Month: month(2025, time.January), Key: "u1", Currency: "USD", Cost: 2},
+		}, nil).
+		Once()
+	m.On("ExchangeRates", mock.Anything).Return(rates(), nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/summary/monthly?from=2024-12&to=2025-01&group_by=user_id&currency=rub", nil)
+	w := httptest.NewRecorder()
+
+	h.GetMonthlySummary(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var out response.MonthlySummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, "RUB", out.Currency)
+	require.Equal(t, int64(190), *out.Months[0].TotalCost, "December at 90")
+	require.Equal(t, []response.GroupCost{{Key: "u1", Currency: "RUB", TotalCost: 190}}, out.Months[0].Groups)
+	require.Equal(t, int64(200), *out.Months[1].TotalCost, "January at 100")
+	require.Len(t, out.Rates, 2)
+	m.AssertExpectations(t)
+}
+
+func TestGetGroupedSummary_Converted(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.On("GroupedSum", mock.Anything, repo.GroupByService,
+		(*uuid.UUID)(nil), (*string)(nil), (*time.Time)(nil), (*time.Time)(nil)).
+		Return([]repo.GroupStats{
+			{Key: "A", Currency: "RUB", Charges: 3, TotalCost: 300, SubscriptionCount: 2, AvgPrice: 100, MinPrice: 50, MaxPrice: 150},
+			{Key: "A", Currency: "USD", Charges: 1, TotalCost: 1, SubscriptionCount: 1, AvgPrice: 1, MinPrice: 1, MaxPrice: 1},
+			{Key: "B", Currency: "USD", Charges: 1, TotalCost: 3, SubscriptionCount: 1, AvgPrice: 3, MinPrice: 3, MaxPrice: 3},
+		}, nil).
+		Once()
+	m.On("ExchangeRates", mock.Anything).Return(rates(), nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/summary/grouped?group_by=service_name&currency=RUB&rate_date=2024-12-15", nil)
+	w := httptest.NewRecorder()
+
+	h.GetGroupedSummary(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"group_by":"service_name","currency":"RUB","groups":[
+		{"key":"A","currency":"RUB","total_cost":390,"charge_count":4,"subscription_count":3,"avg_price":97.5,"min_price":50,"max_price":150},
+		{"key":"B","currency":"RUB","total_cost":270,"charge_count":1,"subscription_count":1,"avg_price":270,"min_price":270,"max_price":270}
+	],"rates":[{"base":"USD","quote":"RUB","rate":90,"effective_from":"2024-12-01T00:00:00Z"}]}`, w.Body.String())
+	m.AssertExpectations(t)
+}
+
+func TestGetGroupedSummary_NoRate(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	m.On("GroupedSum", mock.Anything, repo.GroupByService,
+		(*uuid.UUID)(nil), (*string)(nil), (*time.Time)(nil), (*time.Time)(nil)).
+		Return([]repo.GroupStats{{Key: "A", Currency: "USD", Charges: 1, TotalCost: 1}}, nil).
+		Once()
+	m.On("ExchangeRates", mock.Anything).Return(rates(), nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/summary/grouped?group_by=service_name&currency=RUB&rate_date=2024-11-30", nil)
+	w := httptest.NewRecorder()
+
+	h.GetGroupedSummary(w, req)
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	require.Contains(t, w.Body.String(), "no exchange rate from USD to RUB on 2024-11-30")
+	m.AssertExpectations(t)
+}
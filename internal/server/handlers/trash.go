@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/transport/http/respond"
+)
+
+// GetTrash godoc
+// @Summary List deleted records
+// @Description Get soft-deleted subscription records page by page. Takes the same parameters as GET /users.
+// @Tags users
+// @Produce json
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "Opaque cursor from next_cursor of the previous page"
+// @Param sort query string false "Sort field: price, start_date (default), end_date, service_name"
+// @Param order query string false "Sort direction: asc (default) or desc"
+// @Param service_name query string false "Filter by service name prefix"
+// @Param min_price query int false "Filter by minimum price (inclusive)"
+// @Param max_price query int false "Filter by maximum price (inclusive)"
+// @Param active_at query string false "Only subscriptions active at this moment (RFC3339, YYYY-MM-DD, YYYY-MM or MM-YYYY; a day or month means its first moment)"
+// @Success 200 {object} response.UserInfoPage
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /users/trash [get]
+func (h *HTTP) GetTrash(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.get_trash"
+	ctx := r.Context()
+
+	params, msg, err := parseListParams(r.URL.Query())
+	if msg != "" {
+		respond.Error(w, r, op, http.StatusBadRequest, msg, err)
+		return
+	}
+	params.Deleted = true
+
+	page, err := h.DB.ListPage(ctx, params)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to list trash", err)
+		return
+	}
+
+	out := response.UserInfoPage{
+		Items:      page.Items,
+		NextCursor: page.NextCursor,
+	}
+	if out.Items == nil {
+		out.Items = []models.UserInfo{}
+	}
+	respond.Writer(w, r, op, http.StatusOK, out)
+}
+
+// RestoreInfo godoc
+// @Summary Restore deleted user info
+// @Description Bring back the records removed by the last DELETE /users/{id} of a user. Records that clash with a live one on user, service and start date stay in the trash and are listed in conflicts.
+// @Tags users
+// @Produce json
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} response.RestoreReport
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 404 {object} response.ErrorPayload
+// @Failure 409 {object} response.ErrorPayload "a conflicting record was created concurrently"
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /users/{id}/restore [post]
+func (h *HTTP) RestoreInfo(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.restore_info"
+	ctx := r.Context()
+
+	id, err := parseUUIDVar(r, "id")
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid user_id", err)
+		return
+	}
+
+	res, err := h.DB.RestoreByUserID(ctx, id)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to restore", err)
+		return
+	}
+
+	out := response.RestoreReport{Restored: res.Restored, Conflicts: res.Conflicts}
+	if out.Conflicts == nil {
+		out.Conflicts = []models.UserInfo{}
+	}
+	respond.Writer(w, r, op, http.StatusOK, out)
+}
+
+// RestoreSubscription godoc
+// @Summary Restore deleted subscription
+// @Description Bring a single trashed subscription record back by its ID
+// @Tags subscriptions
+// @Produce json
+// @Param subscription_id path string true "Subscription ID (UUID)"
+// @Success 200 {object} models.UserInfo
+// @Failure 400 {object} response.ErrorPayload
+// @Failure 404 {object} response.ErrorPayload
+// @Failure 409 {object} response.ErrorPayload "a live record with the same user, service and start date exists"
+// @Failure 500 {object} response.ErrorPayload
+// @Failure 429 {object} response.ErrorPayload "rate limit exceeded, see Retry-After"
+// @Security BearerAuth
+// @Router /subscriptions/{subscription_id}/restore [post]
+func (h *HTTP) RestoreSubscription(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.restore_subscription"
+	ctx := r.Context()
+
+	id, err := parseUUIDVar(r, "subscription_id")
+	if err != nil {
+		respond.Error(w, r, op, http.StatusBadRequest, "invalid subscription_id", err)
+		return
+	}
+
+	ui, err := h.DB.RestoreByID(ctx, id)
+	if err != nil {
+		respond.RepoError(w, r, op, "failed to restore", err)
+		return
+	}
+
+	respond.Writer(w, r, op, http.StatusOK, ui)
+}
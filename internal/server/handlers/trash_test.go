@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"user-aggregation/internal/auth"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetTrash_OK(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	deleted := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.On("ListPage", mock.Anything, mock.MatchedBy(func(p repo.ListParams) bool {
+		return p.Deleted && p.Limit == 10 && p.Sort == repo.SortByPrice && p.Desc
+	})).
+		Return(repo.Page{Items: []models.UserInfo{{ServiceName: "Netflix", Price: 400, DeletedAt: &deleted}}}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/trash?limit=10&sort=price&order=desc", nil)
+	w := httptest.NewRecorder()
+
+	h.GetTrash(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var out response.UserInfoPage
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Len(t, out.Items, 1)
+	require.NotNil(t, out.Items[0].DeletedAt)
+	require.True(t, out.Items[0].DeletedAt.Equal(deleted))
+	m.AssertExpectations(t)
+}
+
+func TestGetTrash_BadParams(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/trash?order=sideways", nil)
+	w := httptest.NewRecorder()
+
+	h.GetTrash(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	m.AssertNotCalled(t, "ListPage", mock.Anything, mock.Anything)
+}
+
+func TestRestoreInfo_OK(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	m.On("RestoreByUserID", mock.Anything, uid).
+		Return(repo.RestoreResult{Restored: 3}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/"+uid.String()+"/restore", nil)
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.RestoreInfo(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"restored":3,"conflicts":[]}`, w.Body.String())
+	m.AssertExpectations(t)
+}
+
+func TestRestoreInfo_Conflicts(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid, sid := uuid.New(), uuid.New()
+	m.On("RestoreByUserID", mock.Anything, uid).
+		Return(repo.RestoreResult{
+			Restored:  1,
+			Conflicts: []models.UserInfo{{ID: sid, UserID: uid, ServiceName: "Yandex Plus"}},
+		}, nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/users/"+uid.String()+"/restore", nil)
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.RestoreInfo(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var out response.RestoreReport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.EqualValues(t, 1, out.Restored)
+	require.Len(t, out.Conflicts, 1)
+	require.Equal(t, sid, out.Conflicts[0].ID)
+	m.AssertExpectations(t)
+}
+
+func TestRestoreInfo_Errors(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{repo.ErrNotFound, http.StatusNotFound},
+		{repo.ErrConflict, http.StatusConflict},
+	} {
+		m := new(mocks.RepoMock)
+		h := New(slog.Default(), m)
+
+		uid := uuid.New()
+		m.On("RestoreByUserID", mock.Anything, uid).
+			Return(repo.RestoreResult{}, tc.err).
+			Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/users/"+uid.String()+"/restore", nil)
+		req = withVars(req, "id", uid.String())
+		w := httptest.NewRecorder()
+
+		h.RestoreInfo(w, req)
+		require.Equal(t, tc.code, w.Code)
+		m.AssertExpectations(t)
+	}
+}
+
+func TestRestoreSubscription(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{nil, http.StatusOK},
+		{repo.ErrNotFound, http.StatusNotFound},
+		{repo.ErrConflict, http.StatusConflict},
+	} {
+		m := new(mocks.RepoMock)
+		h := New(slog.Default(), m)
+
+		sid := uuid.New()
+		m.On("RestoreByID", mock.Anything, sid).
+			Return(models.UserInfo{ID: sid}, tc.err).
+			Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/subscriptions/"+sid.String()+"/restore", nil)
+		req = withVars(req, "subscription_id", sid.String())
+		w := httptest.NewRecorder()
+
+		h.RestoreSubscription(w, req)
+		require.Equal(t, tc.code, w.Code)
+		m.AssertExpectations(t)
+	}
+}
+
+func TestDeleteInfo_Permanent(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	m.On("PurgeByUserID", mock.Anything, uid).
+		Return(int64(4), nil).
+		Once()
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/"+uid.String()+"?permanent=true", nil)
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.DeleteInfo(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"deleted":4}`, w.Body.String())
+	m.AssertNotCalled(t, "DeleteByUserID", mock.Anything, mock.Anything)
+	m.AssertExpectations(t)
+
+	req = httptest.NewRequest(http.MethodDelete, "/users/"+uid.String()+"?permanent=maybe", nil)
+	req = withVars(req, "id", uid.String())
+	w = httptest.NewRecorder()
+
+	h.DeleteInfo(w, req)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestDeleteInfo_PermanentNeedsAdmin(t *testing.T) {
+	m := new(mocks.RepoMock)
+	h := New(slog.Default(), m)
+
+	uid := uuid.New()
+	writer := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "writer", Scopes: []auth.Scope{auth.ScopeSubscriptionsWrite}})
+
+	// mux and url.ParseQuery split ";" differently; only the parsed value counts
+	for _, q := range []string{"?permanent=true", "?permanent;=x&permanent=true"} {
+		req := httptest.NewRequest(http.MethodDelete, "/users/"+uid.String()+q, nil).WithContext(writer)
+		req = withVars(req, "id", uid.String())
+		w := httptest.NewRecorder()
+
+		h.DeleteInfo(w, req)
+		require.Equal(t, http.StatusForbidden, w.Code, q)
+	}
+	m.AssertNotCalled(t, "PurgeByUserID", mock.Anything, mock.Anything)
+
+	m.On("PurgeByUserID", mock.Anything, uid).Return(int64(1), nil).Once()
+	admin := auth.WithPrincipal(context.Background(), auth.Principal{Subject: "root", Scopes: []auth.Scope{auth.ScopeAdmin}})
+	req := httptest.NewRequest(http.MethodDelete, "/users/"+uid.String()+"?permanent;=x&permanent=true", nil).WithContext(admin)
+	req = withVars(req, "id", uid.String())
+	w := httptest.NewRecorder()
+
+	h.DeleteInfo(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	m.AssertExpectations(t)
+}
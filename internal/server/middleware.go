@@ -0,0 +1,181 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+	"user-aggregation/internal/lib/reqctx"
+	"user-aggregation/internal/transport/http/recorder"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const headerRequestID = "X-Request-ID"
+
+// maxRequestIDLen caps client-supplied request IDs before they reach the logs.
+const maxRequestIDLen = 128
+
+// requestContext stores the request ID (taken from X-Request-ID or
+// generated), the actor and a request-scoped logger in the request context,
+// and writes one access log line when the request is done.
+//
+// The actor starts as the client address; auth replaces it with the
+// caller. The logger carries the request's method, path, remote address,
+// user agent and, on every record, the time elapsed so far; logRoute adds
+// the route template once the router has matched one.
+//
+// It wraps the whole router, so requests that match no route (404, 405)
+// get an ID and an access log line too.
+func requestContext(log *slog.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			id := r.Header.Get(headerRequestID)
+			if !validRequestID(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(headerRequestID, id)
+
+			addr := r.RemoteAddr
+			if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				addr = host
+			}
+			reqLog := slog.New(elapsedHandler{Handler: log.Handler(), start: start}).With(
+				slog.String("request_id", id),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("remote_addr", addr),
+				slog.String("user_agent", r.UserAgent()),
+			)
+
+			ctx := reqctx.WithRequestID(r.Context(), id)
+			ctx = reqctx.WithActor(ctx, "ip:"+addr)
+			ctx = reqctx.WithLogger(ctx, reqLog)
+			route := new(string)
+			ctx = context.WithValue(ctx, routeKey{}, route)
+
+			rec := recorder.New(w)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			level := slog.LevelInfo
+			switch {
+			case rec.Status() >= http.StatusInternalServerError:
+				level = slog.LevelError
+			case rec.Status() >= http.StatusBadRequest:
+				level = slog.LevelWarn
+			}
+			reqLog.LogAttrs(ctx, level, "request completed",
+				slog.String("route", *route),
+				slog.Int("status", rec.Status()),
+				slog.Int64("bytes", rec.Bytes()),
+			)
+		})
+	}
+}
+
+// routeKey holds the *string that logRoute sets to the matched route
+// template for the access log of requestContext.
+type routeKey struct{}
+
+// logRoute adds the template of the matched route to the request logger
+// and to the access log. It runs inside the router: requestContext, which
+// wraps the router, cannot see the route.
+func logRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cr := mux.CurrentRoute(r); cr != nil {
+			if tpl, err := cr.GetPathTemplate(); err == nil {
+				ctx := r.Context()
+				if route, ok := ctx.Value(routeKey{}).(*string); ok {
+					*route = tpl
+				}
+				ctx = reqctx.WithLogger(ctx, reqctx.Logger(ctx).With(slog.String("route", tpl)))
+				r = r.WithContext(ctx)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validRequestID accepts non-empty IDs of printable ASCII up to maxRequestIDLen.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// elapsedHandler adds the time since start to every record. It cannot be
+// a plain With attribute, since handlers format those once, up front.
+type elapsedHandler struct {
+	slog.Handler
+	start time.Time
+}
+
+func (h elapsedHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(slog.Duration("duration", time.Since(h.start)))
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h elapsedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return elapsedHandler{Handler: h.Handler.WithAttrs(attrs), start: h.start}
+}
+
+func (h elapsedHandler) WithGroup(name string) slog.Handler {
+	return elapsedHandler{Handler: h.Handler.WithGroup(name), start: h.start}
+}
+
+// requestDeadline puts a deadline of d on the request context so that
+// database calls are cancelled before the write timeout cuts the response
+// off. A zero d leaves the context alone.
+func requestDeadline(d time.Duration) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// routeTimeout bounds a route by d: the request context gets the database
+// budget of d and, when d differs from the server timeout, the connection
+// read and write deadlines are moved to d from now.
+func routeTimeout(d, serverTimeout time.Duration) mux.MiddlewareFunc {
+	deadline := requestDeadline(dbBudget(d))
+	return func(next http.Handler) http.Handler {
+		next = deadline(next)
+		if d == serverTimeout {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var until time.Time // zero: no deadline
+			if d > 0 {
+				until = time.Now().Add(d)
+			}
+			rc := http.NewResponseController(w)
+			if err := errors.Join(rc.SetReadDeadline(until), rc.SetWriteDeadline(until)); err != nil {
+				reqctx.Logger(r.Context()).Warn("set route deadline", slog.Any("err", err))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// dbBudget is the part of the request timeout handlers may spend on the
+// database; the rest is left for writing the response or the error.
+func dbBudget(timeout time.Duration) time.Duration {
+	return timeout - timeout/10
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"user-aggregation/internal/lib/reqctx"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestContext(t *testing.T) {
+	var gotID, gotActor string
+	h := requestContext(slog.New(slog.DiscardHandler))(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		gotID = reqctx.RequestID(r.Context())
+		gotActor = reqctx.Actor(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.RemoteAddr = "10.1.2.3:5555"
+	req.Header.Set(headerRequestID, "abc-123")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	require.Equal(t, "abc-123", gotID)
+	require.Equal(t, "abc-123", w.Header().Get(headerRequestID))
+	require.Equal(t, "ip:10.1.2.3", gotActor)
+
+	// missing or oversized IDs are replaced
+	req = httptest.NewRequest(http.MethodGet, "/users", nil)
+	req.Header.Set(headerRequestID, strings.Repeat("x", maxRequestIDLen+1))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	require.Len(t, gotID, 36)
+	require.Equal(t, gotID, w.Header().Get(headerRequestID))
+}
+
+func TestRequestContext_AccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	h := requestContext(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqctx.Logger(r.Context()).InfoContext(r.Context(), "inside")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("nope"))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users/x", nil)
+	req.Header.Set(headerRequestID, "req-1")
+	req.Header.Set("User-Agent", "test-agent")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var lines []map[string]any
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var m map[string]any
+		require.NoError(t, dec.Decode(&m))
+		lines = append(lines, m)
+	}
+	require.Len(t, lines, 2)
+
+	for _, l := range lines {
+		require.Equal(t, "req-1", l["request_id"])
+		require.Equal(t, "GET", l["method"])
+		require.Equal(t, "/users/x", l["path"])
+		require.Equal(t, "test-agent", l["user_agent"])
+		require.Contains(t, l, "duration")
+	}
+	require.Equal(t, "inside", lines[0]["msg"])
+
+	access := lines[1]
+	require.Equal(t, "request completed", access["msg"])
+	require.Equal(t, "WARN", access["level"])
+	require.EqualValues(t, http.StatusNotFound, access["status"])
+	require.EqualValues(t, 4, access["bytes"])
+}
+
+func TestRequestContext_Router(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	r := mux.NewRouter()
+	r.Use(logRoute)
+	r.Methods(http.MethodGet).Path("/users/{id}").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqctx.Logger(r.Context()).InfoContext(r.Context(), "inside")
+	})
+	h := requestContext(log)(r)
+
+	for _, tc := range []struct {
+		method, path, route string
+		status              int
+	}{
+		{http.MethodGet, "/users/x", "/users/{id}", http.StatusOK},
+		{http.MethodGet, "/nowhere", "", http.StatusNotFound},
+		{http.MethodPost, "/users/x", "", http.StatusMethodNotAllowed},
+	} {
+		buf.Reset()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+		require.Equal(t, tc.status, w.Code, tc.path)
+		require.NotEmpty(t, w.Header().Get(headerRequestID), tc.path)
+
+		var lines []map[string]any
+		dec := json.NewDecoder(&buf)
+		for dec.More() {
+			var m map[string]any
+			require.NoError(t, dec.Decode(&m))
+			lines = append(lines, m)
+		}
+		access := lines[len(lines)-1]
+		require.Equal(t, "request completed", access["msg"], tc.path)
+		require.Equal(t, w.Header().Get(headerRequestID), access["request_id"], tc.path)
+		require.Equal(t, tc.route, access["route"], tc.path)
+		if tc.route != "" {
+			require.Equal(t, tc.route, lines[0]["route"], "handler logs carry the route")
+		}
+	}
+}
+
+func TestValidRequestID(t *testing.T) {
+	require.True(t, validRequestID("abc-123_X.y"))
+	require.False(t, validRequestID(""))
+	require.False(t, validRequestID("has space"))
+	require.False(t, validRequestID("line\nbreak"))
+	require.False(t, validRequestID("ünicode"))
+	require.False(t, validRequestID(strings.Repeat("x", maxRequestIDLen+1)))
+}
+
+func TestRequestDeadline(t *testing.T) {
+	var (
+		deadline time.Time
+		ok       bool
+	)
+	h := requestDeadline(dbBudget(time.Second))(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		deadline, ok = r.Context().Deadline()
+	}))
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(900*time.Millisecond), deadline, 100*time.Millisecond)
+
+	h = requestDeadline(0)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		_, ok = r.Context().Deadline()
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users", nil))
+	require.False(t, ok)
+}
+
+func TestRouteTimeout(t *testing.T) {
+	var deadline time.Time
+	h := routeTimeout(10*time.Second, time.Second)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		deadline, _ = r.Context().Deadline()
+	}))
+
+	// a recorder has no connection deadlines to move; the budget still applies
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users/bulk", nil))
+	require.WithinDuration(t, time.Now().Add(9*time.Second), deadline, 100*time.Millisecond)
+}
@@ -3,72 +3,238 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
+	"user-aggregation/internal/auth"
+	"user-aggregation/internal/health"
+	"user-aggregation/internal/metrics"
+	"user-aggregation/internal/ratelimit"
 	"user-aggregation/internal/server/handlers"
 	"user-aggregation/internal/server/handlers/swagger"
+	"user-aggregation/internal/tracing"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
 type Server struct {
 	httpHandlers *handlers.HTTP
+	opts         Options
+
+	srv      *http.Server
+	ln       net.Listener
+	errCh    chan error
+	inFlight atomic.Int64
+}
+
+// Options holds the optional parts of the server.
+type Options struct {
+	// Auth protects the API routes; nil leaves them public.
+	Auth *auth.Authenticator
+	// PublicHealth, PublicDocs and PublicMetrics exempt /livez and /readyz,
+	// the Swagger routes and /metrics from Auth.
+	PublicHealth  bool
+	PublicDocs    bool
+	PublicMetrics bool
+	// RateLimit throttles the API routes by group; nil disables it.
+	RateLimit *ratelimit.Limiter
+	// Metrics records every route and serves /metrics; nil disables both.
+	Metrics *metrics.Metrics
+	// Health runs the /readyz checks; nil reports ready with no checks.
+	Health *health.Checker
+	// GroupTimeouts replace the server timeout for the routes of a group.
+	GroupTimeouts map[string]time.Duration
 }
 
-func New(h *handlers.HTTP) *Server {
-	return &Server{httpHandlers: h}
+// Rate limit groups of the API routes.
+const (
+	GroupRead    = "read"    // single-record and list reads
+	GroupWrite   = "write"   // creates, updates, deletes
+	GroupBulk    = "bulk"    // bulk load and CSV import/export
+	GroupSummary = "summary" // /summary*
+)
+
+func New(h *handlers.HTTP, opts Options) *Server {
+	return &Server{httpHandlers: h, opts: opts}
 }
 
-func (s *Server) Start(
-	ctx context.Context,
-	address string,
-	idleTimeout, rwTimeout,
-	shutdownTimeout time.Duration) error {
+// Start binds address and serves in the background until Shutdown. Errors
+// of the running server are delivered on Err.
+func (s *Server) Start(address string, idleTimeout, rwTimeout time.Duration) error {
 	r := mux.NewRouter()
+	r.Use(logRoute)
+	if s.opts.Metrics != nil {
+		r.Use(s.opts.Metrics.Middleware)
+	}
+	r.Use(tracing.Route)
 
-	r.Methods(http.MethodPost).Path("/users").HandlerFunc(s.httpHandlers.LoadNewInfo)
-	r.Methods(http.MethodGet).Path("/users/{id}").HandlerFunc(s.httpHandlers.GetInfo)
-	r.Methods(http.MethodPatch).Path("/users/{id}").HandlerFunc(s.httpHandlers.PatchUserInfo)
-	r.Methods(http.MethodGet).Path("/users").HandlerFunc(s.httpHandlers.GetAllInfo)
-	r.Methods(http.MethodDelete).Path("/users/{id}").HandlerFunc(s.httpHandlers.DeleteInfo)
+	h := s.httpHandlers
+	a, lim := s.opts.Auth, s.opts.RateLimit
 
-	r.Methods(http.MethodGet).Path("/summary").HandlerFunc(s.httpHandlers.GetFilterSummary)
+	// guard limits by address first, so that failed authentication is
+	// throttled too, then checks access, so that the group limiter can key
+	// on the caller
+	guard := func(require func(http.HandlerFunc) http.Handler, group string) func(http.HandlerFunc) http.Handler {
+		timeout := rwTimeout
+		if d, ok := s.opts.GroupTimeouts[group]; ok {
+			timeout = d
+		}
+		return func(next http.HandlerFunc) http.Handler {
+			return routeTimeout(timeout, rwTimeout)(lim.WrapIP(require(lim.Wrap(group, next).ServeHTTP)))
+		}
+	}
+	read := guard(a.Require(auth.ScopeSubscriptionsRead), GroupRead)
+	write := guard(a.Require(auth.ScopeSubscriptionsWrite), GroupWrite)
+	bulkRead := guard(a.Require(auth.ScopeSubscriptionsRead), GroupBulk)
+	bulkWrite := guard(a.Require(auth.ScopeSubscriptionsWrite), GroupBulk)
+	summary := guard(a.Require(auth.ScopeSummaryRead), GroupSummary)
+	admin := guard(a.Require(auth.ScopeAdmin), GroupWrite)
 
-	r.Methods(http.MethodGet).Path("/health").HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		_, _ = w.Write([]byte("ok"))
-	})
-	swagger.RegisterRoutes(r)
-	srv := &http.Server{
-		Addr:              address,
-		Handler:           r,
+	// the only routes open to user-scoped tokens, for their own user ID
+	pathUser := func(r *http.Request) string { return mux.Vars(r)["id"] }
+	queryUser := func(r *http.Request) string { return r.URL.Query().Get("user_id") }
+	readOwn := guard(a.RequireOwner(auth.ScopeSubscriptionsRead, pathUser), GroupRead)
+	writeOwn := guard(a.RequireOwner(auth.ScopeSubscriptionsWrite, pathUser), GroupWrite)
+	summaryOwn := guard(a.RequireOwner(auth.ScopeSummaryRead, queryUser), GroupSummary)
+
+	r.Methods(http.MethodPost).Path("/users").Handler(write(h.LoadNewInfo))
+	r.Methods(http.MethodPost).Path("/users/bulk").Handler(bulkWrite(h.BulkLoad))
+	r.Methods(http.MethodPost).Path("/users/import.csv").Handler(bulkWrite(h.ImportCSV))
+	r.Methods(http.MethodGet).Path("/users/export.csv").Handler(bulkRead(h.ExportCSV)) // before /users/{id}
+	r.Methods(http.MethodGet).Path("/users/trash").Handler(read(h.GetTrash))
+	r.Methods(http.MethodGet).Path("/users/{id}").Handler(readOwn(h.GetInfo))
+	r.Methods(http.MethodPatch).Path("/users/{id}").Handler(writeOwn(h.PatchUserInfo))
+	r.Methods(http.MethodGet).Path("/users").Handler(read(h.GetAllInfo))
+	// permanent deletion also needs admin, checked by DeleteInfo on the query it parses
+	r.Methods(http.MethodDelete).Path("/users/{id}").Handler(writeOwn(h.DeleteInfo))
+	r.Methods(http.MethodGet).Path("/users/{id}/history").Handler(read(h.GetHistory))
+	r.Methods(http.MethodPost).Path("/users/{id}/restore").Handler(write(h.RestoreInfo))
+	r.Methods(http.MethodPut).Path("/users/{id}/subscriptions").Handler(write(h.UpsertSubscription))
+
+	r.Methods(http.MethodGet).Path("/subscriptions/{subscription_id}").Handler(read(h.GetSubscription))
+	r.Methods(http.MethodPatch).Path("/subscriptions/{subscription_id}").Handler(write(h.PatchSubscription))
+	r.Methods(http.MethodDelete).Path("/subscriptions/{subscription_id}").Handler(write(h.DeleteSubscription))
+	r.Methods(http.MethodPost).Path("/subscriptions/{subscription_id}/restore").Handler(write(h.RestoreSubscription))
+	r.Methods(http.MethodGet).Path("/subscriptions/{subscription_id}/prices").Handler(read(h.GetPriceHistory))
+
+	r.Methods(http.MethodGet).Path("/summary").Handler(summaryOwn(h.GetFilterSummary))
+	r.Methods(http.MethodGet).Path("/summary/grouped").Handler(summary(h.GetGroupedSummary))
+	r.Methods(http.MethodGet).Path("/summary/monthly").Handler(summary(h.GetMonthlySummary))
+
+	r.Methods(http.MethodGet).Path("/exchange-rates").Handler(summary(h.GetExchangeRates))
+	r.Methods(http.MethodPut).Path("/exchange-rates").Handler(admin(h.PutExchangeRates))
+
+	probes := s.opts.Health
+	if probes == nil {
+		probes = health.New(0)
+	}
+	live, ready := http.Handler(http.HandlerFunc(probes.Live)), http.Handler(http.HandlerFunc(probes.Ready))
+	if !s.opts.PublicHealth {
+		live, ready = lim.WrapIP(s.opts.Auth.Authenticated(live)), lim.WrapIP(s.opts.Auth.Authenticated(ready))
+	}
+	r.Methods(http.MethodGet).Path("/livez").Handler(live)
+	r.Methods(http.MethodGet).Path("/readyz").Handler(ready)
+
+	if s.opts.Metrics != nil {
+		metricsHandler := s.opts.Metrics.Handler()
+		if !s.opts.PublicMetrics {
+			metricsHandler = lim.WrapIP(s.opts.Auth.Authenticated(metricsHandler))
+		}
+		r.Methods(http.MethodGet).Path("/metrics").Handler(metricsHandler)
+	}
+
+	docs := r.NewRoute().Subrouter()
+	if !s.opts.PublicDocs {
+		docs.Use(lim.WrapIP, s.opts.Auth.Authenticated)
+	}
+	swagger.RegisterRoutes(docs)
+	s.srv = &http.Server{
+		Handler:           s.track(otelhttp.NewHandler(requestContext(s.httpHandlers.Logger)(r), "http.server")), // renamed per route by tracing.Route
 		IdleTimeout:       idleTimeout,
 		ReadTimeout:       rwTimeout,
 		WriteTimeout:      rwTimeout,
 		ReadHeaderTimeout: rwTimeout * 2,
 	}
 
-	errCh := make(chan error, 1)
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("server: listen %s: %w", address, err)
+	}
+	s.ln = &onceCloseListener{Listener: ln}
+
+	s.errCh = make(chan error, 1)
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			errCh <- err
-			return
+		// a listener closed by StopAccepting is not a failure either
+		err := s.srv.Serve(s.ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			s.errCh <- err
 		}
-		errCh <- nil
 	}()
+	return nil
+}
 
-	select {
-	case <-ctx.Done():
-	case err := <-errCh:
-		return err
+// Addr is the address the server listens on, once started.
+func (s *Server) Addr() net.Addr {
+	return s.ln.Addr()
+}
+
+// Err delivers the error that stopped the server, if it fails on its own.
+func (s *Server) Err() <-chan error {
+	return s.errCh
+}
+
+// InFlight is the number of requests being handled right now.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// StopAccepting closes the listener and turns keep-alive off, so that
+// clients open no new requests while the ones in flight finish.
+func (s *Server) StopAccepting(context.Context) error {
+	s.srv.SetKeepAlivesEnabled(false)
+	if err := s.ln.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("server: close listener: %w", err)
 	}
+	return nil
+}
+
+// Shutdown waits for in-flight requests to finish. When ctx is done first
+// the remaining connections are closed and the requests are cut off.
+func (s *Server) Shutdown(ctx context.Context) error {
+	const op = "server.shutdown"
+	s.httpHandlers.Logger.Info("draining requests", slog.String("op", op), slog.Int64("in_flight", s.InFlight()))
 
-	shCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
-	defer cancel()
-	if err := srv.Shutdown(shCtx); err != nil {
-		_ = srv.Close()
-		return err
+	if err := s.srv.Shutdown(ctx); err != nil {
+		left := s.InFlight()
+		_ = s.srv.Close()
+		return fmt.Errorf("server: %d requests cut off: %w", left, err)
 	}
+	return nil
+}
+
+// track counts requests in flight.
+func (s *Server) track(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// onceCloseListener lets StopAccepting close the listener ahead of
+// http.Server.Shutdown, which closes it again.
+type onceCloseListener struct {
+	net.Listener
+	once sync.Once
+	err  error
+}
 
-	return <-errCh
+func (l *onceCloseListener) Close() error {
+	l.once.Do(func() { l.err = l.Listener.Close() })
+	return l.err
 }
@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"testing"
+	"time"
+	"user-aggregation/internal/lifecycle"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/server/handlers"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// slowServer serves GET /users with a ListPage that blocks until release
+// is closed. entered receives once per request that reached the repo.
+func slowServer(t *testing.T) (s *Server, entered chan struct{}, release chan struct{}) {
+	t.Helper()
+	entered, release = make(chan struct{}, 8), make(chan struct{})
+
+	m := new(mocks.RepoMock)
+	m.On("ListPage", mock.Anything, mock.Anything).
+		Run(func(mock.Arguments) {
+			entered <- struct{}{}
+			<-release
+		}).
+		Return(repo.Page{Items: []models.UserInfo{{ID: uuid.New(), ServiceName: "Netflix"}}}, nil)
+
+	log := slog.New(slog.DiscardHandler)
+	s = New(handlers.New(log, m), Options{})
+	require.NoError(t, s.Start("127.0.0.1:0", time.Minute, 5*time.Second))
+	return s, entered, release
+}
+
+type result struct {
+	code int
+	body []byte
+	err  error
+}
+
+func get(url string) <-chan result {
+	out := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			out <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		out <- result{code: resp.StatusCode, body: body, err: err}
+	}()
+	return out
+}
+
+func TestShutdown_DrainsInFlight(t *testing.T) {
+	s, entered, release := slowServer(t)
+	url := "http://" + s.Addr().String()
+
+	pending := get(url + "/users")
+	<-entered
+	require.EqualValues(t, 1, s.InFlight())
+
+	lc := lifecycle.New(slog.New(slog.DiscardHandler))
+	lc.OnShutdown("drain in-flight requests", 5*time.Second, s.Shutdown)
+	lc.OnShutdown("stop accepting connections", 0, s.StopAccepting)
+
+	done := make(chan error, 1)
+	go func() { done <- lc.Shutdown(context.Background()) }()
+
+	// new connections are refused while the old request is still running
+	require.Eventually(t, func() bool {
+		_, err := http.Get(url + "/livez")
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("shutdown finished before the request: %v", err)
+	default:
+	}
+
+	close(release)
+	require.NoError(t, <-done)
+
+	res := <-pending
+	require.NoError(t, res.err)
+	require.Equal(t, http.StatusOK, res.code)
+	var page response.UserInfoPage
+	require.NoError(t, json.Unmarshal(res.body, &page), "body must arrive complete")
+	require.Len(t, page.Items, 1)
+	require.Zero(t, s.InFlight())
+
+	select {
+	case err := <-s.Err():
+		t.Fatalf("server reported %v", err)
+	default:
+	}
+}
+
+func TestShutdown_TimeoutCutsOff(t *testing.T) {
+	s, entered, release := slowServer(t)
+	defer close(release)
+
+	pending := get("http://" + s.Addr().String() + "/users")
+	<-entered
+
+	require.NoError(t, s.StopAccepting(context.Background()))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := s.Shutdown(ctx)
+	require.ErrorContains(t, err, "1 requests cut off")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.Error(t, (<-pending).err)
+}
@@ -0,0 +1,175 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"time"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/repo"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Repo wraps r so that every call gets a "repo.<Method>" span. The SQL
+// statements run inside show up as its children (see the pgx tracer in
+// the postgres package).
+func Repo(r repo.Repo) repo.Repo {
+	return &tracedRepo{next: r}
+}
+
+type tracedRepo struct {
+	next repo.Repo
+}
+
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "repo."+method, trace.WithSpanKind(trace.SpanKindInternal))
+}
+
+// endSpan closes span; not found is an answer, not a failure.
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, repo.ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (t *tracedRepo) Insert(ctx context.Context, u *models.UserInfo) (err error) {
+	ctx, span := startSpan(ctx, "Insert")
+	defer func() { endSpan(span, err) }()
+	return t.next.Insert(ctx, u)
+}
+
+func (t *tracedRepo) Upsert(ctx context.Context, u *models.UserInfo) (created bool, err error) {
+	ctx, span := startSpan(ctx, "Upsert")
+	defer func() { endSpan(span, err) }()
+	return t.next.Upsert(ctx, u)
+}
+
+func (t *tracedRepo) BulkUpsert(ctx context.Context, items []models.UserInfo, atomic bool) (res []repo.BulkResult, err error) {
+	ctx, span := startSpan(ctx, "BulkUpsert")
+	defer func() { endSpan(span, err) }()
+	return t.next.BulkUpsert(ctx, items, atomic)
+}
+
+func (t *tracedRepo) DeleteByUserID(ctx context.Context, userID uuid.UUID) (n int64, err error) {
+	ctx, span := startSpan(ctx, "DeleteByUserID")
+	defer func() { endSpan(span, err) }()
+	return t.next.DeleteByUserID(ctx, userID)
+}
+
+func (t *tracedRepo) UpdateUserInfo(ctx context.Context, userID uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (n int64, err error) {
+	ctx, span := startSpan(ctx, "UpdateUserInfo")
+	defer func() { endSpan(span, err) }()
+	return t.next.UpdateUserInfo(ctx, userID, price, end, priceFrom)
+}
+
+func (t *tracedRepo) List(ctx context.Context) (out []models.UserInfo, err error) {
+	ctx, span := startSpan(ctx, "List")
+	defer func() { endSpan(span, err) }()
+	return t.next.List(ctx)
+}
+
+func (t *tracedRepo) ListPage(ctx context.Context, params repo.ListParams) (page repo.Page, err error) {
+	ctx, span := startSpan(ctx, "ListPage")
+	defer func() { endSpan(span, err) }()
+	return t.next.ListPage(ctx, params)
+}
+
+func (t *tracedRepo) GetByUserID(ctx context.Context, userID uuid.UUID) (out []models.UserInfo, err error) {
+	ctx, span := startSpan(ctx, "GetByUserID")
+	defer func() { endSpan(span, err) }()
+	return t.next.GetByUserID(ctx, userID)
+}
+
+func (t *tracedRepo) Stream(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time, fn func(models.UserInfo) error) (err error) {
+	ctx, span := startSpan(ctx, "Stream")
+	defer func() { endSpan(span, err) }()
+	return t.next.Stream(ctx, userID, serviceName, start, end, fn)
+}
+
+func (t *tracedRepo) FilterSum(ctx context.Context, userID *uuid.UUID, serviceName *string, start, end *time.Time) (out []repo.CurrencyTotal, err error) {
+	ctx, span := startSpan(ctx, "FilterSum")
+	defer func() { endSpan(span, err) }()
+	return t.next.FilterSum(ctx, userID, serviceName, start, end)
+}
+
+func (t *tracedRepo) GroupedSum(ctx context.Context, groupBy repo.GroupBy, userID *uuid.UUID, serviceName *string, start, end *time.Time) (out []repo.GroupStats, err error) {
+	ctx, span := startSpan(ctx, "GroupedSum")
+	defer func() { endSpan(span, err) }()
+	return t.next.GroupedSum(ctx, groupBy, userID, serviceName, start, end)
+}
+
+func (t *tracedRepo) MonthlyCost(ctx context.Context, userID *uuid.UUID, serviceName *string, from, to time.Time, groupBy repo.GroupBy, basis repo.Basis) (out []repo.MonthlyCost, err error) {
+	ctx, span := startSpan(ctx, "MonthlyCost")
+	defer func() { endSpan(span, err) }()
+	return t.next.MonthlyCost(ctx, userID, serviceName, from, to, groupBy, basis)
+}
+
+func (t *tracedRepo) GetByID(ctx context.Context, id uuid.UUID) (u models.UserInfo, err error) {
+	ctx, span := startSpan(ctx, "GetByID")
+	defer func() { endSpan(span, err) }()
+	return t.next.GetByID(ctx, id)
+}
+
+func (t *tracedRepo) UpdateByID(ctx context.Context, id uuid.UUID, price *int64, end models.NullableTime, priceFrom *time.Time) (u models.UserInfo, err error) {
+	ctx, span := startSpan(ctx, "UpdateByID")
+	defer func() { endSpan(span, err) }()
+	return t.next.UpdateByID(ctx, id, price, end, priceFrom)
+}
+
+func (t *tracedRepo) PriceHistory(ctx context.Context, id uuid.UUID) (out []models.PricePeriod, err error) {
+	ctx, span := startSpan(ctx, "PriceHistory")
+	defer func() { endSpan(span, err) }()
+	return t.next.PriceHistory(ctx, id)
+}
+
+func (t *tracedRepo) DeleteByID(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := startSpan(ctx, "DeleteByID")
+	defer func() { endSpan(span, err) }()
+	return t.next.DeleteByID(ctx, id)
+}
+
+func (t *tracedRepo) RestoreByUserID(ctx context.Context, userID uuid.UUID) (res repo.RestoreResult, err error) {
+	ctx, span := startSpan(ctx, "RestoreByUserID")
+	defer func() { endSpan(span, err) }()
+	return t.next.RestoreByUserID(ctx, userID)
+}
+
+func (t *tracedRepo) RestoreByID(ctx context.Context, id uuid.UUID) (u models.UserInfo, err error) {
+	ctx, span := startSpan(ctx, "RestoreByID")
+	defer func() { endSpan(span, err) }()
+	return t.next.RestoreByID(ctx, id)
+}
+
+func (t *tracedRepo) PurgeByUserID(ctx context.Context, userID uuid.UUID) (n int64, err error) {
+	ctx, span := startSpan(ctx, "PurgeByUserID")
+	defer func() { endSpan(span, err) }()
+	return t.next.PurgeByUserID(ctx, userID)
+}
+
+func (t *tracedRepo) PurgeDeleted(ctx context.Context, before time.Time) (n int64, err error) {
+	ctx, span := startSpan(ctx, "PurgeDeleted")
+	defer func() { endSpan(span, err) }()
+	return t.next.PurgeDeleted(ctx, before)
+}
+
+func (t *tracedRepo) History(ctx context.Context, userID uuid.UUID, afterID int64, limit int) (out []models.AuditEntry, err error) {
+	ctx, span := startSpan(ctx, "History")
+	defer func() { endSpan(span, err) }()
+	return t.next.History(ctx, userID, afterID, limit)
+}
+
+func (t *tracedRepo) ExchangeRates(ctx context.Context) (out []models.ExchangeRate, err error) {
+	ctx, span := startSpan(ctx, "ExchangeRates")
+	defer func() { endSpan(span, err) }()
+	return t.next.ExchangeRates(ctx)
+}
+
+func (t *tracedRepo) SaveExchangeRates(ctx context.Context, rates []models.ExchangeRate) (err error) {
+	ctx, span := startSpan(ctx, "SaveExchangeRates")
+	defer func() { endSpan(span, err) }()
+	return t.next.SaveExchangeRates(ctx, rates)
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-aggregation/internal/models"
+	"user-aggregation/internal/repo"
+	"user-aggregation/internal/server/handlers/mocks"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	sr := tracetest.NewSpanRecorder()
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return sr
+}
+
+func TestRoute_NamesServerSpanAndKeepsParent(t *testing.T) {
+	sr := recordSpans(t)
+
+	r := mux.NewRouter()
+	r.Use(Route)
+	r.Methods(http.MethodGet).Path("/users/{id}").HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	h := otelhttp.NewHandler(r, "http.server")
+
+	req := httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString(), nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "GET /users/{id}", spans[0].Name())
+	require.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	require.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+}
+
+func TestRepo_Spans(t *testing.T) {
+	sr := recordSpans(t)
+	db := new(mocks.RepoMock)
+	r := Repo(db)
+	ctx := context.Background()
+
+	db.On("GetByID", mock.Anything, mock.Anything).Return(models.UserInfo{}, repo.ErrNotFound).Once()
+	db.On("DeleteByID", mock.Anything, mock.Anything).Return(errors.New("boom")).Once()
+
+	_, _ = r.GetByID(ctx, uuid.New())
+	_ = r.DeleteByID(ctx, uuid.New())
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "repo.GetByID", spans[0].Name())
+	require.Equal(t, codes.Unset, spans[0].Status().Code, "not found is not a failure")
+	require.Equal(t, "repo.DeleteByID", spans[1].Name())
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	db.AssertExpectations(t)
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Options{Exporter: "jaeger"})
+	require.Error(t, err)
+}
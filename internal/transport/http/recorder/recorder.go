@@ -0,0 +1,55 @@
+// Package recorder wraps an http.ResponseWriter to remember what was sent.
+package recorder
+
+import "net/http"
+
+// Recorder remembers the status code and body size of a response. It keeps
+// Flush working for streamed responses such as the CSV export.
+type Recorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func New(w http.ResponseWriter) *Recorder {
+	return &Recorder{ResponseWriter: w}
+}
+
+// Status is the status code sent, 200 if the handler only wrote a body.
+func (r *Recorder) Status() int {
+	if r.status == 0 {
+		return http.StatusOK
+	}
+	return r.status
+}
+
+// Bytes is the number of body bytes written.
+func (r *Recorder) Bytes() int64 {
+	return r.bytes
+}
+
+func (r *Recorder) WriteHeader(code int) {
+	if r.status == 0 {
+		r.status = code
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *Recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *Recorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (r *Recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
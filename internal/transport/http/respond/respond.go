@@ -2,17 +2,37 @@ package respond
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"user-aggregation/internal/lib/reqctx"
+	"user-aggregation/internal/lib/validation"
 	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/repo"
 )
 
-func Error(w http.ResponseWriter, log *slog.Logger, op string, code int, clientMsg string, err error) {
+// logger is the request-scoped logger of r, or nil without a request.
+func logger(r *http.Request) *slog.Logger {
+	if r == nil {
+		return nil
+	}
+	return reqctx.Logger(r.Context())
+}
+
+func requestID(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	return reqctx.RequestID(r.Context())
+}
+
+// Error writes an ErrorPayload and logs the failure through the logger of r.
+func Error(w http.ResponseWriter, r *http.Request, op string, code int, clientMsg string, err error) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 
-	if log != nil {
-		attrs := []any{
+	if log := logger(r); log != nil {
+		attrs := []slog.Attr{
 			slog.String("op", op),
 			slog.Int("status", code),
 			slog.String("client_msg", clientMsg),
@@ -20,28 +40,111 @@ func Error(w http.ResponseWriter, log *slog.Logger, op string, code int, clientM
 		if err != nil {
 			attrs = append(attrs, slog.Any("error", err))
 		}
-		log.Error("request failed", attrs...)
+		log.LogAttrs(r.Context(), slog.LevelError, "request failed", attrs...)
 	}
 
 	_ = json.NewEncoder(w).Encode(response.ErrorPayload{
-		Error:  clientMsg,
-		Op:     op,
-		Status: code,
+		Error:     clientMsg,
+		Op:        op,
+		Status:    code,
+		RequestID: requestID(r),
 	})
 }
 
-func Writer(w http.ResponseWriter, log *slog.Logger, op string, code int, v any) {
+func Writer(w http.ResponseWriter, r *http.Request, op string, code int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 
+	log := logger(r)
 	if log != nil {
-		log.Info("response", slog.String("op", op), slog.Int("status", code))
+		log.LogAttrs(r.Context(), slog.LevelInfo, "response", slog.String("op", op), slog.Int("status", code))
 	}
 	if err := json.NewEncoder(w).Encode(v); err != nil && log != nil {
-		log.Warn("write json failed",
+		log.LogAttrs(r.Context(), slog.LevelWarn, "write json failed",
 			slog.String("op", op),
 			slog.Int("status", code),
 			slog.Any("error", err),
 		)
 	}
 }
+
+func Invalid(w http.ResponseWriter, r *http.Request, op string, errs validation.Errors) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+
+	fields := FieldErrors(errs)
+
+	if log := logger(r); log != nil {
+		log.LogAttrs(r.Context(), slog.LevelWarn, "validation failed",
+			slog.String("op", op),
+			slog.Int("status", http.StatusUnprocessableEntity),
+			slog.Any("fields", fields),
+		)
+	}
+
+	_ = json.NewEncoder(w).Encode(response.ValidationError{
+		Error:     "validation failed",
+		Op:        op,
+		Status:    http.StatusUnprocessableEntity,
+		Errors:    fields,
+		RequestID: requestID(r),
+	})
+}
+
+// FieldErrors converts validation errors to their response shape.
+func FieldErrors(errs validation.Errors) []response.FieldError {
+	fields := make([]response.FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, response.FieldError{Field: fe.Field, Code: fe.Code})
+	}
+	return fields
+}
+
+// Status maps repo sentinel errors to HTTP status codes.
+// Anything it does not recognise is a 500.
+func Status(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, repo.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repo.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, repo.ErrBadInput):
+		return http.StatusBadRequest
+	case errors.Is(err, repo.ErrConstraint):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, repo.ErrTimeout):
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// RepoError writes err with the status chosen by Status. For 4xx the
+// client message is suffixed with the reason ("failed to delete: not found");
+// 5xx only get clientMsg so driver details never leak. An end_date before
+// the stored start_date is written as the same field error Invalid gives
+// when the request itself has both dates.
+func RepoError(w http.ResponseWriter, r *http.Request, op string, clientMsg string, err error) {
+	if errors.Is(err, repo.ErrEndBeforeStart) {
+		Invalid(w, r, op, validation.Errors{{Field: "end_date", Code: validation.CodeBeforeStart}})
+		return
+	}
+	code := Status(err)
+	if reason := Reason(err); reason != "" && code < http.StatusInternalServerError {
+		clientMsg += ": " + reason
+	}
+	Error(w, r, op, code, clientMsg, err)
+}
+
+// Reason is the client-safe text of the repo sentinel carried by err,
+// or "" if there is none.
+func Reason(err error) string {
+	for _, sentinel := range []error{repo.ErrNotFound, repo.ErrConflict, repo.ErrBadInput, repo.ErrConstraint, repo.ErrTimeout} {
+		if errors.Is(err, sentinel) {
+			return sentinel.Error()
+		}
+	}
+	return ""
+}
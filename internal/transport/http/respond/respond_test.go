@@ -0,0 +1,70 @@
+package respond
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"user-aggregation/internal/lib/reqctx"
+	"user-aggregation/internal/models/response"
+	"user-aggregation/internal/repo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatus(t *testing.T) {
+	cases := map[error]int{
+		nil:                           http.StatusOK,
+		repo.ErrNotFound:              http.StatusNotFound,
+		repo.ErrConflict:              http.StatusConflict,
+		repo.ErrBadInput:              http.StatusBadRequest,
+		repo.ErrConstraint:            http.StatusUnprocessableEntity,
+		repo.ErrTimeout:               http.StatusGatewayTimeout,
+		errors.New("connection lost"): http.StatusInternalServerError,
+
+		errors.Join(repo.ErrConflict, errors.New("subscription already exists")):                                http.StatusConflict,
+		fmt.Errorf("repo: insert user_info: %w", fmt.Errorf("%w: %w", repo.ErrConstraint, errors.New("23514"))): http.StatusUnprocessableEntity,
+	}
+	for err, want := range cases {
+		require.Equal(t, want, Status(err), "%v", err)
+	}
+}
+
+func TestRepoError(t *testing.T) {
+	w := httptest.NewRecorder()
+	RepoError(w, nil, "op", "failed to delete", fmt.Errorf("repo: delete: %w", repo.ErrNotFound))
+
+	require.Equal(t, http.StatusNotFound, w.Code)
+	var out response.ErrorPayload
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, "failed to delete: not found", out.Error)
+
+	w = httptest.NewRecorder()
+	RepoError(w, nil, "op", "failed to delete", errors.New("pq: password authentication failed"))
+
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, "failed to delete", out.Error)
+
+	w = httptest.NewRecorder()
+	RepoError(w, nil, "op", "failed to update", fmt.Errorf("repo: patch: %w", repo.ErrEndBeforeStart))
+
+	require.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var invalid response.ValidationError
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &invalid))
+	require.Equal(t, []response.FieldError{{Field: "end_date", Code: "before_start"}}, invalid.Errors)
+}
+
+func TestError_RequestID(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users", nil)
+	r = r.WithContext(reqctx.WithRequestID(r.Context(), "req-42"))
+	w := httptest.NewRecorder()
+	Error(w, r, "op", http.StatusBadRequest, "invalid id", nil)
+
+	var out response.ErrorPayload
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &out))
+	require.Equal(t, "invalid id", out.Error)
+	require.Equal(t, "req-42", out.RequestID)
+}
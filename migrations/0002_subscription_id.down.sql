@@ -0,0 +1,4 @@
+ALTER TABLE user_info DROP CONSTRAINT IF EXISTS user_info_user_service_start_key;
+ALTER TABLE user_info DROP CONSTRAINT IF EXISTS user_info_pkey;
+ALTER TABLE user_info ADD CONSTRAINT user_info_pkey PRIMARY KEY (user_id, service_name, start_date);
+ALTER TABLE user_info DROP COLUMN IF EXISTS id;
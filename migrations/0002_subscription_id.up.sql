@@ -0,0 +1,9 @@
+ALTER TABLE user_info
+  ADD COLUMN IF NOT EXISTS id uuid NOT NULL DEFAULT gen_random_uuid();
+
+ALTER TABLE user_info DROP CONSTRAINT IF EXISTS user_info_pkey;
+ALTER TABLE user_info ADD CONSTRAINT user_info_pkey PRIMARY KEY (id);
+
+-- Естественный ключ остаётся уникальным: на нём держится ON CONFLICT в Insert
+ALTER TABLE user_info
+  ADD CONSTRAINT user_info_user_service_start_key UNIQUE (user_id, service_name, start_date);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_user_info_service_id;
+DROP INDEX IF EXISTS idx_user_info_end_id;
+DROP INDEX IF EXISTS idx_user_info_start_id;
+DROP INDEX IF EXISTS idx_user_info_price_id;
@@ -0,0 +1,12 @@
+-- Индексы под keyset-пагинацию GET /users: (колонка сортировки, id)
+CREATE INDEX IF NOT EXISTS idx_user_info_price_id
+  ON user_info (price, id);
+
+CREATE INDEX IF NOT EXISTS idx_user_info_start_id
+  ON user_info (start_date, id);
+
+CREATE INDEX IF NOT EXISTS idx_user_info_end_id
+  ON user_info (end_date, id);
+
+CREATE INDEX IF NOT EXISTS idx_user_info_service_id
+  ON user_info (service_name, id);
@@ -0,0 +1,3 @@
+ALTER TABLE user_info DROP CONSTRAINT IF EXISTS user_info_end_after_start;
+ALTER TABLE user_info DROP CONSTRAINT IF EXISTS user_info_service_name_not_blank;
+ALTER TABLE user_info DROP CONSTRAINT IF EXISTS user_info_price_non_negative;
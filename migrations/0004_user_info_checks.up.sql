@@ -0,0 +1,9 @@
+-- NOT VALID: проверяются только новые и изменяемые строки, старые данные не трогаем
+ALTER TABLE user_info
+  ADD CONSTRAINT user_info_price_non_negative CHECK (price >= 0) NOT VALID;
+
+ALTER TABLE user_info
+  ADD CONSTRAINT user_info_service_name_not_blank CHECK (btrim(service_name) <> '') NOT VALID;
+
+ALTER TABLE user_info
+  ADD CONSTRAINT user_info_end_after_start CHECK (end_date >= start_date) NOT VALID;
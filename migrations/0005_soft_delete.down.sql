@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_user_info_deleted_at;
+DROP INDEX IF EXISTS user_info_user_service_start_live_key;
+
+-- Без deleted_at удалённые строки снова стали бы видны и могли бы нарушить уникальность
+DELETE FROM user_info WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE user_info
+  ADD CONSTRAINT user_info_user_service_start_key UNIQUE (user_id, service_name, start_date);
+ALTER TABLE user_info DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,14 @@
+ALTER TABLE user_info
+  ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+
+-- Удалённые записи не должны мешать завести подписку заново,
+-- поэтому уникальность естественного ключа только среди живых строк
+ALTER TABLE user_info DROP CONSTRAINT IF EXISTS user_info_user_service_start_key;
+CREATE UNIQUE INDEX IF NOT EXISTS user_info_user_service_start_live_key
+  ON user_info (user_id, service_name, start_date)
+  WHERE deleted_at IS NULL;
+
+-- Для корзины и очистки по сроку хранения
+CREATE INDEX IF NOT EXISTS idx_user_info_deleted_at
+  ON user_info (deleted_at)
+  WHERE deleted_at IS NOT NULL;
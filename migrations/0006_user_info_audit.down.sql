@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS user_info_audit_delete ON user_info;
+DROP TRIGGER IF EXISTS user_info_audit_update ON user_info;
+DROP TRIGGER IF EXISTS user_info_audit_insert ON user_info;
+DROP FUNCTION IF EXISTS user_info_audit_write();
+
+DROP TABLE IF EXISTS user_info_audit;
+DROP FUNCTION IF EXISTS user_info_audit_append_only();
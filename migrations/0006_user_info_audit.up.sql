@@ -0,0 +1,84 @@
+CREATE TABLE IF NOT EXISTS user_info_audit (
+  id          bigserial   PRIMARY KEY,
+  record_id   uuid        NOT NULL, -- user_info.id, без FK: запись может быть удалена окончательно
+  user_id     uuid        NOT NULL,
+  operation   text        NOT NULL CHECK (operation IN ('insert', 'update', 'delete', 'restore', 'purge')),
+  old_value   jsonb,
+  new_value   jsonb,
+  actor       text        NOT NULL,
+  request_id  text        NOT NULL DEFAULT '',
+  changed_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_info_audit_user_id ON user_info_audit (user_id, id);
+
+-- Журнал пишется триггером в той же транзакции, что и изменение.
+-- Кто и в рамках какого запроса меняет данные, репозиторий передаёт через
+-- set_config('app.actor' / 'app.request_id', ..., true) в начале транзакции.
+CREATE OR REPLACE FUNCTION user_info_audit_write() RETURNS trigger
+LANGUAGE plpgsql AS $$
+DECLARE
+  op    text;
+  rec   user_info;
+  old_v jsonb;
+  new_v jsonb;
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    op := 'insert';
+    rec := NEW;
+    new_v := to_jsonb(NEW);
+  ELSIF TG_OP = 'DELETE' THEN
+    op := 'purge';
+    rec := OLD;
+    old_v := to_jsonb(OLD);
+  ELSE
+    IF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+      op := 'delete';
+    ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+      op := 'restore';
+    ELSE
+      op := 'update';
+    END IF;
+    rec := NEW;
+    old_v := to_jsonb(OLD);
+    new_v := to_jsonb(NEW);
+  END IF;
+
+  INSERT INTO user_info_audit (record_id, user_id, operation, old_value, new_value, actor, request_id)
+  VALUES (
+    rec.id, rec.user_id, op, old_v, new_v,
+    COALESCE(NULLIF(current_setting('app.actor', true), ''), current_user),
+    COALESCE(current_setting('app.request_id', true), '')
+  );
+  RETURN NULL;
+END
+$$;
+
+DROP TRIGGER IF EXISTS user_info_audit_insert ON user_info;
+CREATE TRIGGER user_info_audit_insert
+  AFTER INSERT ON user_info
+  FOR EACH ROW EXECUTE FUNCTION user_info_audit_write();
+
+-- upsert с теми же значениями ничего не меняет и в журнал не попадает
+DROP TRIGGER IF EXISTS user_info_audit_update ON user_info;
+CREATE TRIGGER user_info_audit_update
+  AFTER UPDATE ON user_info
+  FOR EACH ROW WHEN (OLD.* IS DISTINCT FROM NEW.*) EXECUTE FUNCTION user_info_audit_write();
+
+DROP TRIGGER IF EXISTS user_info_audit_delete ON user_info;
+CREATE TRIGGER user_info_audit_delete
+  AFTER DELETE ON user_info
+  FOR EACH ROW EXECUTE FUNCTION user_info_audit_write();
+
+-- Журнал только дополняется
+CREATE OR REPLACE FUNCTION user_info_audit_append_only() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+  RAISE EXCEPTION 'user_info_audit is append-only';
+END
+$$;
+
+DROP TRIGGER IF EXISTS user_info_audit_append_only ON user_info_audit;
+CREATE TRIGGER user_info_audit_append_only
+  BEFORE UPDATE OR DELETE OR TRUNCATE ON user_info_audit
+  FOR EACH STATEMENT EXECUTE FUNCTION user_info_audit_append_only();
@@ -0,0 +1,2 @@
+DROP VIEW IF EXISTS user_info_periods;
+DROP TABLE IF EXISTS user_info_prices;
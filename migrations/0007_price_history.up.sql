@@ -0,0 +1,28 @@
+-- Цена подписки меняется с даты (UTC), прошлые периоды сохраняют свою цену.
+-- user_info.price остаётся последней установленной ценой.
+CREATE TABLE IF NOT EXISTS user_info_prices (
+  subscription_id uuid   NOT NULL REFERENCES user_info (id) ON DELETE CASCADE,
+  effective_from  date   NOT NULL,
+  price           bigint NOT NULL CHECK (price >= 0),
+  PRIMARY KEY (subscription_id, effective_from)
+);
+
+INSERT INTO user_info_prices (subscription_id, effective_from, price)
+SELECT id, (start_date AT TIME ZONE 'UTC')::date, price
+FROM user_info
+ON CONFLICT DO NOTHING;
+
+-- Подписка, разбитая на ценовые периоды: start_date/end_date здесь - границы
+-- периода внутри подписки, так что суммы можно считать теми же условиями, что и по user_info.
+-- Период заканчивается за микросекунду до начала следующего.
+CREATE OR REPLACE VIEW user_info_periods AS
+SELECT *
+FROM (
+  SELECT u.id, u.user_id, u.service_name, u.deleted_at, p.price,
+         GREATEST(u.start_date, p.effective_from::timestamp AT TIME ZONE 'UTC') AS start_date,
+         LEAST(u.end_date, (LEAD(p.effective_from) OVER w)::timestamp AT TIME ZONE 'UTC' - interval '1 microsecond') AS end_date
+  FROM user_info u
+  JOIN user_info_prices p ON p.subscription_id = u.id
+  WINDOW w AS (PARTITION BY p.subscription_id ORDER BY p.effective_from)
+) periods
+WHERE start_date <= end_date;
@@ -0,0 +1,10 @@
+-- Ключи доступа к API. Сам ключ не хранится, только sha256 от него.
+CREATE TABLE IF NOT EXISTS api_keys (
+  id          uuid        PRIMARY KEY DEFAULT gen_random_uuid(),
+  name        text        NOT NULL CHECK (btrim(name) <> ''),
+  prefix      text        NOT NULL, -- начало ключа, чтобы узнать его в списке
+  key_hash    bytea       NOT NULL UNIQUE,
+  scopes      text[]      NOT NULL,
+  created_at  timestamptz NOT NULL DEFAULT now(),
+  revoked_at  timestamptz
+);
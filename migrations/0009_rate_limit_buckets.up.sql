@@ -0,0 +1,10 @@
+-- Token bucket'ы ограничителя частоты запросов, общие для всех реплик.
+-- UNLOGGED: после сбоя таблица очищается, что для лимитов не страшно.
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+  key         text             PRIMARY KEY, -- группа маршрутов и клиент
+  tokens      double precision NOT NULL,
+  allowed     boolean          NOT NULL,    -- итог последнего запроса
+  updated_at  timestamptz      NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
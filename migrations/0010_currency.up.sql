@@ -0,0 +1,30 @@
+-- Валюта цены (ISO 4217). До этой миграции все цены были в рублях.
+-- Умолчания в БД нет: значение по умолчанию подставляет сервис из конфига.
+ALTER TABLE user_info ADD COLUMN IF NOT EXISTS currency char(3) NOT NULL DEFAULT 'RUB';
+ALTER TABLE user_info ALTER COLUMN currency DROP DEFAULT;
+ALTER TABLE user_info
+  ADD CONSTRAINT user_info_currency_code CHECK (currency ~ '^[A-Z]{3}$');
+
+-- Новая колонка добавляется в конец списка, как того требует CREATE OR REPLACE VIEW.
+CREATE OR REPLACE VIEW user_info_periods AS
+SELECT *
+FROM (
+  SELECT u.id, u.user_id, u.service_name, u.deleted_at, p.price,
+         GREATEST(u.start_date, p.effective_from::timestamp AT TIME ZONE 'UTC') AS start_date,
+         LEAST(u.end_date, (LEAD(p.effective_from) OVER w)::timestamp AT TIME ZONE 'UTC' - interval '1 microsecond') AS end_date,
+         u.currency
+  FROM user_info u
+  JOIN user_info_prices p ON p.subscription_id = u.id
+  WINDOW w AS (PARTITION BY p.subscription_id ORDER BY p.effective_from)
+) periods
+WHERE start_date <= end_date;
+
+-- Курсы валют: 1 base = rate quote, начиная с effective_from (UTC) и до следующего курса той же пары.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+  base           char(3)        NOT NULL CHECK (base ~ '^[A-Z]{3}$'),
+  quote          char(3)        NOT NULL CHECK (quote ~ '^[A-Z]{3}$'),
+  effective_from date           NOT NULL,
+  rate           numeric(20,10) NOT NULL CHECK (rate > 0),
+  PRIMARY KEY (base, quote, effective_from),
+  CHECK (base <> quote)
+);
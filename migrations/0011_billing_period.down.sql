@@ -0,0 +1,16 @@
+DROP VIEW IF EXISTS user_info_periods;
+CREATE VIEW user_info_periods AS
+SELECT *
+FROM (
+  SELECT u.id, u.user_id, u.service_name, u.deleted_at, p.price,
+         GREATEST(u.start_date, p.effective_from::timestamp AT TIME ZONE 'UTC') AS start_date,
+         LEAST(u.end_date, (LEAD(p.effective_from) OVER w)::timestamp AT TIME ZONE 'UTC' - interval '1 microsecond') AS end_date,
+         u.currency
+  FROM user_info u
+  JOIN user_info_prices p ON p.subscription_id = u.id
+  WINDOW w AS (PARTITION BY p.subscription_id ORDER BY p.effective_from)
+) periods
+WHERE start_date <= end_date;
+
+ALTER TABLE user_info DROP CONSTRAINT IF EXISTS user_info_billing_period;
+ALTER TABLE user_info DROP COLUMN IF EXISTS billing_period;
@@ -0,0 +1,22 @@
+-- Периодичность списаний. До этой миграции каждая запись считалась ежемесячной.
+ALTER TABLE user_info ADD COLUMN IF NOT EXISTS billing_period text NOT NULL DEFAULT 'monthly';
+ALTER TABLE user_info
+  ADD CONSTRAINT user_info_billing_period CHECK (billing_period IN ('monthly', 'yearly', 'weekly', 'one_off'));
+
+-- Для расчёта дат списаний нужны границы всей подписки, а не только периода цены:
+-- списания отсчитываются от начала подписки.
+CREATE OR REPLACE VIEW user_info_periods AS
+SELECT *
+FROM (
+  SELECT u.id, u.user_id, u.service_name, u.deleted_at, p.price,
+         GREATEST(u.start_date, p.effective_from::timestamp AT TIME ZONE 'UTC') AS start_date,
+         LEAST(u.end_date, (LEAD(p.effective_from) OVER w)::timestamp AT TIME ZONE 'UTC' - interval '1 microsecond') AS end_date,
+         u.currency,
+         u.billing_period,
+         u.start_date AS subscription_start,
+         u.end_date AS subscription_end
+  FROM user_info u
+  JOIN user_info_prices p ON p.subscription_id = u.id
+  WINDOW w AS (PARTITION BY p.subscription_id ORDER BY p.effective_from)
+) periods
+WHERE start_date <= end_date;
@@ -0,0 +1,26 @@
+-- Бессрочные подписки: end_date IS NULL — подписка действует, пока её не закроют.
+ALTER TABLE user_info ALTER COLUMN end_date DROP NOT NULL;
+
+-- Keyset-пагинация по end_date сравнивает COALESCE(end_date, 'infinity'):
+-- бессрочные записи идут последними при сортировке по возрастанию.
+DROP INDEX IF EXISTS idx_user_info_end_id;
+CREATE INDEX IF NOT EXISTS idx_user_info_end_id
+  ON user_info ((COALESCE(end_date, 'infinity'::timestamptz)), id);
+
+-- LEAST пропускает NULL, поэтому последний период бессрочной подписки
+-- тоже получает end_date IS NULL.
+CREATE OR REPLACE VIEW user_info_periods AS
+SELECT *
+FROM (
+  SELECT u.id, u.user_id, u.service_name, u.deleted_at, p.price,
+         GREATEST(u.start_date, p.effective_from::timestamp AT TIME ZONE 'UTC') AS start_date,
+         LEAST(u.end_date, (LEAD(p.effective_from) OVER w)::timestamp AT TIME ZONE 'UTC' - interval '1 microsecond') AS end_date,
+         u.currency,
+         u.billing_period,
+         u.start_date AS subscription_start,
+         u.end_date AS subscription_end
+  FROM user_info u
+  JOIN user_info_prices p ON p.subscription_id = u.id
+  WINDOW w AS (PARTITION BY p.subscription_id ORDER BY p.effective_from)
+) periods
+WHERE end_date IS NULL OR start_date <= end_date;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_info_deleted_batch;
+ALTER TABLE user_info DROP COLUMN IF EXISTS deleted_batch;
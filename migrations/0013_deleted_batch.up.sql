@@ -0,0 +1,23 @@
+-- Пакет удаления: DELETE /users/{id} помечает все убранные в корзину записи
+-- одним deleted_batch, и POST /users/{id}/restore возвращает только последний
+-- пакет. У записей, удалённых по одной, deleted_batch IS NULL.
+ALTER TABLE user_info ADD COLUMN IF NOT EXISTS deleted_batch uuid;
+
+-- Записи, уже лежащие в корзине: удалённые одним запросом получили один
+-- deleted_at (now() транзакции), по нему и собираем пакеты. Удаление одной
+-- записи здесь от удаления пользователя не отличить. Журнал это не меняет.
+ALTER TABLE user_info DISABLE TRIGGER user_info_audit_update;
+UPDATE user_info u
+SET deleted_batch = b.batch
+FROM (
+  SELECT user_id, deleted_at, gen_random_uuid() AS batch
+  FROM user_info
+  WHERE deleted_at IS NOT NULL
+  GROUP BY user_id, deleted_at
+) b
+WHERE u.user_id = b.user_id AND u.deleted_at = b.deleted_at;
+ALTER TABLE user_info ENABLE TRIGGER user_info_audit_update;
+
+-- Поиск последнего пакета пользователя
+CREATE INDEX IF NOT EXISTS idx_user_info_deleted_batch
+  ON user_info (user_id, deleted_at DESC) WHERE deleted_batch IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS charge_dates(text, timestamptz, timestamptz, timestamptz, timestamptz);
+DROP FUNCTION IF EXISTS charge_index(text, timestamp, timestamp);
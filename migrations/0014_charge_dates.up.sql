@@ -0,0 +1,54 @@
+-- Даты списаний считаются в Postgres, чтобы суммы агрегировались в SQL, а не
+-- выгружались построчно. Правила те же, что у пакета internal/cost
+-- (он остаётся эталоном, с которым сверяются тесты):
+-- первое списание в начале подписки, дальше раз в период, считая от начала;
+-- в коротком месяце — его последний день. Всё считается в UTC.
+
+-- Номер списания не позже t: оценка по длине периода, чтобы не перебирать
+-- всю историю подписки.
+CREATE OR REPLACE FUNCTION charge_index(billing_period text, first_charge timestamp, t timestamp)
+RETURNS int
+LANGUAGE sql IMMUTABLE AS $$
+  SELECT CASE billing_period
+    WHEN 'weekly' THEN floor(extract(epoch FROM t - first_charge) / 604800)::int
+    WHEN 'monthly' THEN ((extract(year FROM t) - extract(year FROM first_charge)) * 12
+                         + extract(month FROM t) - extract(month FROM first_charge))::int
+    WHEN 'yearly' THEN floor(((extract(year FROM t) - extract(year FROM first_charge)) * 12
+                              + extract(month FROM t) - extract(month FROM first_charge)) / 12)::int
+    ELSE 0
+  END
+$$;
+
+-- Списания в [lo, hi], обе границы включительно. В момент stop и после него
+-- списаний нет, кроме самого первого; stop IS NULL — бессрочная подписка.
+-- timestamp + interval '1 month' в коротком месяце даёт его последний день,
+-- а отсчёт всегда от first_charge, так что 31-е число возвращается.
+CREATE OR REPLACE FUNCTION charge_dates(
+  billing_period text,
+  first_charge   timestamptz,
+  stop           timestamptz,
+  lo             timestamptz,
+  hi             timestamptz
+) RETURNS SETOF timestamptz
+LANGUAGE sql STABLE AS $$
+  SELECT c.d AT TIME ZONE 'UTC'
+  FROM (
+    SELECT first_charge AT TIME ZONE 'UTC' AS f,
+           lo AT TIME ZONE 'UTC' AS lo,
+           hi AT TIME ZONE 'UTC' AS hi,
+           stop AT TIME ZONE 'UTC' AS stop,
+           CASE billing_period
+             WHEN 'weekly' THEN interval '7 days'
+             WHEN 'monthly' THEN interval '1 month'
+             WHEN 'yearly' THEN interval '1 year'
+           END AS step
+  ) s
+  CROSS JOIN LATERAL generate_series(
+    GREATEST(charge_index(billing_period, s.f, s.lo) - 1, 0),
+    CASE WHEN s.step IS NULL THEN 0 ELSE GREATEST(charge_index(billing_period, s.f, s.hi), 0) END
+  ) AS n
+  CROSS JOIN LATERAL (SELECT s.f + n * COALESCE(s.step, interval '0')) AS c(d)
+  WHERE c.d >= s.lo
+    AND c.d <= s.hi
+    AND (n = 0 OR s.stop IS NULL OR c.d < s.stop)
+$$;